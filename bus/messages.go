@@ -178,6 +178,17 @@ type B_EthSendTx struct { // send
 	To      common.Address
 	Amount  *big.Int
 	Data    []byte
+	Origin  string // dApp URL, recorded in the transaction history
+}
+
+type B_EthSendBatch struct { // send-batch
+	File string // CSV: chain, token, to, amount
+	From common.Address
+	Mode string // sequential or disperse
+}
+
+type B_EthSafeTx struct { // safe-tx
+	File string // pending SafeTx file
 }
 
 type B_EthCall struct { // send
@@ -229,6 +240,32 @@ type B_EthTxByHash struct { // get-tx-by-hash
 	Hash       common.Hash
 }
 
+type B_EthSignTxFile struct { // sign-tx-file
+	File string // the unsigned transaction file (tx export-unsigned)
+	Out  string // the signed transaction file, File if empty
+}
+
+type B_EthReplaceTx struct { // replace-tx
+	Hash   common.Hash
+	Cancel bool // send 0 to self instead of the original call
+}
+
+type B_EthTxReceipt struct { // get-tx-receipt
+	ChainId int
+	Hash    common.Hash
+	From    common.Address // optional, with Nonce detects dropped transactions
+	Nonce   uint64
+}
+
+type B_EthTxReceipt_Response struct { // get-tx-receipt_response
+	Found       bool // false while the transaction is pending
+	Dropped     bool // not found and the nonce of From was used by another transaction
+	Success     bool
+	GasUsed     uint64
+	BlockNumber uint64
+	Fee         *big.Int
+}
+
 type B_EthTxByHash_Response struct { // get-tx-by-hash_response
 	BlockHash        string `json:"blockHash"`
 	BlockNumber      string `json:"blockNumber"`
@@ -253,6 +290,12 @@ type B_ExplorerDownloadContract struct { // download-contract
 	Address    common.Address
 }
 
+type B_ExplorerGetLogs struct { // get-logs
+	ChainId int
+	Address *common.Address // emitting contract, nil for any
+	Topics  []common.Hash   // topic0..3, zero hash matches any
+}
+
 // ---------- lp_v2 ----------
 type B_LP_V2_Discover struct { // discover
 	ChainId int
@@ -35,18 +35,23 @@ type SConfig struct {
 	TheGraphGateway      string        `yaml:"thegraph_gateway"`       // The Graph gateway URL (default: https://gateway.thegraph.com/api/{api-key}/subgraphs/id/)
 	MinTokenValue        float64       `yaml:"min_token_value"`        // minimum USD value to show token in tokens pane
 	WSEnabled            bool          `yaml:"ws_enabled"`             // enable WebSocket server for browser communication
+	WalletBackups        int           `yaml:"wallet_backups"`         // number of automatic wallet backups to keep (0 = off)
+	WalletBackupInterval time.Duration `yaml:"wallet_backup_interval"` // minimum time between automatic wallet backups
+	Offline              bool          `yaml:"offline"`                // no eth network access (air-gapped signer)
 }
 
 var Config *SConfig = &SConfig{ //Default config
-	Verbosity:         "debug",
-	Theme:             "dark",
-	BusTimeout:        3 * time.Minute,
-	BusHardTimeout:    5 * time.Minute,
-	PriceUpdatePeriod: "15m",
-	Editor:            "code",
-	TheGraphGateway:   "https://gateway.thegraph.com/api/{api-key}/subgraphs/id/",
-	MinTokenValue:     1,
-	WSEnabled:         true,
+	Verbosity:            "debug",
+	Theme:                "dark",
+	BusTimeout:           3 * time.Minute,
+	BusHardTimeout:       5 * time.Minute,
+	PriceUpdatePeriod:    "15m",
+	Editor:               "code",
+	TheGraphGateway:      "https://gateway.thegraph.com/api/{api-key}/subgraphs/id/",
+	MinTokenValue:        1,
+	WSEnabled:            true,
+	WalletBackups:        10,
+	WalletBackupInterval: 15 * time.Minute,
 }
 
 func InitConfig() {
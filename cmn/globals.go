@@ -39,6 +39,7 @@ const (
 )
 
 type Wallet struct {
+	SchemaVersion   int               `json:"schema_version"`
 	Name            string            `json:"name"`
 	Blockchains     []*Blockchain     `json:"blockchains"`
 	Signers         []*Signer         `json:"signers"`
@@ -54,12 +55,18 @@ type Wallet struct {
 	Stakings          []*Staking          `json:"stakings"`
 	StakingPositions  []*StakingPosition  `json:"staking_positions"`
 	Contracts         map[common.Address]*Contract
+	Policies          []*Policy      `json:"policies"`
+	PolicySpends      []*PolicySpend `json:"policy_spends"`
+	Transactions      []*TxRecord    `json:"transactions"`
+	NFTs              []*NFT         `json:"nfts"`
 	AppsPaneOn      bool `json:"apps_pane_on"`
 	LP_V2PaneOn     bool `json:"lp_v2_pane_on"`
 	LP_V3PaneOn     bool `json:"lp_v3_pane_on"`
 	LP_V4PaneOn     bool `json:"lp_v4_pane_on"`
 	TokenPaneOn     bool `json:"token_pane_on"`
 	StakingPaneOn   bool `json:"staking_pane_on"`
+	HistoryPaneOn   bool `json:"history_pane_on"`
+	NFTPaneOn       bool `json:"nft_pane_on"`
 
 	CurrentChainId int            `json:"current_chain_id"`
 	CurrentAddress common.Address `json:"current_address"`
@@ -72,9 +79,12 @@ type Wallet struct {
 	SoundOn bool   `json:"sound_on"`
 	Sound   string `json:"sound"`
 
-	filePath   string     `json:"-"`
-	password   string     `json:"-"`
-	writeMutex sync.Mutex `json:"-"`
+	filePath    string        `json:"-"`
+	password    string        `json:"-"`
+	writeMutex  sync.Mutex    `json:"-"`
+	fileHeader  *walletHeader `json:"-"`
+	fileKey     []byte        `json:"-"`
+	fileKeyPass string        `json:"-"`
 }
 
 type Contract struct {
@@ -94,6 +104,15 @@ type Address struct {
 	Address common.Address `json:"address"`
 	Signer  string         `json:"signer"`
 	Path    string         `json:"path"`
+	Safe    *Safe          `json:"safe,omitempty"` // set for Gnosis Safe multisig accounts
+}
+
+// Safe is the on-chain configuration of a Gnosis Safe, as last read from the chain
+type Safe struct {
+	ChainId   int              `json:"chain_id"`
+	Version   string           `json:"version"`
+	Owners    []common.Address `json:"owners"`
+	Threshold int              `json:"threshold"`
 }
 
 type Blockchain struct {
@@ -110,11 +129,39 @@ type Blockchain struct {
 	Multicall        common.Address `json:"multicall"`
 	RPCRateLimit     int            `json:"rpc_rate_limit,omitempty"` // RPC calls per second (auto-tuned or fixed)
 	RPCRateAuto      bool           `json:"rpc_rate_auto,omitempty"`  // true = auto-tune rate, false = use fixed rate
+	FeePreset        string         `json:"fee_preset,omitempty"`     // default fee preset, normal if empty
+	TxType           string         `json:"tx_type,omitempty"`        // legacy or eip1559, auto-detected if empty
+	FeeModel         string         `json:"fee_model,omitempty"`      // how the L1 data fee is charged, l1 if empty
 }
 
 var EXPLORER_API_TYPES = []string{"etherscan", "blockscout"}
 
-var KNOWN_SIGNER_TYPES = []string{"mnemonics", "ledger", "trezor"}
+const (
+	FEE_PRESET_SLOW   = "slow"
+	FEE_PRESET_NORMAL = "normal"
+	FEE_PRESET_FAST   = "fast"
+	FEE_PRESET_URGENT = "urgent"
+)
+
+var FEE_PRESETS = []string{FEE_PRESET_SLOW, FEE_PRESET_NORMAL, FEE_PRESET_FAST, FEE_PRESET_URGENT}
+
+const (
+	TX_TYPE_AUTO    = "auto"
+	TX_TYPE_LEGACY  = "legacy"
+	TX_TYPE_EIP1559 = "eip1559"
+)
+
+var TX_TYPES = []string{TX_TYPE_AUTO, TX_TYPE_EIP1559, TX_TYPE_LEGACY}
+
+const (
+	FEE_MODEL_L1       = "l1"
+	FEE_MODEL_OP_STACK = "op-stack"
+	FEE_MODEL_ARBITRUM = "arbitrum"
+)
+
+var FEE_MODELS = []string{FEE_MODEL_L1, FEE_MODEL_OP_STACK, FEE_MODEL_ARBITRUM}
+
+var KNOWN_SIGNER_TYPES = []string{"mnemonics", "privkey", "external", "ledger", "trezor"}
 
 type Token struct {
 	ChainId        int            `json:"chain_id"`
@@ -0,0 +1,80 @@
+package cmn
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// NFT holdings. The list is refreshed by the discovery per chain and owner,
+// the LP position NFTs are kept separately.
+
+const (
+	NFT_ERC721  = "erc721"
+	NFT_ERC1155 = "erc1155"
+)
+
+type NFT struct {
+	ChainId    int            `json:"chain_id"`
+	Owner      common.Address `json:"owner"`
+	Contract   common.Address `json:"contract"`
+	TokenId    *big.Int       `json:"token_id"`
+	Standard   string         `json:"standard"`
+	Balance    *big.Int       `json:"balance"` // always 1 for ERC-721
+	Collection string         `json:"collection"`
+}
+
+func (n *NFT) IsERC1155() bool {
+	return n.Standard == NFT_ERC1155
+}
+
+// SetNFTs replaces the holdings of the owner on the chain
+func (w *Wallet) SetNFTs(chainId int, owner common.Address, list []*NFT) error {
+	w.writeMutex.Lock()
+	defer w.writeMutex.Unlock()
+
+	kept := []*NFT{}
+	for _, n := range w.NFTs {
+		if n.ChainId != chainId || n.Owner != owner {
+			kept = append(kept, n)
+		}
+	}
+
+	w.NFTs = append(kept, list...)
+	return w._locked_Save()
+}
+
+// GetNFT finds the token held by the owner
+func (w *Wallet) GetNFT(chainId int, owner common.Address, contract common.Address, id *big.Int) *NFT {
+	for _, n := range w.NFTs {
+		if n.ChainId == chainId && n.Owner == owner && n.Contract == contract && n.TokenId.Cmp(id) == 0 {
+			return n
+		}
+	}
+	return nil
+}
+
+// GetNFTs returns the tokens of the owner, all owners if owner is zero
+func (w *Wallet) GetNFTs(chainId int, owner common.Address) []*NFT {
+	list := []*NFT{}
+	for _, n := range w.NFTs {
+		if n.ChainId == chainId && (owner == common.Address{} || n.Owner == owner) {
+			list = append(list, n)
+		}
+	}
+	return list
+}
+
+func (w *Wallet) RemoveNFT(chainId int, owner common.Address, contract common.Address, id *big.Int) error {
+	w.writeMutex.Lock()
+	defer w.writeMutex.Unlock()
+
+	for i, n := range w.NFTs {
+		if n.ChainId == chainId && n.Owner == owner && n.Contract == contract && n.TokenId.Cmp(id) == 0 {
+			w.NFTs = append(w.NFTs[:i], w.NFTs[i+1:]...)
+			return w._locked_Save()
+		}
+	}
+	return errors.New("nft not found")
+}
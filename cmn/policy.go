@@ -0,0 +1,570 @@
+package cmn
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// Signing policies. A policy applies to an address, or to all the addresses
+// of a signer, and is evaluated before a transaction or typed data is shown
+// for signing.
+
+const (
+	POLICY_MODE_CONFIRM = "confirm" // violations need an explicit override
+	POLICY_MODE_BLOCK   = "block"   // violations reject the request
+)
+
+var POLICY_MODES = []string{POLICY_MODE_CONFIRM, POLICY_MODE_BLOCK}
+
+const POLICY_SPEND_WINDOW = 24 * time.Hour
+
+// approvals at or above 2^128 are treated as unlimited: no token supply
+// comes close, and the dapps use type(uint128).max, uint160 max (Permit2)
+// or 2^255 as well as uint256 max
+var UNLIMITED_APPROVAL = new(big.Int).Lsh(big.NewInt(1), 128)
+
+var (
+	SELECTOR_TRANSFER             = common.FromHex("0xa9059cbb")
+	SELECTOR_TRANSFER_FROM        = common.FromHex("0x23b872dd")
+	SELECTOR_APPROVE              = common.FromHex("0x095ea7b3")
+	SELECTOR_SET_APPROVAL_FOR_ALL = common.FromHex("0xa22cb465")
+)
+
+// KNOWN_SELECTORS can be used by name in the forbidden list
+var KNOWN_SELECTORS = map[string]string{
+	"transfer":          "0xa9059cbb",
+	"transferFrom":      "0x23b872dd",
+	"approve":           "0x095ea7b3",
+	"setApprovalForAll": "0xa22cb465",
+	"increaseAllowance": "0x39509351",
+	"permit":            "0xd505accf",
+}
+
+type PolicyLimit struct {
+	ChainId int            `json:"chain_id"`
+	Token   common.Address `json:"token"`
+	Native  bool           `json:"native"`
+	Amount  *big.Int       `json:"amount"` // per POLICY_SPEND_WINDOW
+}
+
+type Policy struct {
+	Address              common.Address   `json:"address"`
+	Signer               string           `json:"signer,omitempty"`
+	Mode                 string           `json:"mode"`
+	DailyLimits          []*PolicyLimit   `json:"daily_limits"`
+	Allowlist            []common.Address `json:"allowlist"`
+	AllowTrusted         bool             `json:"allow_trusted"` // trusted contracts pass the allowlist
+	ForbiddenSelectors   []string         `json:"forbidden_selectors"`
+	NoUnlimitedApprovals bool             `json:"no_unlimited_approvals"`
+}
+
+// PolicySpend records a sent amount for the daily limits
+type PolicySpend struct {
+	Time    time.Time      `json:"time"`
+	From    common.Address `json:"from"`
+	ChainId int            `json:"chain_id"`
+	Token   common.Address `json:"token"`
+	Native  bool           `json:"native"`
+	Amount  *big.Int       `json:"amount"`
+}
+
+type PolicyViolation struct {
+	Policy *Policy
+	Reason string
+}
+
+// PolicyRequest is a transaction to be checked against the policies
+type PolicyRequest struct {
+	ChainId int
+	From    common.Address
+	To      common.Address
+	Value   *big.Int
+	Data    []byte
+	Nested  []PolicyCall // calls decoded from Data (multicall, multiSend)
+
+	// a same-nonce replacement, the spending is recorded with the original
+	Replacement bool
+}
+
+// PolicyCall is a call made on behalf of the sender inside the transaction
+type PolicyCall struct {
+	To    common.Address
+	Value *big.Int
+	Data  []byte
+}
+
+// calls returns the transaction itself and the nested calls
+func (r *PolicyRequest) calls() []PolicyCall {
+	return append([]PolicyCall{{To: r.To, Value: r.Value, Data: r.Data}}, r.Nested...)
+}
+
+// Spending returns the token amounts the request moves out of the From
+// address, summed per token over the transaction and the nested calls. The
+// value of the transaction may fund the values of the nested calls (multicall
+// with value), so the larger of the two is counted as the native spending.
+func (r *PolicyRequest) Spending() []*PolicySpend {
+	res := []*PolicySpend{}
+
+	if r.Replacement {
+		return res
+	}
+
+	native := big.NewInt(0)
+	if r.Value != nil {
+		native.Set(r.Value)
+	}
+
+	nested := big.NewInt(0)
+	for _, c := range r.Nested {
+		if c.Value != nil && c.Value.Sign() > 0 {
+			nested.Add(nested, c.Value)
+		}
+	}
+
+	if nested.Cmp(native) > 0 {
+		native = nested
+	}
+
+	if native.Sign() > 0 {
+		res = append(res, &PolicySpend{From: r.From, ChainId: r.ChainId, Native: true, Amount: native})
+	}
+
+	tokens := map[common.Address]*PolicySpend{}
+	for _, c := range r.calls() {
+		if len(c.Data) < 4 {
+			continue
+		}
+
+		var amount *big.Int
+		switch {
+		case bytes.Equal(c.Data[:4], SELECTOR_TRANSFER):
+			amount = abiWord(c.Data, 1)
+		case bytes.Equal(c.Data[:4], SELECTOR_TRANSFER_FROM):
+			if from := abiWord(c.Data, 0); from != nil && common.BigToAddress(from) == r.From {
+				amount = abiWord(c.Data, 2)
+			}
+		}
+
+		if amount == nil || amount.Sign() <= 0 {
+			continue
+		}
+
+		if s, ok := tokens[c.To]; ok {
+			s.Amount.Add(s.Amount, amount)
+			continue
+		}
+
+		s := &PolicySpend{From: r.From, ChainId: r.ChainId, Token: c.To, Amount: new(big.Int).Set(amount)}
+		tokens[c.To] = s
+		res = append(res, s)
+	}
+
+	return res
+}
+
+// Counterparties returns the addresses the request sends to or approves,
+// in the transaction and in the nested calls
+func (r *PolicyRequest) Counterparties() []common.Address {
+	res := []common.Address{}
+
+	for i, c := range r.calls() {
+		if len(c.Data) < 4 {
+			// native values sent by the nested calls (disperseEther, multiSend)
+			if i > 0 && c.Value != nil && c.Value.Sign() > 0 && !slices.Contains(res, c.To) {
+				res = append(res, c.To)
+			}
+			continue
+		}
+
+		var a *big.Int
+		switch {
+		case bytes.Equal(c.Data[:4], SELECTOR_TRANSFER),
+			bytes.Equal(c.Data[:4], SELECTOR_APPROVE),
+			bytes.Equal(c.Data[:4], SELECTOR_SET_APPROVAL_FOR_ALL):
+			a = abiWord(c.Data, 0)
+		case bytes.Equal(c.Data[:4], SELECTOR_TRANSFER_FROM):
+			a = abiWord(c.Data, 1)
+		}
+
+		if a != nil && !slices.Contains(res, common.BigToAddress(a)) {
+			res = append(res, common.BigToAddress(a))
+		}
+	}
+
+	return res
+}
+
+// Selectors returns the methods called by the transaction and the nested calls
+func (r *PolicyRequest) Selectors() []string {
+	res := []string{}
+	for _, c := range r.calls() {
+		if len(c.Data) < 4 {
+			continue
+		}
+
+		sel := "0x" + hex.EncodeToString(c.Data[:4])
+		if !slices.Contains(res, sel) {
+			res = append(res, sel)
+		}
+	}
+	return res
+}
+
+// IsUnlimitedApproval is true for approve(spender, max) and
+// setApprovalForAll(operator, true), also nested in multicall or multiSend
+func (r *PolicyRequest) IsUnlimitedApproval() bool {
+	for _, c := range r.calls() {
+		if len(c.Data) < 4 {
+			continue
+		}
+
+		switch {
+		case bytes.Equal(c.Data[:4], SELECTOR_APPROVE):
+			if amount := abiWord(c.Data, 1); amount != nil && amount.Cmp(UNLIMITED_APPROVAL) >= 0 {
+				return true
+			}
+		case bytes.Equal(c.Data[:4], SELECTOR_SET_APPROVAL_FOR_ALL):
+			if approved := abiWord(c.Data, 1); approved != nil && approved.Sign() != 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// abiWord returns the n-th 32 byte argument of the call data
+func abiWord(data []byte, n int) *big.Int {
+	start := 4 + n*32
+	if len(data) < start+32 {
+		return nil
+	}
+	return new(big.Int).SetBytes(data[start : start+32])
+}
+
+// ParseSelector accepts a known method name, a signature like
+// "approve(address,uint256)" or a 4 byte hex selector
+func ParseSelector(s string) (string, error) {
+	if sel, ok := KNOWN_SELECTORS[s]; ok {
+		return sel, nil
+	}
+
+	if strings.Contains(s, "(") {
+		sig := strings.ReplaceAll(s, " ", "")
+		return "0x" + hex.EncodeToString(crypto.Keccak256([]byte(sig))[:4]), nil
+	}
+
+	b, err := hex.DecodeString(strings.TrimPrefix(strings.ToLower(s), "0x"))
+	if err != nil || len(b) != 4 {
+		return "", fmt.Errorf("invalid selector: %s", s)
+	}
+	return "0x" + hex.EncodeToString(b), nil
+}
+
+// PolicyName is the address or signer name the policy applies to
+func (w *Wallet) PolicyName(p *Policy) string {
+	if p.Signer != "" {
+		return p.Signer
+	}
+	if a := w.GetAddress(p.Address); a != nil {
+		return a.Name
+	}
+	return p.Address.String()
+}
+
+// GetPolicy returns the policy of the signer or address with this name
+func (w *Wallet) GetPolicy(name string) *Policy {
+	for _, p := range w.Policies {
+		if p.Signer != "" && p.Signer == name {
+			return p
+		}
+	}
+
+	a := w.GetAddressByName(name)
+	if a == nil {
+		return nil
+	}
+
+	for _, p := range w.Policies {
+		if p.Signer == "" && p.Address == a.Address {
+			return p
+		}
+	}
+	return nil
+}
+
+// GetPoliciesFor returns the policies that apply to the address
+func (w *Wallet) GetPoliciesFor(address common.Address) []*Policy {
+	res := []*Policy{}
+	a := w.GetAddress(address)
+
+	for _, p := range w.Policies {
+		if p.Signer == "" && p.Address == address {
+			res = append(res, p)
+		} else if p.Signer != "" && a != nil && a.Signer == p.Signer {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+func (p *Policy) GetLimit(chainId int, token common.Address, native bool) *PolicyLimit {
+	for _, l := range p.DailyLimits {
+		if l.ChainId == chainId && l.Native == native && (native || l.Token == token) {
+			return l
+		}
+	}
+	return nil
+}
+
+func (p *Policy) HasAllowlist() bool {
+	return len(p.Allowlist) > 0 || p.AllowTrusted
+}
+
+func (w *Wallet) policyAllows(p *Policy, a common.Address) bool {
+	return slices.Contains(p.Allowlist, a) || (p.AllowTrusted && w.IsContractTrusted(a))
+}
+
+func (w *Wallet) policyCovers(p *Policy, address common.Address) bool {
+	if p.Signer == "" {
+		return p.Address == address
+	}
+	a := w.GetAddress(address)
+	return a != nil && a.Signer == p.Signer
+}
+
+// SpentToday sums the amounts recorded within POLICY_SPEND_WINDOW for the policy
+func (w *Wallet) SpentToday(p *Policy, chainId int, token common.Address, native bool) *big.Int {
+	res := big.NewInt(0)
+	since := time.Now().Add(-POLICY_SPEND_WINDOW)
+
+	for _, s := range w.PolicySpends {
+		if s.Time.Before(since) || s.ChainId != chainId || s.Native != native || (!native && s.Token != token) {
+			continue
+		}
+		if w.policyCovers(p, s.From) {
+			res.Add(res, s.Amount)
+		}
+	}
+	return res
+}
+
+func (w *Wallet) policyTokenName(chainId int, token common.Address, native bool, amount *big.Int) string {
+	var t *Token
+	if native {
+		if b := w.GetBlockchain(chainId); b != nil {
+			t, _ = w.GetNativeToken(b)
+		}
+	} else {
+		t = w.GetTokenByAddress(chainId, token)
+	}
+
+	if t == nil {
+		return amount.String() + " of " + token.String()
+	}
+	return t.Value2Str(amount) + " " + t.Symbol
+}
+
+// CheckPolicies returns the policy violations of the transactions sent
+// together, their spending is summed against the daily limits
+func (w *Wallet) CheckPolicies(requests ...*PolicyRequest) []PolicyViolation {
+	res := []PolicyViolation{}
+	if len(requests) == 0 {
+		return res
+	}
+
+	add := func(p *Policy, reason string) {
+		if !slices.ContainsFunc(res, func(v PolicyViolation) bool { return v.Reason == reason }) {
+			res = append(res, PolicyViolation{p, reason})
+		}
+	}
+
+	// the spending of all the requests counts against the limits at once
+	type spendKey struct {
+		ChainId int
+		Token   common.Address
+		Native  bool
+	}
+
+	spending := map[spendKey]*big.Int{}
+	keys := []spendKey{}
+	for _, r := range requests {
+		for _, s := range r.Spending() {
+			k := spendKey{s.ChainId, s.Token, s.Native}
+			if a, ok := spending[k]; ok {
+				a.Add(a, s.Amount)
+				continue
+			}
+			spending[k] = new(big.Int).Set(s.Amount)
+			keys = append(keys, k)
+		}
+	}
+
+	for _, p := range w.GetPoliciesFor(requests[0].From) {
+		name := w.PolicyName(p)
+
+		for _, k := range keys {
+			l := p.GetLimit(k.ChainId, k.Token, k.Native)
+			if l == nil {
+				continue
+			}
+
+			total := new(big.Int).Add(w.SpentToday(p, k.ChainId, k.Token, k.Native), spending[k])
+			if total.Cmp(l.Amount) > 0 {
+				add(p, fmt.Sprintf("%s: daily limit %s exceeded (%s with this transaction)", name,
+					w.policyTokenName(k.ChainId, k.Token, k.Native, l.Amount),
+					w.policyTokenName(k.ChainId, k.Token, k.Native, total)))
+			}
+		}
+
+		for _, r := range requests {
+			if p.HasAllowlist() {
+				// calls to the wallet tokens are allowed, their recipients are checked below
+				if !w.policyAllows(p, r.To) && !(len(r.Data) >= 4 && w.GetTokenByAddress(r.ChainId, r.To) != nil) {
+					add(p, fmt.Sprintf("%s: %s is not in the allowlist", name, r.To.String()))
+				}
+
+				for _, a := range r.Counterparties() {
+					if !w.policyAllows(p, a) {
+						add(p, fmt.Sprintf("%s: %s is not in the allowlist", name, a.String()))
+					}
+				}
+			}
+
+			for _, sel := range r.Selectors() {
+				if slices.Contains(p.ForbiddenSelectors, sel) {
+					add(p, fmt.Sprintf("%s: method %s is forbidden", name, sel))
+				}
+			}
+
+			if p.NoUnlimitedApprovals && r.IsUnlimitedApproval() {
+				add(p, fmt.Sprintf("%s: unlimited approvals are forbidden", name))
+			}
+		}
+	}
+
+	return res
+}
+
+// CheckTypedDataPolicies returns the policy violations of the typed data
+func (w *Wallet) CheckTypedDataPolicies(from common.Address, td apitypes.TypedData) []PolicyViolation {
+	res := []PolicyViolation{}
+
+	contract := common.Address{}
+	if common.IsHexAddress(td.Domain.VerifyingContract) {
+		contract = common.HexToAddress(td.Domain.VerifyingContract)
+	}
+
+	chainId := w.CurrentChainId
+	if td.Domain.ChainId != nil {
+		chainId = int((*big.Int)(td.Domain.ChainId).Int64())
+	}
+
+	spender := common.Address{}
+	if s, ok := td.Message["spender"].(string); ok && common.IsHexAddress(s) {
+		spender = common.HexToAddress(s)
+	}
+
+	for _, p := range w.GetPoliciesFor(from) {
+		name := w.PolicyName(p)
+
+		if p.HasAllowlist() {
+			if contract != (common.Address{}) && !w.policyAllows(p, contract) &&
+				w.GetTokenByAddress(chainId, contract) == nil {
+				res = append(res, PolicyViolation{p, fmt.Sprintf("%s: %s is not in the allowlist", name, contract.String())})
+			}
+
+			if spender != (common.Address{}) && !w.policyAllows(p, spender) {
+				res = append(res, PolicyViolation{p, fmt.Sprintf("%s: %s is not in the allowlist", name, spender.String())})
+			}
+		}
+
+		if p.NoUnlimitedApprovals && isUnlimitedPermit(td) {
+			res = append(res, PolicyViolation{p, fmt.Sprintf("%s: unlimited permits are forbidden", name)})
+		}
+	}
+
+	return res
+}
+
+// isUnlimitedPermit detects EIP-2612 and Permit2 permits for the max amount
+func isUnlimitedPermit(td apitypes.TypedData) bool {
+	if !strings.HasPrefix(td.PrimaryType, "Permit") {
+		return false
+	}
+
+	var unlimited func(v interface{}) bool
+	unlimited = func(v interface{}) bool {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			for k, e := range v {
+				if k == "value" || k == "amount" {
+					if n, ok := toBig(e); ok && n.Cmp(UNLIMITED_APPROVAL) >= 0 {
+						return true
+					}
+				}
+				if unlimited(e) {
+					return true
+				}
+			}
+		case []interface{}:
+			for _, e := range v {
+				if unlimited(e) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	return unlimited(map[string]interface{}(td.Message))
+}
+
+func toBig(v interface{}) (*big.Int, bool) {
+	switch v := v.(type) {
+	case string:
+		return new(big.Int).SetString(v, 0)
+	case float64:
+		f := new(big.Float).SetFloat64(v)
+		n, _ := f.Int(nil)
+		return n, true
+	case *big.Int:
+		return v, v != nil
+	}
+	return nil, false
+}
+
+// RecordPolicySpend stores what the sent transaction spent and drops the
+// records older than POLICY_SPEND_WINDOW
+func (w *Wallet) RecordPolicySpend(r *PolicyRequest) error {
+	w.writeMutex.Lock()
+	defer w.writeMutex.Unlock()
+
+	if len(w.GetPoliciesFor(r.From)) == 0 {
+		return nil
+	}
+
+	since := time.Now().Add(-POLICY_SPEND_WINDOW)
+	spends := []*PolicySpend{}
+	for _, s := range w.PolicySpends {
+		if !s.Time.Before(since) {
+			spends = append(spends, s)
+		}
+	}
+
+	for _, s := range r.Spending() {
+		s.Time = time.Now()
+		spends = append(spends, s)
+	}
+
+	w.PolicySpends = spends
+	return w._locked_Save()
+}
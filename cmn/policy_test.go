@@ -0,0 +1,206 @@
+package cmn
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	policyFrom  = common.HexToAddress("0x1000000000000000000000000000000000000001")
+	policyTo    = common.HexToAddress("0x2000000000000000000000000000000000000002")
+	policyToken = common.HexToAddress("0x3000000000000000000000000000000000000003")
+	policyOther = common.HexToAddress("0x4000000000000000000000000000000000000004")
+	policyBatch = common.HexToAddress("0x5000000000000000000000000000000000000005")
+)
+
+// policyCall packs the selector and the 32 byte arguments
+func policyCall(selector []byte, args ...*big.Int) []byte {
+	data := append([]byte{}, selector...)
+	for _, a := range args {
+		data = append(data, common.BigToHash(a).Bytes()...)
+	}
+	return data
+}
+
+func policyAddr(a common.Address) *big.Int {
+	return new(big.Int).SetBytes(a.Bytes())
+}
+
+func policyTransfer(to common.Address, amount int64) []byte {
+	return policyCall(SELECTOR_TRANSFER, policyAddr(to), big.NewInt(amount))
+}
+
+func policyTransferFrom(from, to common.Address, amount int64) []byte {
+	return policyCall(SELECTOR_TRANSFER_FROM, policyAddr(from), policyAddr(to), big.NewInt(amount))
+}
+
+func TestPolicySpending(t *testing.T) {
+	tests := []struct {
+		name   string
+		req    *PolicyRequest
+		native int64
+		tokens map[common.Address]int64
+	}{
+		{
+			name:   "native",
+			req:    &PolicyRequest{To: policyTo, Value: big.NewInt(5)},
+			native: 5,
+		},
+		{
+			name:   "transfer",
+			req:    &PolicyRequest{To: policyToken, Data: policyTransfer(policyTo, 7)},
+			tokens: map[common.Address]int64{policyToken: 7},
+		},
+		{
+			name:   "transferFrom of another owner",
+			req:    &PolicyRequest{To: policyToken, Data: policyTransferFrom(policyOther, policyTo, 7)},
+			tokens: map[common.Address]int64{},
+		},
+		{
+			name: "nested transfers are summed per token",
+			req: &PolicyRequest{To: policyBatch, Data: []byte{1, 2, 3, 4}, Nested: []PolicyCall{
+				{To: policyToken, Data: policyTransfer(policyTo, 3)},
+				{To: policyToken, Data: policyTransfer(policyOther, 4)},
+				{To: policyOther, Data: policyTransferFrom(policyFrom, policyTo, 9)},
+				{To: policyOther, Data: policyTransferFrom(policyTo, policyFrom, 100)},
+			}},
+			tokens: map[common.Address]int64{policyToken: 7, policyOther: 9},
+		},
+		{
+			name: "nested values",
+			req: &PolicyRequest{To: policyBatch, Nested: []PolicyCall{
+				{To: policyTo, Value: big.NewInt(2)},
+				{To: policyOther, Value: big.NewInt(3)},
+			}},
+			native: 5,
+		},
+		{
+			name: "nested values funded by the transaction",
+			req: &PolicyRequest{To: policyBatch, Value: big.NewInt(5), Nested: []PolicyCall{
+				{To: policyTo, Value: big.NewInt(2)},
+				{To: policyOther, Value: big.NewInt(3)},
+			}},
+			native: 5,
+		},
+		{
+			name: "replacement",
+			req: &PolicyRequest{To: policyToken, Value: big.NewInt(5), Data: policyTransfer(policyTo, 7),
+				Replacement: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.From = policyFrom
+
+			native := int64(0)
+			tokens := map[common.Address]int64{}
+			for _, s := range tt.req.Spending() {
+				if s.From != policyFrom {
+					t.Errorf("spend from %s", s.From.Hex())
+				}
+				if s.Native {
+					native += s.Amount.Int64()
+				} else {
+					if _, ok := tokens[s.Token]; ok {
+						t.Errorf("token %s listed twice", s.Token.Hex())
+					}
+					tokens[s.Token] = s.Amount.Int64()
+				}
+			}
+
+			if native != tt.native {
+				t.Errorf("native %d, want %d", native, tt.native)
+			}
+
+			if len(tokens) != len(tt.tokens) {
+				t.Fatalf("tokens %v, want %v", tokens, tt.tokens)
+			}
+			for a, n := range tt.tokens {
+				if tokens[a] != n {
+					t.Errorf("token %s %d, want %d", a.Hex(), tokens[a], n)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckPoliciesNestedLimit(t *testing.T) {
+	w := &Wallet{
+		Policies: []*Policy{{
+			Address: policyFrom,
+			Mode:    POLICY_MODE_BLOCK,
+			DailyLimits: []*PolicyLimit{
+				{ChainId: 1, Token: policyToken, Amount: big.NewInt(10)},
+			},
+		}},
+		PolicySpends: []*PolicySpend{
+			{Time: time.Now(), From: policyFrom, ChainId: 1, Token: policyToken, Amount: big.NewInt(4)},
+			{Time: time.Now().Add(-2 * POLICY_SPEND_WINDOW), From: policyFrom, ChainId: 1, Token: policyToken,
+				Amount: big.NewInt(100)},
+		},
+	}
+
+	// a multiSend of transfers each under the limit
+	r := &PolicyRequest{ChainId: 1, From: policyFrom, To: policyBatch, Nested: []PolicyCall{
+		{To: policyToken, Data: policyTransfer(policyTo, 3)},
+		{To: policyToken, Data: policyTransfer(policyOther, 3)},
+	}}
+
+	if v := w.CheckPolicies(r); len(v) != 0 {
+		t.Fatalf("6 of 10 with 4 spent today: %v", v[0].Reason)
+	}
+
+	r.Nested = append(r.Nested, PolicyCall{To: policyToken, Data: policyTransferFrom(policyFrom, policyTo, 1)})
+
+	v := w.CheckPolicies(r)
+	if len(v) != 1 || !strings.Contains(v[0].Reason, "daily limit") {
+		t.Fatalf("violations %v, want the daily limit", v)
+	}
+}
+
+func TestCheckPoliciesBatch(t *testing.T) {
+	w := &Wallet{
+		Policies: []*Policy{{
+			Address: policyFrom,
+			Mode:    POLICY_MODE_BLOCK,
+			DailyLimits: []*PolicyLimit{
+				{ChainId: 1, Native: true, Amount: big.NewInt(10)},
+			},
+			Allowlist: []common.Address{policyTo, policyBatch},
+		}},
+		PolicySpends: []*PolicySpend{
+			{Time: time.Now(), From: policyFrom, ChainId: 1, Native: true, Amount: big.NewInt(4)},
+		},
+	}
+
+	// payments each under the limit, sent as separate transactions
+	payment := func(amount int64) *PolicyRequest {
+		return &PolicyRequest{ChainId: 1, From: policyFrom, To: policyTo, Value: big.NewInt(amount)}
+	}
+
+	if v := w.CheckPolicies(payment(3), payment(3)); len(v) != 0 {
+		t.Fatalf("6 of 10 with 4 spent today: %v", v[0].Reason)
+	}
+
+	v := w.CheckPolicies(payment(3), payment(3), payment(1))
+	if len(v) != 1 || !strings.Contains(v[0].Reason, "daily limit") {
+		t.Fatalf("violations %v, want the daily limit once", v)
+	}
+
+	// the recipients of the native values of the nested calls are checked
+	r := &PolicyRequest{ChainId: 1, From: policyFrom, To: policyBatch, Value: big.NewInt(2), Data: []byte{1, 2, 3, 4},
+		Nested: []PolicyCall{
+			{To: policyTo, Value: big.NewInt(1)},
+			{To: policyOther, Value: big.NewInt(1)},
+		}}
+
+	v = w.CheckPolicies(r)
+	if len(v) != 1 || !strings.Contains(v[0].Reason, policyOther.String()) {
+		t.Fatalf("violations %v, want %s not in the allowlist", v, policyOther.String())
+	}
+}
@@ -81,6 +81,7 @@ var PredefinedBlockchains []Blockchain = []Blockchain{
 		Currency:      "ETH",
 		WTokenAddress: common.HexToAddress("0x4200000000000000000000000000000000000006"),
 		Multicall:     common.HexToAddress("0xcA11bde05977b3631167028862bE2a173976CA11"),
+		FeeModel:      FEE_MODEL_OP_STACK,
 	}, {
 		Name:        "Fantom Opera",
 		ShortName:   "FTM",
@@ -125,6 +126,7 @@ var PredefinedBlockchains []Blockchain = []Blockchain{
 		ExplorerUrl: "https://arbiscan.io,https://explorer.arbitrum.io",
 		Currency:    "ETH",
 		Multicall:   common.HexToAddress("0xcA11bde05977b3631167028862bE2a173976CA11"),
+		FeeModel:    FEE_MODEL_ARBITRUM,
 	},
 	{
 		Name:        "Klaytn Mainnet Cypress",
@@ -178,6 +180,7 @@ var PredefinedBlockchains []Blockchain = []Blockchain{
 		ExplorerUrl: "https://optimistic.etherscan.io",
 		Currency:    "ETH",
 		Multicall:   common.HexToAddress("0xcA11bde05977b3631167028862bE2a173976CA11"),
+		FeeModel:    FEE_MODEL_OP_STACK,
 	},
 	{
 		Name:        "Gnosis Chain formerly xDai",
@@ -627,6 +630,7 @@ var PredefinedBlockchains []Blockchain = []Blockchain{
 		ChainId:     204,
 		ExplorerUrl: "https://opbnbscan.com/",
 		Currency:    "BNB",
+		FeeModel:    FEE_MODEL_OP_STACK,
 	},
 	{
 		Name:        "Arbitrum Nova",
@@ -636,6 +640,7 @@ var PredefinedBlockchains []Blockchain = []Blockchain{
 		ExplorerUrl: "https://nova-explorer.arbitrum.io",
 		Currency:    "ETH",
 		Multicall:   common.HexToAddress("0xcA11bde05977b3631167028862bE2a173976CA11"),
+		FeeModel:    FEE_MODEL_ARBITRUM,
 	},
 	{
 		Name:        "ZKFair Mainnet",
@@ -1314,6 +1319,7 @@ var PredefinedBlockchains []Blockchain = []Blockchain{
 		ChainId:     1135,
 		ExplorerUrl: "https://blockscout.lisk.com",
 		Currency:    "LSK",
+		FeeModel:    FEE_MODEL_OP_STACK,
 	},
 	{
 		Name:        "REI Network",
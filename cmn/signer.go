@@ -1,12 +1,29 @@
 package cmn
 
+import (
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
 type Signer struct {
 	Name      string   `json:"name"`
 	Type      string   `json:"type"`
 	MasterKey string   `json:"master-key"`
 	Copies    []string `json:"copies"`
+
+	// BIP39 passphrase of mnemonics signers. If AskPassphrase is set, the
+	// passphrase is not stored and must be entered once per session.
+	Passphrase    string         `json:"passphrase,omitempty"`
+	AskPassphrase bool           `json:"ask_passphrase,omitempty"`
+	Fingerprint   common.Address `json:"fingerprint,omitempty"` // first address, m/44'/60'/0'/0/0
 }
 
+// session passphrases by sessionKey, the name changes on rename and promote
+var sessionPassphrases = map[string]string{}
+var sessionPassphrasesMutex sync.Mutex
+
 var STANDARD_DERIVATIONS = map[string]struct {
 	Name   string
 	Format string
@@ -30,3 +47,56 @@ func (s *Signer) GetAllCopies() []string {
 	r = append(r, s.Copies...)
 	return r
 }
+
+func (s *Signer) HasPassphrase() bool {
+	return s.Passphrase != "" || s.AskPassphrase
+}
+
+// sessionKey identifies the seed and the passphrase: the signers sharing a
+// mnemonic with different passphrases have different fingerprints
+func (s *Signer) sessionKey() string {
+	return s.MasterKey + "/" + s.Fingerprint.Hex()
+}
+
+// GetPassphrase returns the stored passphrase, or the one entered in this
+// session
+func (s *Signer) GetPassphrase() (string, error) {
+	if !s.AskPassphrase {
+		return s.Passphrase, nil
+	}
+
+	sessionPassphrasesMutex.Lock()
+	defer sessionPassphrasesMutex.Unlock()
+
+	p, ok := sessionPassphrases[s.sessionKey()]
+	if !ok {
+		return "", fmt.Errorf("signer %s is locked, use 'signer unlock %s'", s.Name, s.Name)
+	}
+	return p, nil
+}
+
+func (s *Signer) IsUnlocked() bool {
+	_, err := s.GetPassphrase()
+	return err == nil
+}
+
+func (s *Signer) SetSessionPassphrase(p string) {
+	sessionPassphrasesMutex.Lock()
+	defer sessionPassphrasesMutex.Unlock()
+
+	sessionPassphrases[s.sessionKey()] = p
+}
+
+func (s *Signer) Lock() {
+	sessionPassphrasesMutex.Lock()
+	defer sessionPassphrasesMutex.Unlock()
+
+	delete(sessionPassphrases, s.sessionKey())
+}
+
+func ClearSessionPassphrases() {
+	sessionPassphrasesMutex.Lock()
+	defer sessionPassphrasesMutex.Unlock()
+
+	sessionPassphrases = map[string]string{}
+}
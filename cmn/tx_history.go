@@ -0,0 +1,153 @@
+package cmn
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Transaction journal. Every broadcast transaction is recorded in the wallet
+// and stays pending until the tracker finds its receipt.
+
+const (
+	TX_STATUS_PENDING   = "pending"
+	TX_STATUS_CONFIRMED = "confirmed"
+	TX_STATUS_REVERTED  = "reverted"
+	TX_STATUS_REPLACED  = "replaced" // another tx with the same nonce was mined
+	TX_STATUS_DROPPED   = "dropped"  // the nonce was used by a tx not in the journal
+)
+
+// TX_HISTORY_SIZE is the number of transactions kept in the journal
+const TX_HISTORY_SIZE = 1000
+
+type TxRecord struct {
+	Hash    common.Hash    `json:"hash"`
+	ChainId int            `json:"chain_id"`
+	From    common.Address `json:"from"`
+	To      common.Address `json:"to"` // zero for contract creation
+	Nonce   uint64         `json:"nonce"`
+	Value   *big.Int       `json:"value"`
+	Method  string         `json:"method"`
+	Origin  string         `json:"origin"` // dApp URL, empty for the wallet itself
+	Time    time.Time      `json:"time"`
+	Status  string         `json:"status"`
+	GasUsed uint64         `json:"gas_used"`
+	Block   uint64         `json:"block"`
+	Fee     *big.Int       `json:"fee"`
+
+	Replaces   common.Hash `json:"replaces,omitempty"`    // speed-up or cancel of this tx
+	ReplacedBy common.Hash `json:"replaced_by,omitempty"` // latest replacement sent
+}
+
+func (r *TxRecord) IsPending() bool {
+	return r.Status == TX_STATUS_PENDING
+}
+
+func (r *TxRecord) IsMined() bool {
+	return r.Status == TX_STATUS_CONFIRMED || r.Status == TX_STATUS_REVERTED
+}
+
+// AddTxRecord appends the transaction to the journal, dropping the oldest
+// records above TX_HISTORY_SIZE
+func (w *Wallet) AddTxRecord(r *TxRecord) error {
+	w.writeMutex.Lock()
+	defer w.writeMutex.Unlock()
+
+	if r.Status == "" {
+		r.Status = TX_STATUS_PENDING
+	}
+
+	w.Transactions = append(w.Transactions, r)
+	if len(w.Transactions) > TX_HISTORY_SIZE {
+		w.Transactions = w.Transactions[len(w.Transactions)-TX_HISTORY_SIZE:]
+	}
+
+	return w._locked_Save()
+}
+
+// GetTxRecord finds the transaction by the full hash or a hash prefix
+func (w *Wallet) GetTxRecord(hash string) *TxRecord {
+	hash = strings.ToLower(hash)
+	if hash == "" || hash == "0x" {
+		return nil
+	}
+
+	for i := len(w.Transactions) - 1; i >= 0; i-- {
+		if strings.HasPrefix(strings.ToLower(w.Transactions[i].Hash.Hex()), hash) {
+			return w.Transactions[i]
+		}
+	}
+	return nil
+}
+
+// PendingTxRecords returns the transactions waiting for a receipt
+func (w *Wallet) PendingTxRecords() []*TxRecord {
+	w.writeMutex.Lock()
+	defer w.writeMutex.Unlock()
+
+	list := []*TxRecord{}
+	for _, r := range w.Transactions {
+		if r.IsPending() {
+			list = append(list, r)
+		}
+	}
+	return list
+}
+
+// LinkTxReplacement marks the transaction as replaced by a same-nonce one
+func (w *Wallet) LinkTxReplacement(original, replacement common.Hash) error {
+	w.writeMutex.Lock()
+	defer w.writeMutex.Unlock()
+
+	var o, r *TxRecord
+	for _, t := range w.Transactions {
+		switch t.Hash {
+		case original:
+			o = t
+		case replacement:
+			r = t
+		}
+	}
+
+	if o == nil || r == nil {
+		return errors.New("transaction not found")
+	}
+
+	o.ReplacedBy = replacement
+	r.Replaces = original
+	return w._locked_Save()
+}
+
+// UpdateTxRecord changes the record under the wallet lock. Once the record
+// is mined, the other pending transactions with its nonce are marked as
+// replaced. The wallet is not saved.
+func (w *Wallet) UpdateTxRecord(hash common.Hash, update func(r *TxRecord)) error {
+	w.writeMutex.Lock()
+	defer w.writeMutex.Unlock()
+
+	for _, r := range w.Transactions {
+		if r.Hash == hash {
+			update(r)
+			if r.IsMined() {
+				w._locked_ResolveNonce(r)
+			}
+			return nil
+		}
+	}
+
+	return errors.New("transaction not found")
+}
+
+// _locked_ResolveNonce marks the other pending transactions with the nonce
+// of the mined one as replaced
+func (w *Wallet) _locked_ResolveNonce(mined *TxRecord) {
+	for _, t := range w.Transactions {
+		if t != mined && t.IsPending() && t.ChainId == mined.ChainId &&
+			t.From == mined.From && t.Nonce == mined.Nonce {
+			t.Status = TX_STATUS_REPLACED
+		}
+	}
+}
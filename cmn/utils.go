@@ -265,6 +265,17 @@ func (b *Blockchain) ExplorerLink(address common.Address) string {
 
 }
 
+func (b *Blockchain) ExplorerTxLink(hash common.Hash) string {
+	if b.ExplorerUrl == "" {
+		return ""
+	}
+	if strings.HasSuffix(b.ExplorerUrl, "/") {
+		return b.ExplorerUrl + "tx/" + hash.Hex()
+	}
+
+	return b.ExplorerUrl + "/tx/" + hash.Hex()
+}
+
 // GetShortName returns ShortName if set, otherwise falls back to Currency
 func (b *Blockchain) GetShortName() string {
 	if b.ShortName != "" {
@@ -297,6 +308,9 @@ func AddAddressLink(v *gocui.View, a common.Address) {
 func AddAddressShortLink(v *gocui.View, a common.Address) {
 	s := a.String()
 	v.AddLink(s[:6]+ICON_3DOTS+s[len(s)-4:], "copy "+a.String(), a.String(), "")
+	if name := lookupAddressName(a); name != "" {
+		fmt.Fprintf(v, " %s", name)
+	}
 }
 
 func AddFixedAddressShortLink(v *gocui.View, a common.Address, width int) {
@@ -313,8 +327,24 @@ func TagLink(text, action, tip string) string {
 func TagAddressShortLink(a common.Address) string {
 	s := a.String()
 
-	return fmt.Sprintf("<l text:'%s%s%s' action:'copy %s' tip:'%s'>",
+	r := fmt.Sprintf("<l text:'%s%s%s' action:'copy %s' tip:'%s'>",
 		s[:6], ICON_3DOTS, s[len(s)-4:], a.String(), a.String())
+
+	if name := lookupAddressName(a); name != "" {
+		r += " " + name
+	}
+	return r
+}
+
+// AddressNameLookup returns the public name of a counterparty address (ENS
+// primary name), set by the eth package. It must not block.
+var AddressNameLookup func(a common.Address) string
+
+func lookupAddressName(a common.Address) string {
+	if AddressNameLookup == nil {
+		return ""
+	}
+	return AddressNameLookup(a)
 }
 
 func TagBytesLink(b []byte) string {
@@ -37,6 +37,11 @@ func Open(name string, pass string) error {
 	defer w.writeMutex.Unlock()
 
 	if w != nil {
+		if err := w._locked_Migrate(); err != nil {
+			log.Error().Msgf("Error migrating wallet: %v\n", err)
+			return err
+		}
+
 		w._locked_AuditNativeTokens()
 		if w.CurrentChainId == 0 || w.GetBlockchain(w.CurrentChainId) == nil {
 			if len(w.Blockchains) > 0 {
@@ -61,6 +66,7 @@ func Open(name string, pass string) error {
 			}
 		}
 
+		ClearSessionPassphrases()
 		CurrentWallet = w
 
 		bus.Send("wallet", "open", nil)
@@ -104,33 +110,6 @@ func (w *Wallet) _locked_AuditNativeTokens() {
 
 	eddited := false
 
-	// Migrate missing ShortNames and Multicall addresses from predefined chains
-	for _, b := range w.Blockchains {
-		for _, predefined := range PredefinedBlockchains {
-			if predefined.ChainId == b.ChainId {
-				if b.ShortName == "" && predefined.ShortName != "" {
-					b.ShortName = predefined.ShortName
-					eddited = true
-				}
-				if b.Multicall == (common.Address{}) && predefined.Multicall != (common.Address{}) {
-					b.Multicall = predefined.Multicall
-					eddited = true
-				}
-				break
-			}
-		}
-	}
-
-	// remove doubles
-	for i, t := range w.Tokens {
-		for j, tt := range w.Tokens {
-			if i != j && t.ChainId == tt.ChainId && t.Symbol == tt.Symbol {
-				w.Tokens = append(w.Tokens[:j], w.Tokens[j+1:]...)
-				eddited = true
-			}
-		}
-	}
-
 	for _, b := range w.Blockchains { // audit native tokens
 		found := false
 		for _, t := range w.Tokens {
@@ -242,6 +221,9 @@ func Exists(name string) bool {
 
 func Create(name, pass string) error {
 	w := &Wallet{}
+	if err := MigrateWallet(w); err != nil {
+		return err
+	}
 
 	return SaveToFile(w, DataFolder+"/wallets/"+name, pass)
 }
@@ -451,7 +433,7 @@ func WalletList() []string {
 
 	names := []string{}
 	for _, file := range files {
-		if file.IsDir() {
+		if file.IsDir() || strings.HasPrefix(file.Name(), ".") { // skip temp files
 			continue
 		}
 		names = append(names, file.Name())
@@ -461,15 +443,14 @@ func WalletList() []string {
 }
 
 func BackupList() []string {
-	backupDir := DataFolder + "/wallets/backups"
-	files, err := os.ReadDir(backupDir)
+	files, err := os.ReadDir(BackupFolder())
 	if err != nil {
 		return nil
 	}
 
 	names := []string{}
 	for _, file := range files {
-		if file.IsDir() {
+		if file.IsDir() || strings.HasPrefix(file.Name(), ".") { // skip temp files
 			continue
 		}
 		names = append(names, file.Name())
@@ -483,7 +464,7 @@ func OpenFromFile(filePath string, pass string) (*Wallet, error) {
 	return openFromFile(filePath, pass)
 }
 
-func encrypt(data []byte, passphrase []byte) ([]byte, error) {
+func encrypt(data []byte, passphrase []byte, additionalData []byte) ([]byte, error) {
 	block, err := aes.NewCipher([]byte(passphrase))
 	if err != nil {
 		return nil, err
@@ -499,11 +480,11 @@ func encrypt(data []byte, passphrase []byte) ([]byte, error) {
 		return nil, err
 	}
 
-	ciphertext := gcm.Seal(nonce, nonce, data, nil)
+	ciphertext := gcm.Seal(nonce, nonce, data, additionalData)
 	return ciphertext, nil
 }
 
-func decrypt(data []byte, passphrase []byte) ([]byte, error) {
+func decrypt(data []byte, passphrase []byte, additionalData []byte) ([]byte, error) {
 	block, err := aes.NewCipher([]byte(passphrase))
 	if err != nil {
 		return nil, err
@@ -516,11 +497,11 @@ func decrypt(data []byte, passphrase []byte) ([]byte, error) {
 
 	nonceSize := gcm.NonceSize()
 	if len(data) < nonceSize {
-		return nil, err
+		return nil, errors.New("ciphertext is too short")
 	}
 
 	nonce, ciphertext := data[:nonceSize], data[nonceSize:]
-	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, additionalData)
 	if err != nil {
 		return nil, err
 	}
@@ -528,9 +509,9 @@ func decrypt(data []byte, passphrase []byte) ([]byte, error) {
 	return plaintext, nil
 }
 
-// GenerateKey derives a key from a password using PBKDF2
+// GenerateKey derives a key from a password using PBKDF2 (legacy wallet files)
 func generateKey(password string, salt []byte) []byte {
-	return pbkdf2.Key([]byte(password), salt, 4096, 32, sha256.New)
+	return pbkdf2.Key([]byte(password), salt, LEGACY_PBKDF2_ITERATIONS, 32, sha256.New)
 }
 
 func SaveToFile(w *Wallet, file, pass string) error {
@@ -548,23 +529,34 @@ func _locked_SaveToFile(w *Wallet, file, pass string) error {
 		return err
 	}
 
-	solt := make([]byte, SOLT_SIZE)
-	_, err = rand.Read(solt)
-	if err != nil {
-		log.Error().Msgf("Error generating salt: %v\n", err)
-		return err
-	}
+	// the KDF is expensive, so the key is derived once per password
+	if w.fileHeader == nil || w.fileKey == nil || w.fileKeyPass != pass {
+		h, err := defaultWalletHeader()
+		if err != nil {
+			log.Error().Msgf("Error generating salt: %v\n", err)
+			return err
+		}
 
-	key := generateKey(pass, solt)
+		key, err := h.DeriveKey(pass)
+		if err != nil {
+			log.Error().Msgf("Error deriving key: %v\n", err)
+			return err
+		}
+
+		w.fileHeader = h
+		w.fileKey = key
+		w.fileKeyPass = pass
+	}
 
-	encrypted, err := encrypt(jsonData, key)
+	sealed, err := sealWallet(w.fileHeader, w.fileKey, jsonData)
 	if err != nil {
 		log.Error().Msgf("Error encrypting data: %v\n", err)
 		return err
 	}
 
-	// write with the soltencrypted
-	err = os.WriteFile(file, append(solt, encrypted...), 0644)
+	autoBackup(file, false)
+
+	err = writeFileAtomic(file, sealed)
 	if err != nil {
 		log.Error().Msgf("Error writing file: %v\n", err)
 		return err
@@ -581,12 +573,7 @@ func openFromFile(file string, pass string) (*Wallet, error) {
 		return nil, err
 	}
 
-	solt := data[:SOLT_SIZE]
-	data = data[SOLT_SIZE:]
-
-	key := generateKey(pass, solt)
-
-	decrypted, err := decrypt(data, key)
+	decrypted, h, key, err := openWallet(data, pass)
 	if err != nil {
 		log.Error().Msgf("Error decrypting data: %v\n", err)
 		return nil, err
@@ -598,68 +585,21 @@ func openFromFile(file string, pass string) (*Wallet, error) {
 		writeMutex: sync.Mutex{},
 	}
 
+	if h != nil && h.KDF == KDF_ARGON2ID {
+		w.fileHeader = h
+		w.fileKey = key
+		w.fileKeyPass = pass
+	} else {
+		// legacy or weaker KDF: re-encrypted with the default KDF on the next save
+		log.Info().Msgf("Wallet %s will be upgraded to format v%d on the next save", file, WALLET_FORMAT_VERSION)
+	}
+
 	err = json.Unmarshal(decrypted, w)
 	if err != nil {
 		log.Error().Msgf("Error unmarshaling JSON: %v\n", err)
 		return nil, err
 	}
 
-	if w.Contracts == nil {
-		w.Contracts = make(map[common.Address]*Contract)
-	}
-
-	if w.Tokens == nil {
-		w.Tokens = []*Token{}
-	}
-
-	if w.Addresses == nil {
-		w.Addresses = []*Address{}
-	}
-
-	if w.Signers == nil {
-		w.Signers = []*Signer{}
-	}
-
-	if w.Origins == nil {
-		w.Origins = []*Origin{}
-	}
-
-	if w.Blockchains == nil {
-		w.Blockchains = []*Blockchain{}
-	}
-
-	if w.LP_V3_Providers == nil {
-		w.LP_V3_Providers = []*LP_V3{}
-	}
-
-	if w.LP_V3_Positions == nil {
-		w.LP_V3_Positions = []*LP_V3_Position{}
-	}
-
-	if w.LP_V4_Providers == nil {
-		w.LP_V4_Providers = []*LP_V4{}
-	}
-
-	if w.LP_V4_Positions == nil {
-		w.LP_V4_Positions = []*LP_V4_Position{}
-	}
-
-	if w.Stakings == nil {
-		w.Stakings = []*Staking{}
-	}
-
-	if w.StakingPositions == nil {
-		w.StakingPositions = []*StakingPosition{}
-	}
-
-	if w.ParamInt == nil {
-		w.ParamInt = make(map[string]int)
-	}
-
-	if w.ParamStr == nil {
-		w.ParamStr = make(map[string]string)
-	}
-
 	return w, nil
 }
 
@@ -757,6 +697,27 @@ func (w *Wallet) GetAddressByName(n string) *Address {
 	return w.GetAddress(n)
 }
 
+func (s *Safe) IsOwner(a common.Address) bool {
+	for _, o := range s.Owners {
+		if o == a {
+			return true
+		}
+	}
+	return false
+}
+
+// GetSafeSigners returns the owners of the Safe that can sign with this wallet
+func (w *Wallet) GetSafeSigners(s *Safe) []*Address {
+	res := []*Address{}
+	for _, o := range s.Owners {
+		a := w.GetAddress(o)
+		if a != nil && a.Signer != "" && w.GetSigner(a.Signer) != nil {
+			res = append(res, a)
+		}
+	}
+	return res
+}
+
 func (w *Wallet) GetToken(chain int, a string) *Token {
 	if common.IsHexAddress(a) {
 		t := w.GetTokenByAddress(chain, common.HexToAddress(a))
@@ -933,6 +894,9 @@ func (w *Wallet) EditBlockchain(ub *Blockchain) error {
 	b.Multicall = ub.Multicall
 	b.RPCRateLimit = ub.RPCRateLimit
 	b.RPCRateAuto = ub.RPCRateAuto
+	b.FeePreset = ub.FeePreset
+	b.TxType = ub.TxType
+	b.FeeModel = ub.FeeModel
 
 	w._locked_AuditNativeTokens()
 
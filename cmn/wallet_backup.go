@@ -0,0 +1,161 @@
+package cmn
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const BACKUP_TIME_FORMAT = "2006-01-02_15-04-05.000"
+const AUTO_BACKUP_SUFFIX = "_auto"
+
+// Format: walletName[_auto]_YYYY-MM-DD_HH-MM-SS[.mmm], the older backups
+// have no milliseconds
+var backupNameRe = regexp.MustCompile(`(` + AUTO_BACKUP_SUFFIX + `)?_\d{4}-\d{2}-\d{2}_\d{2}-\d{2}-\d{2}(\.\d+)?$`)
+
+func BackupFolder() string {
+	return DataFolder + "/wallets/backups"
+}
+
+// BackupWalletName extracts the wallet name from a backup file name
+func BackupWalletName(backupFile string) string {
+	return backupNameRe.ReplaceAllString(backupFile, "")
+}
+
+func IsAutoBackup(backupFile string) bool {
+	m := backupNameRe.FindStringSubmatch(backupFile)
+	return len(m) > 1 && m[1] != ""
+}
+
+// BackupTime returns the timestamp encoded in the backup file name
+func BackupTime(backupFile string) (time.Time, error) {
+	m := backupNameRe.FindString(backupFile)
+	if m == "" {
+		return time.Time{}, errors.New("not a backup file name")
+	}
+	m = strings.TrimPrefix(m, AUTO_BACKUP_SUFFIX)
+	// the layout without milliseconds parses both forms
+	return time.ParseInLocation("2006-01-02_15-04-05", m[1:], time.Local)
+}
+
+// WalletBackupList returns manual and automatic backups of the wallet, newest first
+func WalletBackupList(name string) []string {
+	res := []string{}
+	for _, f := range BackupList() {
+		if BackupWalletName(f) == name {
+			res = append(res, f)
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		ti, _ := BackupTime(res[i])
+		tj, _ := BackupTime(res[j])
+		return ti.After(tj)
+	})
+
+	return res
+}
+
+// BackupWallet copies the (already encrypted) wallet file to the backup folder
+func BackupWallet(file string, auto bool) (string, error) {
+	if err := os.MkdirAll(BackupFolder(), 0700); err != nil {
+		return "", fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to read wallet file: %w", err)
+	}
+
+	name := filepath.Base(file)
+	if auto {
+		name += AUTO_BACKUP_SUFFIX
+	}
+
+	// two saves in the same millisecond must not overwrite each other
+	t := time.Now()
+	backupPath := BackupFolder() + "/" + name + "_" + t.Format(BACKUP_TIME_FORMAT)
+	for {
+		if _, err := os.Stat(backupPath); err != nil {
+			break
+		}
+		t = t.Add(time.Millisecond)
+		backupPath = BackupFolder() + "/" + name + "_" + t.Format(BACKUP_TIME_FORMAT)
+	}
+
+	if err := writeFileAtomic(backupPath, data); err != nil {
+		return "", fmt.Errorf("failed to write backup file: %w", err)
+	}
+
+	return backupPath, nil
+}
+
+// autoBackup keeps the last Config.WalletBackups versions of the wallet file,
+// at most one per Config.WalletBackupInterval unless forced
+func autoBackup(file string, force bool) {
+	if Config.WalletBackups <= 0 {
+		return
+	}
+
+	if _, err := os.Stat(file); err != nil {
+		return // nothing to back up yet
+	}
+
+	name := filepath.Base(file)
+
+	if !force {
+		for _, b := range WalletBackupList(name) {
+			if !IsAutoBackup(b) {
+				continue
+			}
+			if t, err := BackupTime(b); err == nil && time.Since(t) < Config.WalletBackupInterval {
+				return
+			}
+			break
+		}
+	}
+
+	if _, err := BackupWallet(file, true); err != nil {
+		log.Error().Err(err).Msgf("Auto backup of %s failed", name)
+		return
+	}
+
+	n := 0
+	for _, b := range WalletBackupList(name) {
+		if !IsAutoBackup(b) {
+			continue // manual backups are never pruned
+		}
+		n++
+		if n > Config.WalletBackups {
+			if err := os.Remove(BackupFolder() + "/" + b); err != nil {
+				log.Error().Err(err).Msgf("Error removing old backup %s", b)
+			}
+		}
+	}
+}
+
+// RestoreBackup replaces the wallet file with the backup. The current
+// version of the wallet is backed up first, so a restore can be undone.
+func RestoreBackup(backupFile string, walletName string) error {
+	data, err := os.ReadFile(BackupFolder() + "/" + backupFile)
+	if err != nil {
+		return fmt.Errorf("failed to read backup: %w", err)
+	}
+
+	target := DataFolder + "/wallets/" + walletName
+
+	autoBackup(target, true)
+
+	if err := writeFileAtomic(target, data); err != nil {
+		return fmt.Errorf("failed to restore wallet: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,257 @@
+package cmn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Wallet file envelope:
+//
+//	magic(4) | version(1) | kdf(1) | p1(4) | p2(4) | p3(4) | salt_len(1) | salt | nonce+ciphertext
+//
+// The header is authenticated as AES-GCM additional data. Files without the
+// magic are legacy: salt(32) | nonce+ciphertext with PBKDF2-SHA256 x4096.
+
+var WALLET_MAGIC = []byte("W3PW")
+
+const WALLET_FORMAT_VERSION = 1
+
+const (
+	KDF_PBKDF2_SHA256 = 1
+	KDF_ARGON2ID      = 2
+	KDF_SCRYPT        = 3
+)
+
+const (
+	LEGACY_PBKDF2_ITERATIONS = 4096
+
+	ARGON2ID_TIME    = 3
+	ARGON2ID_MEMORY  = 64 * 1024 // KiB
+	ARGON2ID_THREADS = 4
+
+	SCRYPT_N = 1 << 17
+	SCRYPT_R = 8
+	SCRYPT_P = 1
+)
+
+// The KDF parameters come from the header, which is authenticated only after
+// the key is derived. The limits keep a corrupted or hostile file from
+// exhausting the memory or the time before the GCM tag is checked.
+const (
+	MAX_PBKDF2_ITERATIONS = 10_000_000
+
+	MAX_ARGON2ID_TIME   = 16
+	MAX_ARGON2ID_MEMORY = 1024 * 1024 // KiB, 1 GiB
+
+	MAX_SCRYPT_N      = 1 << 20
+	MAX_SCRYPT_RP     = 64      // r*p, the time
+	MAX_SCRYPT_MEMORY = 1 << 30 // 128*N*r bytes, 1 GiB
+)
+
+const walletHeaderFixedSize = 4 + 1 + 1 + 4 + 4 + 4 + 1
+
+type walletHeader struct {
+	Version byte
+	KDF     byte
+	P1      uint32 // pbkdf2: iterations, argon2id: time, scrypt: N
+	P2      uint32 // argon2id: memory (KiB), scrypt: r
+	P3      uint32 // argon2id: threads, scrypt: p
+	Salt    []byte
+}
+
+// defaultWalletHeader returns a header with a fresh salt and the default KDF
+func defaultWalletHeader() (*walletHeader, error) {
+	salt := make([]byte, SOLT_SIZE)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	return &walletHeader{
+		Version: WALLET_FORMAT_VERSION,
+		KDF:     KDF_ARGON2ID,
+		P1:      ARGON2ID_TIME,
+		P2:      ARGON2ID_MEMORY,
+		P3:      ARGON2ID_THREADS,
+		Salt:    salt,
+	}, nil
+}
+
+func (h *walletHeader) Bytes() []byte {
+	buf := make([]byte, 0, walletHeaderFixedSize+len(h.Salt))
+	buf = append(buf, WALLET_MAGIC...)
+	buf = append(buf, h.Version, h.KDF)
+	buf = binary.BigEndian.AppendUint32(buf, h.P1)
+	buf = binary.BigEndian.AppendUint32(buf, h.P2)
+	buf = binary.BigEndian.AppendUint32(buf, h.P3)
+	buf = append(buf, byte(len(h.Salt)))
+	buf = append(buf, h.Salt...)
+	return buf
+}
+
+// parseWalletHeader returns the header and its raw bytes, or nil if the data
+// has no envelope (legacy file)
+func parseWalletHeader(data []byte) (*walletHeader, []byte, error) {
+	if len(data) < walletHeaderFixedSize || !bytes.Equal(data[:4], WALLET_MAGIC) {
+		return nil, nil, nil
+	}
+
+	h := &walletHeader{
+		Version: data[4],
+		KDF:     data[5],
+		P1:      binary.BigEndian.Uint32(data[6:10]),
+		P2:      binary.BigEndian.Uint32(data[10:14]),
+		P3:      binary.BigEndian.Uint32(data[14:18]),
+	}
+
+	if h.Version == 0 || h.Version > WALLET_FORMAT_VERSION {
+		return nil, nil, fmt.Errorf("unsupported wallet format version: %d", h.Version)
+	}
+
+	salt_len := int(data[18])
+	if len(data) < walletHeaderFixedSize+salt_len {
+		return nil, nil, errors.New("wallet header is truncated")
+	}
+
+	h.Salt = data[walletHeaderFixedSize : walletHeaderFixedSize+salt_len]
+
+	return h, data[:walletHeaderFixedSize+salt_len], nil
+}
+
+// DeriveKey derives the AES-256 key for the header's KDF
+func (h *walletHeader) DeriveKey(password string) ([]byte, error) {
+	switch h.KDF {
+	case KDF_PBKDF2_SHA256:
+		if h.P1 == 0 || h.P1 > MAX_PBKDF2_ITERATIONS {
+			return nil, errors.New("invalid pbkdf2 parameters")
+		}
+		return pbkdf2.Key([]byte(password), h.Salt, int(h.P1), 32, sha256.New), nil
+	case KDF_ARGON2ID:
+		if h.P1 == 0 || h.P1 > MAX_ARGON2ID_TIME ||
+			h.P2 == 0 || h.P2 > MAX_ARGON2ID_MEMORY ||
+			h.P3 == 0 || h.P3 > 255 {
+			return nil, errors.New("invalid argon2id parameters")
+		}
+		return argon2.IDKey([]byte(password), h.Salt, h.P1, h.P2, uint8(h.P3), 32), nil
+	case KDF_SCRYPT:
+		if h.P1 < 2 || h.P1 > MAX_SCRYPT_N || h.P1&(h.P1-1) != 0 ||
+			h.P2 == 0 || h.P3 == 0 || uint64(h.P2)*uint64(h.P3) > MAX_SCRYPT_RP ||
+			128*uint64(h.P1)*uint64(h.P2) > MAX_SCRYPT_MEMORY {
+			return nil, errors.New("invalid scrypt parameters")
+		}
+		return scrypt.Key([]byte(password), h.Salt, int(h.P1), int(h.P2), int(h.P3), 32)
+	default:
+		return nil, fmt.Errorf("unknown KDF: %d", h.KDF)
+	}
+}
+
+func (h *walletHeader) KDFName() string {
+	switch h.KDF {
+	case KDF_PBKDF2_SHA256:
+		return fmt.Sprintf("pbkdf2-sha256 (i=%d)", h.P1)
+	case KDF_ARGON2ID:
+		return fmt.Sprintf("argon2id (t=%d, m=%dKiB, p=%d)", h.P1, h.P2, h.P3)
+	case KDF_SCRYPT:
+		return fmt.Sprintf("scrypt (N=%d, r=%d, p=%d)", h.P1, h.P2, h.P3)
+	}
+	return "unknown"
+}
+
+// sealWallet encrypts the wallet JSON into the versioned envelope
+func sealWallet(h *walletHeader, key []byte, plain []byte) ([]byte, error) {
+	header := h.Bytes()
+
+	encrypted, err := encrypt(plain, key, header)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(header, encrypted...), nil
+}
+
+// openWallet decrypts either an enveloped or a legacy wallet file. The
+// returned header is nil for legacy files.
+func openWallet(data []byte, password string) ([]byte, *walletHeader, []byte, error) {
+	h, header, header_err := parseWalletHeader(data)
+
+	if h != nil {
+		key, err := h.DeriveKey(password)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		plain, err := decrypt(data[len(header):], key, header)
+		if err == nil {
+			return plain, h, key, nil
+		}
+		// a legacy salt may start with the magic by chance, try legacy below
+	}
+
+	if len(data) < SOLT_SIZE {
+		return nil, nil, nil, errors.New("wallet file is too short")
+	}
+
+	key := generateKey(password, data[:SOLT_SIZE])
+	plain, err := decrypt(data[SOLT_SIZE:], key, nil)
+	if err != nil {
+		if header_err != nil {
+			return nil, nil, nil, header_err
+		}
+		return nil, nil, nil, err
+	}
+
+	return plain, nil, nil, nil
+}
+
+// writeFileAtomic writes data to a temp file in the same folder, syncs it and
+// renames it over the target, so a crash never leaves a half-written wallet
+func writeFileAtomic(file string, data []byte) error {
+	dir := filepath.Dir(file)
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(file)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmp_name := tmp.Name()
+	defer os.Remove(tmp_name) // no-op after a successful rename
+
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp_name, file); err != nil {
+		return err
+	}
+
+	// persist the rename itself (not supported on all platforms)
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+
+	return nil
+}
@@ -0,0 +1,230 @@
+package cmn
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/rs/zerolog/log"
+)
+
+// Wallet schema migrations. Each migration upgrades the decrypted wallet from
+// Version-1 to Version and must not touch the file system or the bus, so it
+// can be run against a wallet unmarshaled from a fixture.
+//
+// Add new migrations to the end of the list; never reorder or remove them.
+
+type WalletMigration struct {
+	Version int
+	Name    string
+	Apply   func(w *Wallet) error
+}
+
+var walletMigrations = []WalletMigration{
+	{1, "default empty collections", migrateDefaultCollections},
+	{2, "backfill chain short names and multicall", migrateBackfillPredefinedChains},
+	{3, "remove duplicate tokens by address", migrateRemoveDuplicateTokens},
+	{4, "signing policies", migrateDefaultPolicies},
+	{5, "transaction history", migrateTransactionHistory},
+	{6, "chain fee models", migrateBackfillFeeModels},
+	{7, "nft holdings", migrateNFTs},
+}
+
+// WALLET_SCHEMA_VERSION is the schema version written by this build
+var WALLET_SCHEMA_VERSION = walletMigrations[len(walletMigrations)-1].Version
+
+// NeedsMigration returns true if the wallet schema is older than this build
+func (w *Wallet) NeedsMigration() bool {
+	return w.SchemaVersion < WALLET_SCHEMA_VERSION
+}
+
+// MigrateWallet applies all pending migrations in order. It stops at the
+// first failing migration, leaving SchemaVersion at the last successful one.
+func MigrateWallet(w *Wallet) error {
+	if w.SchemaVersion > WALLET_SCHEMA_VERSION {
+		return fmt.Errorf("wallet schema version %d is newer than supported %d", w.SchemaVersion, WALLET_SCHEMA_VERSION)
+	}
+
+	for _, m := range walletMigrations {
+		if m.Version <= w.SchemaVersion {
+			continue
+		}
+
+		if err := m.Apply(w); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", m.Version, m.Name, err)
+		}
+
+		log.Info().Msgf("Wallet migrated to schema v%d: %s", m.Version, m.Name)
+		w.SchemaVersion = m.Version
+	}
+
+	return nil
+}
+
+// _locked_Migrate backs the wallet file up and applies pending migrations
+func (w *Wallet) _locked_Migrate() error {
+	if !w.NeedsMigration() {
+		return MigrateWallet(w) // reports a too new schema
+	}
+
+	backup, err := BackupWallet(w.filePath, false)
+	if err != nil {
+		return fmt.Errorf("failed to back up wallet before migration: %w", err)
+	}
+	log.Info().Msgf("Wallet backed up to %s before migration", backup)
+
+	if err := MigrateWallet(w); err != nil {
+		return err
+	}
+
+	return w._locked_Save()
+}
+
+func migrateDefaultCollections(w *Wallet) error {
+	if w.Contracts == nil {
+		w.Contracts = make(map[common.Address]*Contract)
+	}
+
+	if w.Tokens == nil {
+		w.Tokens = []*Token{}
+	}
+
+	if w.Addresses == nil {
+		w.Addresses = []*Address{}
+	}
+
+	if w.Signers == nil {
+		w.Signers = []*Signer{}
+	}
+
+	if w.Origins == nil {
+		w.Origins = []*Origin{}
+	}
+
+	if w.Blockchains == nil {
+		w.Blockchains = []*Blockchain{}
+	}
+
+	if w.LP_V3_Providers == nil {
+		w.LP_V3_Providers = []*LP_V3{}
+	}
+
+	if w.LP_V3_Positions == nil {
+		w.LP_V3_Positions = []*LP_V3_Position{}
+	}
+
+	if w.LP_V4_Providers == nil {
+		w.LP_V4_Providers = []*LP_V4{}
+	}
+
+	if w.LP_V4_Positions == nil {
+		w.LP_V4_Positions = []*LP_V4_Position{}
+	}
+
+	if w.Stakings == nil {
+		w.Stakings = []*Staking{}
+	}
+
+	if w.StakingPositions == nil {
+		w.StakingPositions = []*StakingPosition{}
+	}
+
+	if w.ParamInt == nil {
+		w.ParamInt = make(map[string]int)
+	}
+
+	if w.ParamStr == nil {
+		w.ParamStr = make(map[string]string)
+	}
+
+	return nil
+}
+
+func migrateBackfillPredefinedChains(w *Wallet) error {
+	for _, b := range w.Blockchains {
+		for _, predefined := range PredefinedBlockchains {
+			if predefined.ChainId == b.ChainId {
+				if b.ShortName == "" && predefined.ShortName != "" {
+					b.ShortName = predefined.ShortName
+				}
+				if b.Multicall == (common.Address{}) && predefined.Multicall != (common.Address{}) {
+					b.Multicall = predefined.Multicall
+				}
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// migrateBackfillFeeModels sets the fee model of the known L2 chains added
+// before the field existed
+func migrateBackfillFeeModels(w *Wallet) error {
+	for _, b := range w.Blockchains {
+		if b.FeeModel != "" {
+			continue
+		}
+		for _, predefined := range PredefinedBlockchains {
+			if predefined.ChainId == b.ChainId {
+				b.FeeModel = predefined.FeeModel
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// migrateRemoveDuplicateTokens keeps the first of the tokens with the same
+// chain and address (or the first native token of a chain). The former audit
+// on open matched the chain and the symbol, which also dropped different
+// tokens sharing a symbol (USDC and bridged USDC); those are kept now.
+func migrateRemoveDuplicateTokens(w *Wallet) error {
+	type key struct {
+		chainId int
+		address common.Address
+		native  bool
+	}
+
+	seen := map[key]bool{}
+	tokens := []*Token{}
+
+	for _, t := range w.Tokens {
+		k := key{t.ChainId, t.Address, t.Native}
+		if t.Native {
+			k.address = common.Address{}
+		}
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		tokens = append(tokens, t)
+	}
+
+	w.Tokens = tokens
+	return nil
+}
+
+func migrateDefaultPolicies(w *Wallet) error {
+	if w.Policies == nil {
+		w.Policies = []*Policy{}
+	}
+
+	if w.PolicySpends == nil {
+		w.PolicySpends = []*PolicySpend{}
+	}
+
+	return nil
+}
+
+func migrateTransactionHistory(w *Wallet) error {
+	if w.Transactions == nil {
+		w.Transactions = []*TxRecord{}
+	}
+	return nil
+}
+
+func migrateNFTs(w *Wallet) error {
+	if w.NFTs == nil {
+		w.NFTs = []*NFT{}
+	}
+	return nil
+}
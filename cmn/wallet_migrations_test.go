@@ -0,0 +1,156 @@
+package cmn
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// The fixtures in testdata/wallets are the decrypted wallets as written by
+// each schema version.
+
+func loadWalletFixture(t *testing.T, version int) *Wallet {
+	t.Helper()
+
+	data, err := os.ReadFile(fmt.Sprintf("testdata/wallets/v%d.json", version))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := &Wallet{}
+	if err := json.Unmarshal(data, w); err != nil {
+		t.Fatal(err)
+	}
+
+	if w.SchemaVersion != version {
+		t.Fatalf("fixture v%d has schema version %d", version, w.SchemaVersion)
+	}
+
+	return w
+}
+
+func walletJSON(t *testing.T, w *Wallet) string {
+	t.Helper()
+
+	data, err := json.Marshal(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestMigrateWallet(t *testing.T) {
+	usdc := common.HexToAddress("0x0b2C639c533813f4Aa9D7837CAf62653d097Ff85")
+	usdc_e := common.HexToAddress("0x7F5c764cBc14f9669B88837ca1490cCa17c31607")
+
+	// duplicate native and USDC tokens are dropped, the bridged USDC with
+	// the same symbol is kept
+	want_tokens := []common.Address{{}, usdc, usdc_e}
+
+	tests := []struct {
+		version int
+		name    string
+	}{
+		{0, "legacy wallet without collections and chain details"},
+		{1, "no short names and multicall"},
+		{2, "duplicate tokens"},
+		{3, "no policies"},
+		{4, "no transaction history"},
+		{5, "no fee models"},
+		{6, "no nft holdings"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("v%d %s", tt.version, tt.name), func(t *testing.T) {
+			w := loadWalletFixture(t, tt.version)
+
+			if !w.NeedsMigration() {
+				t.Fatal("fixture does not need migration")
+			}
+
+			if err := MigrateWallet(w); err != nil {
+				t.Fatal(err)
+			}
+
+			if w.SchemaVersion != WALLET_SCHEMA_VERSION {
+				t.Errorf("schema version %d, want %d", w.SchemaVersion, WALLET_SCHEMA_VERSION)
+			}
+
+			if w.Contracts == nil || w.Tokens == nil || w.Addresses == nil || w.Signers == nil ||
+				w.Origins == nil || w.Blockchains == nil || w.ParamInt == nil || w.ParamStr == nil ||
+				w.Policies == nil || w.PolicySpends == nil || w.Transactions == nil || w.NFTs == nil {
+				t.Error("nil collection after migration")
+			}
+
+			b := w.GetBlockchain(10)
+			if b == nil {
+				t.Fatal("blockchain 10 not found")
+			}
+			if b.ShortName != "OP" {
+				t.Errorf("short name %q, want OP", b.ShortName)
+			}
+			if b.Multicall != common.HexToAddress("0xcA11bde05977b3631167028862bE2a173976CA11") {
+				t.Errorf("multicall %s not backfilled", b.Multicall.Hex())
+			}
+			if b.FeeModel != FEE_MODEL_OP_STACK {
+				t.Errorf("fee model %q, want %s", b.FeeModel, FEE_MODEL_OP_STACK)
+			}
+
+			if len(w.Tokens) != len(want_tokens) {
+				t.Fatalf("%d tokens, want %d", len(w.Tokens), len(want_tokens))
+			}
+			for i, a := range want_tokens {
+				if w.Tokens[i].Address != a {
+					t.Errorf("token %d is %s, want %s", i, w.Tokens[i].Address.Hex(), a.Hex())
+				}
+			}
+
+			// idempotence: neither a second run nor re-applying every
+			// migration changes the wallet
+			migrated := walletJSON(t, w)
+
+			if err := MigrateWallet(w); err != nil {
+				t.Fatal(err)
+			}
+			if walletJSON(t, w) != migrated {
+				t.Error("second MigrateWallet changed the wallet")
+			}
+
+			for _, m := range walletMigrations {
+				if err := m.Apply(w); err != nil {
+					t.Fatalf("migration %d: %v", m.Version, err)
+				}
+				if walletJSON(t, w) != migrated {
+					t.Errorf("migration %d (%s) is not idempotent", m.Version, m.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestMigrateWalletNewerSchema(t *testing.T) {
+	w := loadWalletFixture(t, 6)
+	w.SchemaVersion = WALLET_SCHEMA_VERSION + 1
+	before := walletJSON(t, w)
+
+	err := MigrateWallet(w)
+	if err == nil || !strings.Contains(err.Error(), "newer than supported") {
+		t.Fatalf("error %v, want newer than supported", err)
+	}
+
+	if walletJSON(t, w) != before {
+		t.Error("wallet changed on a failed migration")
+	}
+}
+
+func TestMigrationsOrdered(t *testing.T) {
+	for i, m := range walletMigrations {
+		if m.Version != i+1 {
+			t.Errorf("migration %d (%s) has version %d", i, m.Name, m.Version)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package command
 
 import (
+	"fmt"
 	"math/big"
 	"sort"
 	"strings"
@@ -27,7 +28,7 @@ Usage: address [COMMAND]
 Manage addresses
 
 Commands:
-  add [ADDRESS]      - Add watch-only address
+  add [ADDRESS]      - Add watch-only address (hex or ENS name)
   set [ADDRESS]      - Set the current address
   list               - List addresses
   edit [ADDRESS]     - Edit address
@@ -35,6 +36,7 @@ Commands:
   balances [ADDRESS] - Show token balances for address
 
 Note: To add addresses with a signer, use 'signer addresses' command.
+      To add a Gnosis Safe, use 'safe add' command.
 		`,
 		Help:             `Manage addresses`,
 		Process:          Address_Process,
@@ -86,16 +88,35 @@ func Address_Process(c *Command, input string) {
 	w := cmn.CurrentWallet
 
 	//parse command subcommand parameters
-	tokens := cmn.SplitN(input, 3)
-	_, subcommand, p0 := tokens[0], tokens[1], tokens[2]
+	tokens := cmn.SplitN(input, 5)
+	_, subcommand, p0, signer, path := tokens[0], tokens[1], tokens[2], tokens[3], tokens[4]
 
 	switch subcommand {
 	case "add":
-		if !common.IsHexAddress(p0) {
-			ui.PrintErrorf("Invalid address")
+		if signer != "" { // from 'signer addresses'
+			if !common.IsHexAddress(p0) {
+				ui.PrintErrorf("Invalid address")
+				return
+			}
+			if w.GetSigner(signer) == nil {
+				ui.PrintErrorf("Signer not found: %s", signer)
+				return
+			}
+			bus.Send("ui", "popup", ui.DlgAddressAdd(p0, signer, path))
 			return
 		}
-		bus.Send("ui", "popup", ui.DlgAddressAddWatch(p0))
+
+		name := ""
+		if eth.IsENSName(p0) {
+			name = eth.NormalizeENSName(p0)
+		}
+
+		a, err := parseAddress(w, p0)
+		if err != nil {
+			ui.PrintErrorf("%v", err)
+			return
+		}
+		bus.Send("ui", "popup", ui.DlgAddressAddWatch(a.String(), name))
 	case "remove":
 		for i, a := range w.Addresses {
 			if a.Name == p0 {
@@ -133,7 +154,9 @@ func Address_Process(c *Command, input string) {
 			ui.Terminal.Screen.AddLink(cmn.ICON_EDIT, "command address edit '"+a.Name+"'", "Edit address", "")
 			ui.Terminal.Screen.AddLink(cmn.ICON_DELETE, "command address remove '"+a.Name+"'", "Remove address", "")
 			signerInfo := a.Signer
-			if signerInfo == "" {
+			if a.Safe != nil {
+				signerInfo = fmt.Sprintf("safe %d/%d", a.Safe.Threshold, len(a.Safe.Owners))
+			} else if signerInfo == "" {
 				signerInfo = "watch"
 			}
 			ui.Printf(" %-14s (%s) \n", a.Name, signerInfo)
@@ -605,3 +628,29 @@ func showAddressBalances(w *cmn.Wallet, addr *cmn.Address) {
 	cmn.AddDollarLink(ui.Terminal.Screen, totalUSD)
 	ui.Printf("\n")
 }
+
+// parseAddress accepts a hex address, a wallet address name or an ENS name
+func parseAddress(w *cmn.Wallet, s string) (common.Address, error) {
+	if common.IsHexAddress(s) {
+		return common.HexToAddress(s), nil
+	}
+
+	if a := w.GetAddressByName(s); a != nil {
+		return a.Address, nil
+	}
+
+	if !eth.IsENSName(s) {
+		return common.Address{}, fmt.Errorf("invalid address: %s", s)
+	}
+
+	a, err := eth.ResolveENS(s)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	ui.Printf("%s resolved to ", eth.NormalizeENSName(s))
+	cmn.AddAddressLink(ui.Terminal.Screen, a)
+	ui.Printf("\n")
+
+	return a, nil
+}
@@ -0,0 +1,219 @@
+package command
+
+import (
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/AlexNa-Holdings/web3pro/bus"
+	"github.com/AlexNa-Holdings/web3pro/cmn"
+	"github.com/AlexNa-Holdings/web3pro/eth"
+	"github.com/AlexNa-Holdings/web3pro/ui"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var approvals_subcommands = []string{"list", "revoke"}
+
+func NewApprovalsCommand() *Command {
+	return &Command{
+		Command:      "approvals",
+		ShortCommand: "",
+		Subcommands:  approvals_subcommands,
+		Usage: `
+Usage: approvals [COMMAND]
+
+Inspect and revoke the token allowances of the wallet addresses
+
+Commands:
+  list [ADDRESS]                          - List the allowances on the current chain
+  revoke OWNER TOKEN SPENDER [permit2]    - Set the allowance to 0
+
+Note: The allowances are found from the Approval events (explorer API or
+eth_getLogs). The exposure is the part of the allowance covered by the
+current balance. Permit2 allowances are listed with their expiration.
+		`,
+		Help:             `Inspect and revoke token allowances`,
+		Process:          Approvals_Process,
+		AutoCompleteFunc: Approvals_AutoComplete,
+	}
+}
+
+func Approvals_AutoComplete(input string) (string, *[]ui.ACOption, string) {
+	options := []ui.ACOption{}
+
+	if cmn.CurrentWallet == nil {
+		return "", &options, ""
+	}
+
+	w := cmn.CurrentWallet
+
+	p := cmn.SplitN(input, 3)
+	command, subcommand, param := p[0], p[1], p[2]
+
+	if !cmn.IsInArray(approvals_subcommands, subcommand) {
+		for _, sc := range approvals_subcommands {
+			if input == "" || strings.Contains(sc, subcommand) {
+				options = append(options, ui.ACOption{Name: sc, Result: command + " " + sc + " "})
+			}
+		}
+		return "action", &options, subcommand
+	}
+
+	if subcommand == "list" {
+		for _, a := range w.Addresses {
+			if cmn.Contains(a.Name+a.Address.String(), param) {
+				options = append(options, ui.ACOption{
+					Name:   cmn.ShortAddress(a.Address) + " " + a.Name,
+					Result: command + " " + subcommand + " '" + a.Name + "'"})
+			}
+		}
+		return "address", &options, param
+	}
+
+	return "", &options, ""
+}
+
+func Approvals_Process(c *Command, input string) {
+	if cmn.CurrentWallet == nil {
+		ui.PrintErrorf("No wallet open")
+		return
+	}
+
+	w := cmn.CurrentWallet
+
+	b := w.GetBlockchain(w.CurrentChainId)
+	if b == nil {
+		ui.PrintErrorf("No current blockchain")
+		return
+	}
+
+	//parse command subcommand parameters
+	tokens := cmn.SplitN(input, 6)
+	_, subcommand, p0, p1, p2, p3 := tokens[0], tokens[1], tokens[2], tokens[3], tokens[4], tokens[5]
+
+	switch subcommand {
+	case "list", "":
+		addresses := w.Addresses
+		if p0 != "" {
+			a := w.GetAddressByName(p0)
+			if a == nil {
+				ui.PrintErrorf("Address not found: %s", p0)
+				return
+			}
+			addresses = []*cmn.Address{a}
+		}
+
+		symbols := map[common.Address]string{}
+		for _, a := range addresses {
+			ui.Printf("\nAllowances of ")
+			cmn.AddAddressShortLink(ui.Terminal.Screen, a.Address)
+			ui.Printf(" %s on %s\n", a.Name, b.Name)
+
+			list, scanned, err := eth.GetAllowances(b, a.Address)
+			if err != nil {
+				ui.PrintErrorf("Error getting allowances: %v", err)
+				continue
+			}
+
+			if scanned != nil {
+				ui.PrintErrorf("  Scanned only blocks %d-%d, older approvals are not listed", scanned.From, scanned.To)
+			}
+
+			if len(list) == 0 {
+				ui.Printf("  (none)\n")
+				continue
+			}
+
+			for _, al := range list {
+				printAllowance(w, b, al, symbols)
+			}
+		}
+	case "revoke":
+		if !common.IsHexAddress(p0) || !common.IsHexAddress(p1) || !common.IsHexAddress(p2) {
+			ui.PrintErrorf("Usage: approvals revoke OWNER TOKEN SPENDER [permit2]")
+			return
+		}
+
+		owner := w.GetAddress(p0)
+		if owner == nil {
+			ui.PrintErrorf("Address not found: %s", p0)
+			return
+		}
+
+		to, data, err := eth.BuildRevokeCall(&eth.TokenAllowance{
+			Owner:   owner.Address,
+			Token:   common.HexToAddress(p1),
+			Spender: common.HexToAddress(p2),
+			Permit2: p3 == "permit2",
+		})
+		if err != nil {
+			ui.PrintErrorf("Error building revoke call: %v", err)
+			return
+		}
+
+		bus.Send("eth", "send-tx", &bus.B_EthSendTx{
+			ChainId: b.ChainId,
+			From:    owner.Address,
+			To:      to,
+			Amount:  big.NewInt(0),
+			Data:    data,
+		})
+	default:
+		ui.PrintErrorf("Invalid subcommand: %s", subcommand)
+	}
+}
+
+func printAllowance(w *cmn.Wallet, b *cmn.Blockchain, a *eth.TokenAllowance, symbols map[common.Address]string) {
+	t := w.GetTokenByAddress(b.ChainId, a.Token)
+
+	symbol, ok := symbols[a.Token]
+	if !ok {
+		symbol = cmn.ShortAddress(a.Token)
+		if t != nil {
+			symbol = t.Symbol
+		} else if s, _, _, err := eth.GetERC20TokenInfo(b, a.Token); err == nil {
+			symbol = s
+		}
+		symbols[a.Token] = symbol
+	}
+
+	ui.Printf("  ")
+	ui.Terminal.Screen.AddLink(cmn.FixedWidth(symbol, 8), "copy "+a.Token.String(), a.Token.String(), "")
+
+	var amount string
+	switch {
+	case a.Amount.Cmp(cmn.UNLIMITED_APPROVAL) >= 0:
+		amount = "unlimited"
+	case t != nil:
+		amount = t.Value2Str(a.Amount)
+	default:
+		amount = a.Amount.String()
+	}
+	ui.Printf(" %14s to ", amount)
+
+	cmn.AddAddressShortLink(ui.Terminal.Screen, a.Spender)
+	if c := w.GetContract(a.Spender); c != nil && c.Name != "" {
+		ui.Printf(" %s", c.Name)
+	} else if wa := w.GetAddress(a.Spender); wa != nil {
+		ui.Printf(" %s", wa.Name)
+	}
+
+	if t != nil && t.Price > 0 {
+		exposure := a.Amount
+		if a.Balance != nil && a.Balance.Cmp(exposure) < 0 {
+			exposure = a.Balance
+		}
+		ui.Printf(" exposure: ")
+		cmn.AddDollarValueLink(ui.Terminal.Screen, exposure, t)
+	}
+
+	action := "command approvals revoke " + a.Owner.String() + " " + a.Token.String() + " " + a.Spender.String()
+	if a.Permit2 {
+		ui.Printf(" Permit2 until %s", time.Unix(int64(a.Expiration), 0).Format("2006-01-02"))
+		action += " permit2"
+	}
+
+	ui.Printf(" ")
+	ui.Terminal.Screen.AddLink("revoke", action, "Set the allowance to 0", "")
+	ui.Printf("\n")
+}
@@ -34,8 +34,16 @@ func Init() {
 		NewSignerCommand(),
 		NewUsbCommand(),
 		NewAddressCommand(),
+		NewSafeCommand(),
+		NewPolicyCommand(),
 		NewTokenCommand(),
 		NewSendCommand(),
+		NewTxCommand(),
+		NewNonceCommand(),
+		NewApprovalsCommand(),
+		NewNFTCommand(),
+		NewContractCommand(),
+		NewSignCommand(),
 		NewPriceCommand(),
 		NewWebSocketCommand(),
 		NewAppCommand(),
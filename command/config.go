@@ -24,6 +24,9 @@ var config_params = []string{
 	"thegraph_gateway",
 	"min_token_value",
 	"ws_enabled",
+	"wallet_backups",
+	"wallet_backup_interval",
+	"offline",
 }
 
 func NewConfigCommand() *Command {
@@ -52,6 +55,10 @@ PARAMETERS:
   thegraph_gateway      - The Graph gateway URL
   min_token_value       - minimum USD value to show token in tokens pane
   ws_enabled            - enable WebSocket server for browser (true/false)
+  wallet_backups        - number of automatic wallet backups to keep (0 = off)
+  wallet_backup_interval - minimum time between automatic backups (e.g., 15m, 1h)
+  offline               - disable all eth network access, for the air-gapped
+                          signer (true/false)
 `,
 		Help:             `Application configuration management`,
 		Process:          Config_Process,
@@ -136,6 +143,9 @@ func showConfig() {
 	ui.Printf("  %-20s %s\n", "thegraph_gateway:", cmn.Config.TheGraphGateway)
 	ui.Printf("  %-20s %.2f\n", "min_token_value:", cmn.Config.MinTokenValue)
 	ui.Printf("  %-20s %t\n", "ws_enabled:", cmn.Config.WSEnabled)
+	ui.Printf("  %-20s %d\n", "wallet_backups:", cmn.Config.WalletBackups)
+	ui.Printf("  %-20s %s\n", "wallet_backup_interval:", cmn.Config.WalletBackupInterval.String())
+	ui.Printf("  %-20s %t\n", "offline:", cmn.Config.Offline)
 	ui.Printf("\n")
 }
 
@@ -232,6 +242,43 @@ func setConfigParam(param, value string) {
 			bus.Send("ws", "stop", nil)
 		}
 
+	case "wallet_backups":
+		val, err := strconv.Atoi(value)
+		if err != nil {
+			ui.PrintErrorf("Invalid number: %v\n", err)
+			return
+		}
+		if val < 0 {
+			ui.PrintErrorf("Value must be >= 0\n")
+			return
+		}
+		cmn.Config.WalletBackups = val
+
+	case "wallet_backup_interval":
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			ui.PrintErrorf("Invalid duration: %v\n", err)
+			return
+		}
+		cmn.Config.WalletBackupInterval = duration
+
+	case "offline":
+		var offline bool
+		if value == "true" || value == "1" || value == "yes" {
+			offline = true
+		} else if value == "false" || value == "0" || value == "no" {
+			offline = false
+		} else {
+			ui.PrintErrorf("Invalid boolean value. Use: true/false, 1/0, yes/no\n")
+			return
+		}
+
+		if offline != cmn.Config.Offline {
+			cmn.Config.Offline = offline
+			// close or open the connections
+			bus.Send("eth", "reconnect", nil)
+		}
+
 	default:
 		ui.PrintErrorf("Unknown parameter: %s\n", param)
 		return
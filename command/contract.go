@@ -0,0 +1,379 @@
+package command
+
+import (
+	"math/big"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/AlexNa-Holdings/web3pro/bus"
+	"github.com/AlexNa-Holdings/web3pro/cmn"
+	"github.com/AlexNa-Holdings/web3pro/eth"
+	"github.com/AlexNa-Holdings/web3pro/ui"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var contract_subcommands = []string{"read", "write", "methods", "abi"}
+
+// the arguments keep the brackets and signs, cmn.SplitN trims them
+var re_contract_arg = regexp.MustCompile(`'[^']*'|"[^"]*"|\S+`)
+
+func NewContractCommand() *Command {
+	return &Command{
+		Command:      "contract",
+		ShortCommand: "",
+		Subcommands:  contract_subcommands,
+		Usage: `
+Usage: contract [COMMAND]
+
+Call any contract method described by an ABI
+
+Commands:
+  read BLOCKCHAIN ADDRESS METHOD [ARGS...]          - Call a view method
+  write BLOCKCHAIN ADDRESS METHOD [ARGS...] [VALUE] - Send a transaction
+  methods ADDRESS                                   - List the methods
+  abi ADDRESS FILE                                  - Import the ABI from a file
+
+The ABI is downloaded with 'explorer download' or imported from a JSON file
+(plain ABI or a build artifact with the "abi" field). The ERC-20, ERC-721,
+ERC-1155 and other embedded ABIs are always available.
+
+An overloaded METHOD is given by the signature, like
+safeTransferFrom(address,address,uint256). The arrays and tuples are given
+as JSON in quotes: '["0x...", "0x..."]' or '{"amount": "1000", "to": "0x..."}'.
+The VALUE is the amount of the native token for the payable methods.
+The transaction is sent from the current address.
+`,
+		Help:             `Call contract methods`,
+		Process:          Contract_Process,
+		AutoCompleteFunc: Contract_AutoComplete,
+	}
+}
+
+// splitContractArgs splits the input and removes the quotes
+func splitContractArgs(input string) []string {
+	r := []string{}
+	for _, s := range re_contract_arg.FindAllString(input, -1) {
+		if len(s) >= 2 && (s[0] == '\'' || s[0] == '"') && s[len(s)-1] == s[0] {
+			s = s[1 : len(s)-1]
+		}
+		r = append(r, s)
+	}
+	return r
+}
+
+func Contract_AutoComplete(input string) (string, *[]ui.ACOption, string) {
+	options := []ui.ACOption{}
+
+	if cmn.CurrentWallet == nil {
+		return "", &options, ""
+	}
+
+	w := cmn.CurrentWallet
+
+	p := splitContractArgs(input)
+	raw := re_contract_arg.FindAllString(input, -1)
+	if strings.HasSuffix(input, " ") || len(p) == 0 {
+		p = append(p, "")
+		raw = append(raw, "")
+	}
+
+	// the parameter being typed, an open quote included
+	prefix := input[:len(input)-len(raw[len(raw)-1])]
+	current := strings.Trim(p[len(p)-1], `'"`)
+	if len(p) < 2 {
+		p = append(p, "")
+		prefix = input + " "
+	}
+	command, subcommand := p[0], p[1]
+
+	if len(p) == 2 {
+		for _, sc := range contract_subcommands {
+			if input == "" || strings.Contains(sc, subcommand) {
+				options = append(options, ui.ACOption{Name: sc, Result: command + " " + sc + " "})
+			}
+		}
+		return "action", &options, subcommand
+	}
+
+	// the position of the contract address
+	a_pos := 3
+	if subcommand == "methods" || subcommand == "abi" {
+		a_pos = 2
+	}
+
+	switch {
+	case len(p) == 3 && a_pos == 3:
+		for _, b := range w.Blockchains {
+			if cmn.Contains(b.Name, current) {
+				options = append(options, ui.ACOption{Name: b.Name, Result: prefix + "'" + b.Name + "' "})
+			}
+		}
+		return "blockchain", &options, current
+	case len(p) == a_pos+1:
+		for _, a := range contractAddresses() {
+			if cmn.Contains(a.String(), current) {
+				options = append(options, ui.ACOption{Name: a.String(), Result: prefix + a.String() + " "})
+			}
+		}
+		return "contract", &options, current
+	case a_pos == 2:
+		return "", &options, ""
+	case len(p) == 5:
+		if !common.IsHexAddress(p[3]) {
+			return "", &options, ""
+		}
+
+		names := map[string]int{}
+		methods := eth.ContractMethods(common.HexToAddress(p[3]))
+		for _, m := range methods {
+			names[m.RawName]++
+		}
+
+		for _, m := range methods {
+			if (subcommand == "read") != m.IsConstant() || !cmn.Contains(m.Sig, current) {
+				continue
+			}
+			name := m.RawName
+			if names[name] > 1 {
+				name = m.Sig
+			}
+			options = append(options, ui.ACOption{Name: m.Sig, Result: prefix + name + " "})
+		}
+		return "method", &options, current
+	}
+
+	if !common.IsHexAddress(p[3]) {
+		return "", &options, ""
+	}
+
+	m, err := eth.FindContractMethod(common.HexToAddress(p[3]), p[4])
+	if err != nil {
+		return "", &options, ""
+	}
+
+	i := len(p) - 6 // the argument being typed
+	if i >= len(m.Inputs) {
+		if subcommand == "write" && m.IsPayable() && i == len(m.Inputs) {
+			options = append(options, ui.ACOption{Name: "VALUE (native token)", Result: input})
+			return "payable", &options, ""
+		}
+		return "", &options, ""
+	}
+
+	in := m.Inputs[i]
+	if in.Type.T == abi.AddressTy {
+		for _, a := range w.Addresses {
+			if cmn.Contains(a.Name+a.Address.String(), current) {
+				options = append(options, ui.ACOption{
+					Name:   cmn.ShortAddress(a.Address) + " " + a.Name,
+					Result: prefix + a.Address.String() + " "})
+			}
+		}
+	}
+
+	if len(options) == 0 {
+		options = append(options, ui.ACOption{Name: in.Type.String() + " " + in.Name, Result: input})
+	}
+
+	return in.Type.String() + " " + in.Name, &options, current
+}
+
+// contractAddresses returns the contracts with a downloaded or imported ABI
+func contractAddresses() []common.Address {
+	r := []common.Address{}
+
+	files, _ := os.ReadDir(cmn.DataFolder + "/contracts")
+	for _, f := range files {
+		if f.IsDir() && common.IsHexAddress(f.Name()) {
+			r = append(r, common.HexToAddress(f.Name()))
+		}
+	}
+	return r
+}
+
+func Contract_Process(c *Command, input string) {
+	if cmn.CurrentWallet == nil {
+		ui.PrintErrorf("No wallet open")
+		return
+	}
+
+	w := cmn.CurrentWallet
+
+	p := splitContractArgs(input)
+	for len(p) < 4 {
+		p = append(p, "")
+	}
+	subcommand := p[1]
+
+	switch subcommand {
+	case "abi":
+		if !common.IsHexAddress(p[2]) || p[3] == "" {
+			ui.PrintErrorf("Usage: contract abi ADDRESS FILE")
+			return
+		}
+
+		if err := eth.ImportContractABI(common.HexToAddress(p[2]), p[3]); err != nil {
+			ui.PrintErrorf("Error importing ABI: %v", err)
+			return
+		}
+		ui.Printf("ABI imported\n")
+	case "methods":
+		to, err := parseAddress(w, p[2])
+		if err != nil {
+			ui.PrintErrorf("Usage: contract methods ADDRESS")
+			return
+		}
+
+		ui.Printf("\nMethods of ")
+		cmn.AddAddressShortLink(ui.Terminal.Screen, to)
+		ui.Printf("\n")
+
+		for _, m := range eth.ContractMethods(to) {
+			kind := "write"
+			if m.IsConstant() {
+				kind = "read "
+			}
+			if m.IsPayable() {
+				kind = "pay  "
+			}
+			ui.Printf("  %s %s", kind, m.Sig)
+			if len(m.Outputs) > 0 {
+				ui.Printf(" -> %s", typeList(m.Outputs))
+			}
+			ui.Printf("\n")
+		}
+	case "read", "write":
+		b := w.GetBlockchainByName(p[2])
+		if b == nil {
+			ui.PrintErrorf("Blockchain not found: %s", p[2])
+			return
+		}
+
+		to, err := parseAddress(w, p[3])
+		if err != nil {
+			ui.PrintErrorf("Invalid contract address: %v", err)
+			return
+		}
+
+		if len(p) < 5 {
+			ui.PrintErrorf("Usage: contract %s BLOCKCHAIN ADDRESS METHOD [ARGS...]", subcommand)
+			return
+		}
+
+		m, err := eth.FindContractMethod(to, p[4])
+		if err != nil {
+			ui.PrintErrorf("%v", err)
+			return
+		}
+
+		args := p[5:]
+
+		value := big.NewInt(0)
+		if subcommand == "write" && m.IsPayable() && len(args) == len(m.Inputs)+1 {
+			nt, err := w.GetNativeToken(b)
+			if err != nil {
+				ui.PrintErrorf("Error: %v", err)
+				return
+			}
+
+			value, err = nt.Str2Wei(args[len(args)-1])
+			if err != nil {
+				ui.PrintErrorf("Invalid value: %s", args[len(args)-1])
+				return
+			}
+			args = args[:len(args)-1]
+		}
+
+		// the wallet address names and ENS names are accepted too
+		for i, in := range m.Inputs {
+			if i < len(args) && in.Type.T == abi.AddressTy && !common.IsHexAddress(args[i]) {
+				a, err := parseAddress(w, args[i])
+				if err != nil {
+					ui.PrintErrorf("Argument %d: %v", i+1, err)
+					return
+				}
+				args[i] = a.String()
+			}
+		}
+
+		values, err := eth.ParseContractArgs(m, args)
+		if err != nil {
+			ui.PrintErrorf("%v", err)
+			return
+		}
+
+		if subcommand == "write" {
+			data, err := eth.ContractCallData(m, values)
+			if err != nil {
+				ui.PrintErrorf("Error packing call: %v", err)
+				return
+			}
+
+			bus.Send("eth", "send-tx", &bus.B_EthSendTx{
+				ChainId: b.ChainId,
+				From:    w.CurrentAddress,
+				To:      to,
+				Amount:  value,
+				Data:    data,
+			})
+			return
+		}
+
+		if !m.IsConstant() {
+			ui.Printf("%s is not a view method, the result is simulated\n", m.Sig)
+		}
+
+		outputs, err := eth.ReadContract(b, w.CurrentAddress, to, m, values)
+		if err != nil {
+			ui.PrintErrorf("Error calling %s: %v", m.Sig, err)
+			return
+		}
+
+		ui.Printf("\n%s on %s\n", m.Sig, b.Name)
+		for i, out := range m.Outputs {
+			if i >= len(outputs) {
+				break
+			}
+			printContractValue(b, to, m, out, outputs[i])
+		}
+	default:
+		ui.PrintErrorf("Invalid subcommand: %s", subcommand)
+	}
+}
+
+func printContractValue(b *cmn.Blockchain, to common.Address, m *abi.Method, out abi.Argument, v interface{}) {
+	name := out.Name
+	if name == "" {
+		name = out.Type.String()
+	}
+	ui.Printf("  %s: ", name)
+
+	switch out.Type.T {
+	case abi.AddressTy:
+		cmn.AddAddressShortLink(ui.Terminal.Screen, v.(common.Address))
+	case abi.UintTy:
+		s := eth.FormatContractValue(out.Type, v)
+		if t := eth.ContractValueToken(b, to, m, out.Name, out.Type); t != nil {
+			ui.Terminal.Screen.AddLink(t.Value2Str(v.(*big.Int))+" "+t.Symbol, "copy "+s, s, "")
+		} else {
+			ui.Terminal.Screen.AddLink(s, "copy "+s, "Copy value", "")
+		}
+	default:
+		ui.Printf("%s", eth.FormatContractValue(out.Type, v))
+	}
+	ui.Printf("\n")
+}
+
+func typeList(args abi.Arguments) string {
+	r := []string{}
+	for _, a := range args {
+		s := a.Type.String()
+		if a.Name != "" {
+			s += " " + a.Name
+		}
+		r = append(r, s)
+	}
+	return "(" + strings.Join(r, ", ") + ")"
+}
@@ -0,0 +1,249 @@
+package command
+
+import (
+	"math/big"
+	"strings"
+
+	"github.com/AlexNa-Holdings/web3pro/bus"
+	"github.com/AlexNa-Holdings/web3pro/cmn"
+	"github.com/AlexNa-Holdings/web3pro/eth"
+	"github.com/AlexNa-Holdings/web3pro/ui"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var nft_subcommands = []string{"list", "discover", "send", "on", "off"}
+
+func NewNFTCommand() *Command {
+	return &Command{
+		Command:      "nft",
+		ShortCommand: "",
+		Subcommands:  nft_subcommands,
+		Usage: `
+Usage: nft [COMMAND]
+
+ERC-721 and ERC-1155 holdings of the wallet addresses
+
+Commands:
+  list [ADDRESS]                          - List the holdings on the current chain
+  discover [ADDRESS]                      - Find the holdings on the current chain
+  send FROM CONTRACT ID TO [AMOUNT]       - Transfer with safeTransferFrom
+  on                                      - Open NFT pane
+  off                                     - Close NFT pane
+
+Note: The tokens are found from the Transfer, TransferSingle and
+TransferBatch events (explorer API or eth_getLogs) and confirmed with
+ownerOf/balanceOf. The AMOUNT is used by ERC-1155 only (default 1).
+LP position NFTs are listed by the LP panes.
+		`,
+		Help:             `NFT holdings and transfers`,
+		Process:          NFT_Process,
+		AutoCompleteFunc: NFT_AutoComplete,
+	}
+}
+
+func NFT_AutoComplete(input string) (string, *[]ui.ACOption, string) {
+	options := []ui.ACOption{}
+
+	if cmn.CurrentWallet == nil {
+		return "", &options, ""
+	}
+
+	w := cmn.CurrentWallet
+
+	p := cmn.SplitN(input, 3)
+	command, subcommand, param := p[0], p[1], p[2]
+
+	if !cmn.IsInArray(nft_subcommands, subcommand) {
+		for _, sc := range nft_subcommands {
+			if input == "" || strings.Contains(sc, subcommand) {
+				options = append(options, ui.ACOption{Name: sc, Result: command + " " + sc + " "})
+			}
+		}
+		return "action", &options, subcommand
+	}
+
+	if subcommand == "list" || subcommand == "discover" {
+		for _, a := range w.Addresses {
+			if cmn.Contains(a.Name+a.Address.String(), param) {
+				options = append(options, ui.ACOption{
+					Name:   cmn.ShortAddress(a.Address) + " " + a.Name,
+					Result: command + " " + subcommand + " '" + a.Name + "'"})
+			}
+		}
+		return "address", &options, param
+	}
+
+	return "", &options, ""
+}
+
+func NFT_Process(c *Command, input string) {
+	if cmn.CurrentWallet == nil {
+		ui.PrintErrorf("No wallet open")
+		return
+	}
+
+	w := cmn.CurrentWallet
+
+	b := w.GetBlockchain(w.CurrentChainId)
+	if b == nil {
+		ui.PrintErrorf("No current blockchain")
+		return
+	}
+
+	//parse command subcommand parameters
+	tokens := cmn.SplitN(input, 7)
+	_, subcommand, p0, p1, p2, p3, p4 := tokens[0], tokens[1], tokens[2], tokens[3], tokens[4], tokens[5], tokens[6]
+
+	switch subcommand {
+	case "list", "", "discover":
+		addresses := w.Addresses
+		if p0 != "" {
+			a := w.GetAddressByName(p0)
+			if a == nil {
+				ui.PrintErrorf("Address not found: %s", p0)
+				return
+			}
+			addresses = []*cmn.Address{a}
+		}
+
+		for _, a := range addresses {
+			if subcommand == "discover" {
+				list, err := eth.DiscoverNFTs(b, a.Address)
+				if err != nil {
+					ui.PrintErrorf("Error discovering NFTs of %s: %v", a.Name, err)
+					continue
+				}
+
+				if err := w.SetNFTs(b.ChainId, a.Address, list); err != nil {
+					ui.PrintErrorf("Error saving wallet: %v", err)
+					return
+				}
+			}
+
+			ui.Printf("\nNFTs of ")
+			cmn.AddAddressShortLink(ui.Terminal.Screen, a.Address)
+			ui.Printf(" %s on %s\n", a.Name, b.Name)
+
+			list := w.GetNFTs(b.ChainId, a.Address)
+			if len(list) == 0 {
+				ui.Printf("  (none)\n")
+				continue
+			}
+
+			for _, n := range list {
+				printNFT(n)
+			}
+		}
+
+		if subcommand != "discover" {
+			action := "command nft discover"
+			if p0 != "" {
+				action += " '" + p0 + "'"
+			}
+			ui.Printf("\n")
+			ui.Terminal.Screen.AddLink("discover", action, "Find the holdings on "+b.Name, "")
+			ui.Printf("\n")
+		}
+	case "send":
+		from := w.GetAddressByName(p0)
+		if from == nil {
+			from = w.GetAddress(p0)
+		}
+		if from == nil {
+			ui.PrintErrorf("Address not found: %s", p0)
+			return
+		}
+
+		if !common.IsHexAddress(p1) {
+			ui.PrintErrorf("Usage: nft send FROM CONTRACT ID TO [AMOUNT]")
+			return
+		}
+
+		id, ok := new(big.Int).SetString(p2, 0)
+		if !ok {
+			ui.PrintErrorf("Invalid token id: %s", p2)
+			return
+		}
+
+		n := w.GetNFT(b.ChainId, from.Address, common.HexToAddress(p1), id)
+		if n == nil {
+			ui.PrintErrorf("NFT not found, run: nft discover")
+			return
+		}
+
+		if p3 == "" {
+			bus.Send("ui", "popup", ui.DlgNFTSend(b, n, from))
+			return
+		}
+
+		to, err := parseAddress(w, p3)
+		if err != nil {
+			ui.PrintErrorf("Invalid address to: %v", err)
+			return
+		}
+
+		amount := big.NewInt(1)
+		if p4 != "" {
+			amount, ok = new(big.Int).SetString(p4, 10)
+			if !ok {
+				ui.PrintErrorf("Invalid amount: %s", p4)
+				return
+			}
+		}
+
+		if n.Balance != nil && amount.Cmp(n.Balance) > 0 {
+			ui.PrintErrorf("Insufficient balance: %s", n.Balance.String())
+			return
+		}
+
+		data, err := eth.BuildNFTTransferCall(n, to, amount)
+		if err != nil {
+			ui.PrintErrorf("Error building transfer call: %v", err)
+			return
+		}
+
+		bus.Send("eth", "send-tx", &bus.B_EthSendTx{
+			ChainId: b.ChainId,
+			From:    from.Address,
+			To:      n.Contract,
+			Amount:  big.NewInt(0),
+			Data:    data,
+		})
+	case "on":
+		w.NFTPaneOn = true
+		if err := w.Save(); err != nil {
+			ui.PrintErrorf("Error saving wallet: %v", err)
+			return
+		}
+		ui.Printf("NFT pane enabled\n")
+	case "off":
+		w.NFTPaneOn = false
+		if err := w.Save(); err != nil {
+			ui.PrintErrorf("Error saving wallet: %v", err)
+			return
+		}
+		ui.Printf("NFT pane disabled\n")
+	default:
+		ui.PrintErrorf("Invalid subcommand: %s", subcommand)
+	}
+}
+
+func printNFT(n *cmn.NFT) {
+	collection := n.Collection
+	if collection == "" {
+		collection = cmn.ShortAddress(n.Contract)
+	}
+
+	ui.Printf("  ")
+	ui.Terminal.Screen.AddLink(cmn.FixedWidth(collection, 16), "copy "+n.Contract.String(), n.Contract.String(), "")
+	ui.Printf(" #%-10s", cmn.FixedWidth(n.TokenId.String(), 10))
+
+	if n.IsERC1155() {
+		ui.Printf(" x%-6s ERC-1155 ", n.Balance.String())
+	} else {
+		ui.Printf("         ERC-721  ")
+	}
+
+	ui.Terminal.Screen.AddLink(cmn.ICON_SEND, "command nft send "+n.Owner.String()+" "+n.Contract.String()+" "+n.TokenId.String(), "Transfer", "")
+	ui.Printf("\n")
+}
@@ -0,0 +1,144 @@
+package command
+
+import (
+	"strings"
+	"time"
+
+	"github.com/AlexNa-Holdings/web3pro/cmn"
+	"github.com/AlexNa-Holdings/web3pro/eth"
+	"github.com/AlexNa-Holdings/web3pro/ui"
+)
+
+var nonce_subcommands = []string{"list", "resync"}
+
+func NewNonceCommand() *Command {
+	return &Command{
+		Command:      "nonce",
+		ShortCommand: "",
+		Subcommands:  nonce_subcommands,
+		Usage: `
+Usage: nonce [COMMAND]
+
+Inspect the local nonce queues
+
+Commands:
+  list              - List reserved and in-flight nonces
+  resync [ADDRESS]  - Drop the local queue of the address on the current chain
+
+Note: A nonce reserved for signing is released after 10 minutes, a sent
+transaction unknown to the node is treated as dropped after 5 minutes.
+		`,
+		Help:             `Inspect the local nonce queues`,
+		Process:          Nonce_Process,
+		AutoCompleteFunc: Nonce_AutoComplete,
+	}
+}
+
+func Nonce_AutoComplete(input string) (string, *[]ui.ACOption, string) {
+	options := []ui.ACOption{}
+
+	if cmn.CurrentWallet == nil {
+		return "", &options, ""
+	}
+
+	w := cmn.CurrentWallet
+
+	p := cmn.SplitN(input, 3)
+	command, subcommand, param := p[0], p[1], p[2]
+
+	if !cmn.IsInArray(nonce_subcommands, subcommand) {
+		for _, sc := range nonce_subcommands {
+			if input == "" || strings.Contains(sc, subcommand) {
+				options = append(options, ui.ACOption{Name: sc, Result: command + " " + sc + " "})
+			}
+		}
+		return "action", &options, subcommand
+	}
+
+	if subcommand == "resync" {
+		for _, a := range w.Addresses {
+			if a.Signer != "" && cmn.Contains(a.Name+a.Address.String(), param) {
+				options = append(options, ui.ACOption{
+					Name:   cmn.ShortAddress(a.Address) + " " + a.Name,
+					Result: command + " " + subcommand + " '" + a.Name + "'"})
+			}
+		}
+		return "address", &options, param
+	}
+
+	return "", &options, ""
+}
+
+func Nonce_Process(c *Command, input string) {
+	if cmn.CurrentWallet == nil {
+		ui.PrintErrorf("No wallet open")
+		return
+	}
+
+	w := cmn.CurrentWallet
+
+	//parse command subcommand parameters
+	tokens := cmn.SplitN(input, 3)
+	_, subcommand, p0 := tokens[0], tokens[1], tokens[2]
+
+	switch subcommand {
+	case "list", "":
+		queues := eth.NonceQueues()
+		if len(queues) == 0 {
+			ui.Printf("\nNo reserved or in-flight nonces\n")
+			return
+		}
+
+		for _, q := range queues {
+			b := w.GetBlockchain(q.ChainId)
+			if b == nil {
+				continue
+			}
+
+			ui.Printf("\n%s ", b.Name)
+			cmn.AddAddressShortLink(ui.Terminal.Screen, q.Address)
+			if a := w.GetAddress(q.Address); a != nil {
+				ui.Printf(" %s", a.Name)
+			}
+
+			mined, pending, err := eth.GetChainNonces(b, q.Address)
+			if err != nil {
+				ui.Printf(" (chain nonce unknown: %v)\n", err)
+			} else {
+				ui.Printf(" mined: %d pending: %d\n", mined, pending)
+			}
+
+			for _, s := range q.Slots {
+				ui.Printf("  %5d %-8s %4s ", s.Nonce, s.State, time.Since(s.Time).Round(time.Second))
+				if s.State == eth.NONCE_SENT {
+					ui.Terminal.Screen.AddLink(s.Hash.Hex()[:10], "command tx show "+s.Hash.Hex(), "Show transaction", "")
+				}
+				ui.Printf("\n")
+			}
+		}
+	case "resync":
+		b := w.GetBlockchain(w.CurrentChainId)
+		if b == nil {
+			ui.PrintErrorf("No current blockchain")
+			return
+		}
+
+		a := w.GetAddressByName(p0)
+		if a == nil {
+			ui.PrintErrorf("Address not found: %s", p0)
+			return
+		}
+
+		eth.ResyncNonces(b.ChainId, a.Address)
+
+		mined, pending, err := eth.GetChainNonces(b, a.Address)
+		if err != nil {
+			ui.PrintErrorf("Error reading nonce: %v", err)
+			return
+		}
+
+		ui.Printf("Nonce queue of %s on %s resynced, mined: %d pending: %d\n", a.Name, b.Name, mined, pending)
+	default:
+		ui.PrintErrorf("Invalid subcommand: %s", subcommand)
+	}
+}
@@ -0,0 +1,369 @@
+package command
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/AlexNa-Holdings/web3pro/bus"
+	"github.com/AlexNa-Holdings/web3pro/cmn"
+	"github.com/AlexNa-Holdings/web3pro/ui"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var policy_subcommands = []string{"list", "show", "add", "remove", "mode", "limit", "allow", "disallow", "trusted", "forbid", "unforbid", "unlimited"}
+
+func NewPolicyCommand() *Command {
+	return &Command{
+		Command:      "policy",
+		ShortCommand: "",
+		Subcommands:  policy_subcommands,
+		Usage: `
+Usage: policy [COMMAND]
+
+Manage signing policies of addresses and signers
+
+Commands:
+  list                                    - List policies
+  show [POLICY]                           - Show policy rules
+  add [ADDRESS|SIGNER]                    - Add policy for an address or all addresses of a signer
+  remove [POLICY]                         - Remove policy
+  mode [POLICY] [confirm|block]           - Ask to override violations, or reject them
+  limit [POLICY] [CHAIN] [TOKEN] [AMOUNT] - Daily spending cap (0 removes the cap)
+  allow [POLICY] [ADDRESS]                - Add recipient or contract to the allowlist
+  disallow [POLICY] [ADDRESS]             - Remove address from the allowlist
+  trusted [POLICY] [on|off]               - Trusted contracts pass the allowlist
+  forbid [POLICY] [METHOD]                - Forbid method (name, signature or 0x selector)
+  unforbid [POLICY] [METHOD]              - Allow forbidden method again
+  unlimited [POLICY] [on|off]             - Forbid unlimited approvals and permits
+
+Note: With an empty allowlist and 'trusted off' any recipient is allowed.
+		`,
+		Help:             `Manage signing policies`,
+		Process:          Policy_Process,
+		AutoCompleteFunc: Policy_AutoComplete,
+	}
+}
+
+func Policy_AutoComplete(input string) (string, *[]ui.ACOption, string) {
+	options := []ui.ACOption{}
+
+	if cmn.CurrentWallet == nil {
+		return "", &options, ""
+	}
+
+	w := cmn.CurrentWallet
+
+	p := cmn.SplitN(input, 4)
+	command, subcommand, param, p3 := p[0], p[1], p[2], p[3]
+
+	if !cmn.IsInArray(policy_subcommands, subcommand) {
+		for _, sc := range policy_subcommands {
+			if input == "" || strings.Contains(sc, subcommand) {
+				options = append(options, ui.ACOption{Name: sc, Result: command + " " + sc + " "})
+			}
+		}
+		return "action", &options, subcommand
+	}
+
+	if subcommand == "add" {
+		for _, s := range w.Signers {
+			if cmn.Contains(s.Name, param) {
+				options = append(options, ui.ACOption{
+					Name: s.Name + " (signer)", Result: command + " " + subcommand + " '" + s.Name + "'"})
+			}
+		}
+		for _, a := range w.Addresses {
+			if cmn.Contains(a.Name+a.Address.String(), param) {
+				options = append(options, ui.ACOption{
+					Name:   cmn.ShortAddress(a.Address) + " " + a.Name,
+					Result: command + " " + subcommand + " '" + a.Name + "'"})
+			}
+		}
+		return "address", &options, param
+	}
+
+	if subcommand == "list" {
+		return "", &options, ""
+	}
+
+	if w.GetPolicy(param) == nil || (p3 == "" && !strings.HasSuffix(input, " ")) {
+		for _, pl := range w.Policies {
+			name := w.PolicyName(pl)
+			if cmn.Contains(name, param) {
+				options = append(options, ui.ACOption{
+					Name: name, Result: command + " " + subcommand + " '" + name + "' "})
+			}
+		}
+		return "policy", &options, param
+	}
+
+	values := []string{}
+	switch subcommand {
+	case "mode":
+		values = cmn.POLICY_MODES
+	case "trusted", "unlimited":
+		values = []string{"on", "off"}
+	case "forbid":
+		for k := range cmn.KNOWN_SELECTORS {
+			values = append(values, k)
+		}
+		slices.Sort(values)
+	case "unforbid":
+		values = w.GetPolicy(param).ForbiddenSelectors
+	case "disallow":
+		for _, a := range w.GetPolicy(param).Allowlist {
+			values = append(values, a.String())
+		}
+	case "limit":
+		for _, b := range w.Blockchains {
+			values = append(values, b.Name)
+		}
+	}
+
+	for _, v := range values {
+		if cmn.Contains(v, p3) {
+			options = append(options, ui.ACOption{
+				Name: v, Result: command + " " + subcommand + " '" + param + "' '" + v + "' "})
+		}
+	}
+	return "value", &options, p3
+}
+
+func Policy_Process(c *Command, input string) {
+	if cmn.CurrentWallet == nil {
+		ui.PrintErrorf("No wallet open")
+		return
+	}
+
+	w := cmn.CurrentWallet
+
+	//parse command subcommand parameters
+	tokens := cmn.SplitN(input, 6)
+	_, subcommand, p0, p1, p2, p3 := tokens[0], tokens[1], tokens[2], tokens[3], tokens[4], tokens[5]
+
+	if subcommand == "list" || subcommand == "" {
+		ui.Printf("\nPolicies:\n")
+		for _, p := range w.Policies {
+			name := w.PolicyName(p)
+			ui.Terminal.Screen.AddLink(cmn.ICON_EDIT, "command policy show '"+name+"'", "Show policy", "")
+			ui.Terminal.Screen.AddLink(cmn.ICON_DELETE, "command policy remove '"+name+"'", "Remove policy", "")
+			kind := "address"
+			if p.Signer != "" {
+				kind = "signer"
+			}
+			ui.Printf(" %-14s (%s) %s\n", name, kind, p.Mode)
+		}
+		return
+	}
+
+	if subcommand == "add" {
+		if w.GetPolicy(p0) != nil {
+			ui.PrintErrorf("Policy already exists: %s", p0)
+			return
+		}
+
+		p := &cmn.Policy{Mode: cmn.POLICY_MODE_CONFIRM}
+		if s := w.GetSigner(p0); s != nil {
+			p.Signer = s.Name
+		} else if a := w.GetAddressByName(p0); a != nil {
+			p.Address = a.Address
+		} else {
+			ui.PrintErrorf("Address or signer not found: %s", p0)
+			return
+		}
+
+		w.Policies = append(w.Policies, p)
+		if err := w.Save(); err != nil {
+			ui.PrintErrorf("Error saving wallet: %v", err)
+			return
+		}
+
+		ui.Printf("Policy added: %s\n", w.PolicyName(p))
+		return
+	}
+
+	if !cmn.IsInArray(policy_subcommands, subcommand) {
+		ui.PrintErrorf("Invalid subcommand: %s", subcommand)
+		return
+	}
+
+	p := w.GetPolicy(p0)
+	if p == nil {
+		ui.PrintErrorf("Policy not found: %s", p0)
+		return
+	}
+
+	switch subcommand {
+	case "show":
+		printPolicy(w, p)
+		return
+	case "remove":
+		bus.Send("ui", "popup", ui.DlgConfirm(
+			"Remove policy",
+			`
+<c>Are you sure you want to remove policy
+<c> `+w.PolicyName(p)+"? \n",
+			func() bool {
+				for i, pl := range w.Policies {
+					if pl == p {
+						w.Policies = append(w.Policies[:i], w.Policies[i+1:]...)
+						break
+					}
+				}
+
+				if err := w.Save(); err != nil {
+					ui.PrintErrorf("Error saving wallet: %v", err)
+					return false
+				}
+				ui.Notification.Show("Policy removed")
+				return true
+			}))
+		return
+	case "mode":
+		if !cmn.IsInArray(cmn.POLICY_MODES, p1) {
+			ui.PrintErrorf("Invalid mode: %s (use %s)", p1, strings.Join(cmn.POLICY_MODES, " or "))
+			return
+		}
+		p.Mode = p1
+	case "limit":
+		b := w.GetBlockchainByName(p1)
+		if b == nil {
+			ui.PrintErrorf("Blockchain not found: %s", p1)
+			return
+		}
+
+		t := w.GetToken(b.ChainId, p2)
+		if t == nil {
+			ui.PrintErrorf("Token not found: %s", p2)
+			return
+		}
+
+		amount, err := t.Str2Wei(p3)
+		if err != nil || amount.Sign() < 0 {
+			ui.PrintErrorf("Invalid amount: %s", p3)
+			return
+		}
+
+		limits := []*cmn.PolicyLimit{}
+		for _, l := range p.DailyLimits {
+			if l != p.GetLimit(b.ChainId, t.Address, t.Native) {
+				limits = append(limits, l)
+			}
+		}
+		if amount.Sign() > 0 {
+			limits = append(limits, &cmn.PolicyLimit{ChainId: b.ChainId, Token: t.Address, Native: t.Native, Amount: amount})
+		}
+		p.DailyLimits = limits
+	case "allow", "disallow":
+		var address common.Address
+		if a := w.GetAddressByName(p1); a != nil {
+			address = a.Address
+		} else if common.IsHexAddress(p1) {
+			address = common.HexToAddress(p1)
+		} else {
+			ui.PrintErrorf("Invalid address: %s", p1)
+			return
+		}
+
+		p.Allowlist = slices.DeleteFunc(p.Allowlist, func(a common.Address) bool { return a == address })
+		if subcommand == "allow" {
+			p.Allowlist = append(p.Allowlist, address)
+		}
+	case "trusted", "unlimited":
+		if p1 != "on" && p1 != "off" {
+			ui.PrintErrorf("Use 'on' or 'off'")
+			return
+		}
+		if subcommand == "trusted" {
+			p.AllowTrusted = p1 == "on"
+		} else {
+			p.NoUnlimitedApprovals = p1 == "on"
+		}
+	case "forbid", "unforbid":
+		sel, err := cmn.ParseSelector(p1)
+		if err != nil {
+			ui.PrintErrorf("%v", err)
+			return
+		}
+
+		p.ForbiddenSelectors = slices.DeleteFunc(p.ForbiddenSelectors, func(s string) bool { return s == sel })
+		if subcommand == "forbid" {
+			p.ForbiddenSelectors = append(p.ForbiddenSelectors, sel)
+		}
+	}
+
+	if err := w.Save(); err != nil {
+		ui.PrintErrorf("Error saving wallet: %v", err)
+		return
+	}
+
+	printPolicy(w, p)
+}
+
+func printPolicy(w *cmn.Wallet, p *cmn.Policy) {
+	name := w.PolicyName(p)
+
+	ui.Printf("\nPolicy: %s\n", name)
+	ui.Printf("Mode: %s\n", p.Mode)
+
+	ui.Printf("Daily limits:\n")
+	for _, l := range p.DailyLimits {
+		b := w.GetBlockchain(l.ChainId)
+		if b == nil {
+			continue
+		}
+
+		var t *cmn.Token
+		if l.Native {
+			t, _ = w.GetNativeToken(b)
+		} else {
+			t = w.GetTokenByAddress(l.ChainId, l.Token)
+		}
+		if t == nil {
+			continue
+		}
+
+		token := t.Symbol
+		if !t.Native {
+			token = t.Address.String()
+		}
+
+		spent := w.SpentToday(p, l.ChainId, l.Token, l.Native)
+		ui.Printf("  %-10s %s %s (spent %s) ", b.Name, t.Value2Str(l.Amount), t.Symbol, t.Value2Str(spent))
+		ui.Terminal.Screen.AddLink(cmn.ICON_DELETE, "command policy limit '"+name+"' '"+b.Name+"' "+token+" 0", "Remove limit", "")
+		ui.Printf("\n")
+	}
+
+	ui.Printf("Allowlist:")
+	if !p.HasAllowlist() {
+		ui.Printf(" (any recipient)")
+	}
+	ui.Printf("\n")
+	for _, a := range p.Allowlist {
+		ui.Printf("  ")
+		cmn.AddAddressShortLink(ui.Terminal.Screen, a)
+		if wa := w.GetAddress(a); wa != nil {
+			ui.Printf(" %s", wa.Name)
+		} else if c := w.GetContract(a); c != nil {
+			ui.Printf(" %s", c.Name)
+		}
+		ui.Printf(" ")
+		ui.Terminal.Screen.AddLink(cmn.ICON_DELETE, "command policy disallow '"+name+"' "+a.String(), "Remove from allowlist", "")
+		ui.Printf("\n")
+	}
+	ui.Printf("Trusted contracts allowed: %t\n", p.AllowTrusted)
+
+	ui.Printf("Forbidden methods:\n")
+	for _, s := range p.ForbiddenSelectors {
+		known := ""
+		for k, v := range cmn.KNOWN_SELECTORS {
+			if v == s {
+				known = k
+			}
+		}
+		ui.Printf("  %s %s ", s, known)
+		ui.Terminal.Screen.AddLink(cmn.ICON_DELETE, "command policy unforbid '"+name+"' "+s, "Allow method", "")
+		ui.Printf("\n")
+	}
+	ui.Printf("Unlimited approvals forbidden: %t\n", p.NoUnlimitedApprovals)
+}
@@ -0,0 +1,288 @@
+package command
+
+import (
+	"os"
+	"strings"
+
+	"github.com/AlexNa-Holdings/web3pro/bus"
+	"github.com/AlexNa-Holdings/web3pro/cmn"
+	"github.com/AlexNa-Holdings/web3pro/eth"
+	"github.com/AlexNa-Holdings/web3pro/ui"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var safe_subcommands = []string{"add", "list", "info", "pending", "open", "import", "export", "discard"}
+
+func NewSafeCommand() *Command {
+	return &Command{
+		Command:      "safe",
+		ShortCommand: "",
+		Subcommands:  safe_subcommands,
+		Usage: `
+Usage: safe [COMMAND]
+
+Manage Gnosis Safe multisig accounts
+
+Commands:
+  add [ADDRESS] [NAME]   - Add Safe deployed on the current blockchain
+  list                   - List Safes
+  info [SAFE]            - Read owners and threshold from the chain
+  pending                - List partially signed Safe transactions
+  open [SAFETX]          - Sign or execute a pending Safe transaction
+  import [FILE]          - Import Safe transaction signed by co-signers
+  export [SAFETX] [FILE] - Export Safe transaction for co-signers
+  discard [SAFETX]       - Discard pending Safe transaction
+
+Note: Use 'send' from a Safe address to propose a new Safe transaction.
+		`,
+		Help:             `Manage Gnosis Safe multisig accounts`,
+		Process:          Safe_Process,
+		AutoCompleteFunc: Safe_AutoComplete,
+	}
+}
+
+func Safe_AutoComplete(input string) (string, *[]ui.ACOption, string) {
+	options := []ui.ACOption{}
+
+	if cmn.CurrentWallet == nil {
+		return "", &options, ""
+	}
+
+	w := cmn.CurrentWallet
+
+	p := cmn.SplitN(input, 4)
+	command, subcommand, param := p[0], p[1], p[2]
+
+	if !cmn.IsInArray(safe_subcommands, subcommand) {
+		for _, sc := range safe_subcommands {
+			if input == "" || strings.Contains(sc, subcommand) {
+				options = append(options, ui.ACOption{Name: sc, Result: command + " " + sc + " "})
+			}
+		}
+		return "action", &options, subcommand
+	}
+
+	switch subcommand {
+	case "info":
+		for _, a := range w.Addresses {
+			if a.Safe != nil && cmn.Contains(a.Name+a.Address.String(), param) {
+				options = append(options, ui.ACOption{
+					Name:   cmn.ShortAddress(a.Address) + " " + a.Name,
+					Result: command + " " + subcommand + " '" + a.Name + "'"})
+			}
+		}
+		return "safe", &options, param
+	case "open", "export", "discard":
+		for _, f := range eth.SafeTxList() {
+			if cmn.Contains(f, param) {
+				options = append(options, ui.ACOption{
+					Name: f, Result: command + " " + subcommand + " '" + f + "' "})
+			}
+		}
+		return "safe tx", &options, param
+	}
+
+	return "", &options, ""
+}
+
+func Safe_Process(c *Command, input string) {
+	if cmn.CurrentWallet == nil {
+		ui.PrintErrorf("No wallet open")
+		return
+	}
+
+	w := cmn.CurrentWallet
+
+	//parse command subcommand parameters
+	tokens := cmn.SplitN(input, 4)
+	_, subcommand, p0, p1 := tokens[0], tokens[1], tokens[2], tokens[3]
+
+	switch subcommand {
+	case "add":
+		if !common.IsHexAddress(p0) {
+			ui.PrintErrorf("Invalid address")
+			return
+		}
+
+		b := w.GetBlockchain(w.CurrentChainId)
+		if b == nil {
+			ui.PrintErrorf("No current blockchain")
+			return
+		}
+
+		address := common.HexToAddress(p0)
+		a := w.GetAddress(address)
+		if a != nil && a.Signer != "" {
+			ui.PrintErrorf("Address %s has a signer, a Safe is a contract", a.Name)
+			return
+		}
+
+		s, err := eth.ReadSafe(b, address)
+		if err != nil {
+			ui.PrintErrorf("Error reading Safe: %v", err)
+			return
+		}
+
+		if a == nil {
+			name := p1
+			if name == "" {
+				name = "Safe " + cmn.ShortAddress(address)
+			}
+			if w.GetAddressByName(name) != nil {
+				ui.PrintErrorf("Address with name %s already exists", name)
+				return
+			}
+			a = &cmn.Address{Name: name, Address: address}
+			w.Addresses = append(w.Addresses, a)
+		}
+		a.Safe = s
+
+		if err := w.Save(); err != nil {
+			ui.PrintErrorf("Error saving wallet: %v", err)
+			return
+		}
+
+		ui.Printf("\nSafe added: %s\n", a.Name)
+		printSafe(a)
+	case "list", "":
+		ui.Printf("\nSafes:\n")
+		for _, a := range w.Addresses {
+			if a.Safe == nil {
+				continue
+			}
+
+			chain := "(unknown chain)"
+			if b := w.GetBlockchain(a.Safe.ChainId); b != nil {
+				chain = b.Name
+			}
+
+			cmn.AddAddressShortLink(ui.Terminal.Screen, a.Address)
+			ui.Printf(" ")
+			ui.Terminal.Screen.AddLink(a.Name, "command safe info '"+a.Name+"'", "Show Safe info", "")
+			ui.Printf(" %d of %d %s\n", a.Safe.Threshold, len(a.Safe.Owners), chain)
+		}
+	case "info":
+		a := w.GetAddressByName(p0)
+		if a == nil || a.Safe == nil {
+			ui.PrintErrorf("Safe not found: %s", p0)
+			return
+		}
+
+		b := w.GetBlockchain(a.Safe.ChainId)
+		if b == nil {
+			ui.PrintErrorf("Blockchain not found: %d", a.Safe.ChainId)
+			return
+		}
+
+		if err := eth.RefreshSafe(b, a); err != nil {
+			ui.PrintErrorf("Error reading Safe: %v", err)
+			return
+		}
+
+		ui.Printf("\n%s\n", a.Name)
+		printSafe(a)
+
+		if nonce, err := eth.GetSafeNonce(b, a.Address); err == nil {
+			ui.Printf("Nonce: %s\n", nonce.String())
+		}
+	case "pending":
+		ui.Printf("\nPending Safe transactions:\n")
+		for _, f := range eth.SafeTxList() {
+			ui.Terminal.Screen.AddLink(cmn.ICON_EDIT, "command safe open '"+f+"'", "Sign or execute", "")
+			ui.Terminal.Screen.AddLink(cmn.ICON_DELETE, "command safe discard '"+f+"'", "Discard", "")
+			ui.Printf(" %s\n", f)
+		}
+	case "open":
+		openSafeTx(eth.SafeTxFolder() + "/" + p0)
+	case "import":
+		if p0 == "" {
+			ui.PrintErrorf("Usage: safe import [FILE]")
+			return
+		}
+
+		file, err := eth.ImportSafeTx(p0)
+		if err != nil {
+			ui.PrintErrorf("Error importing Safe transaction: %v", err)
+			return
+		}
+
+		ui.Printf("Safe transaction imported: %s\n", file)
+		openSafeTx(file)
+	case "export":
+		if p0 == "" || p1 == "" {
+			ui.PrintErrorf("Usage: safe export [SAFETX] [FILE]")
+			return
+		}
+
+		st, err := eth.LoadSafeTx(eth.SafeTxFolder() + "/" + p0)
+		if err != nil {
+			ui.PrintErrorf("Error loading Safe transaction: %v", err)
+			return
+		}
+
+		if err := eth.ExportSafeTx(st, p1); err != nil {
+			ui.PrintErrorf("Error exporting Safe transaction: %v", err)
+			return
+		}
+
+		ui.Printf("Safe transaction exported to %s\n", p1)
+	case "discard":
+		file := eth.SafeTxFolder() + "/" + p0
+		if _, err := os.Stat(file); p0 == "" || err != nil {
+			ui.PrintErrorf("Safe transaction not found: %s", p0)
+			return
+		}
+
+		bus.Send("ui", "popup", ui.DlgConfirm(
+			"Discard Safe transaction",
+			`
+<c>Are you sure you want to discard
+<c> `+p0+"? \n",
+			func() bool {
+				if err := os.Remove(file); err != nil {
+					ui.PrintErrorf("Error removing file: %v", err)
+					return false
+				}
+				ui.Notification.Show("Safe transaction discarded")
+				return true
+			}))
+	default:
+		ui.PrintErrorf("Invalid subcommand: %s", subcommand)
+	}
+}
+
+func printSafe(a *cmn.Address) {
+	w := cmn.CurrentWallet
+
+	ui.Printf("Version: %s\n", a.Safe.Version)
+	ui.Printf("Threshold: %d of %d\n", a.Safe.Threshold, len(a.Safe.Owners))
+	ui.Printf("Owners:\n")
+	for _, o := range a.Safe.Owners {
+		ui.Printf("  ")
+		cmn.AddAddressShortLink(ui.Terminal.Screen, o)
+		if oa := w.GetAddress(o); oa != nil {
+			signerInfo := oa.Signer
+			if signerInfo == "" {
+				signerInfo = "watch"
+			}
+			ui.Printf(" %s (%s)", oa.Name, signerInfo)
+		}
+		ui.Printf("\n")
+	}
+}
+
+func openSafeTx(file string) {
+	st, err := eth.LoadSafeTx(file)
+	if err != nil {
+		ui.PrintErrorf("Error loading Safe transaction: %v", err)
+		return
+	}
+
+	a := cmn.CurrentWallet.GetAddress(st.Safe)
+	if a == nil || a.Safe == nil {
+		ui.PrintErrorf("Safe %s is not in the wallet, use 'safe add'", st.Safe.String())
+		return
+	}
+
+	bus.Send("eth", "safe-tx", &bus.B_EthSafeTx{File: file})
+}
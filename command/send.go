@@ -7,6 +7,7 @@ import (
 
 	"github.com/AlexNa-Holdings/web3pro/bus"
 	"github.com/AlexNa-Holdings/web3pro/cmn"
+	"github.com/AlexNa-Holdings/web3pro/eth"
 	"github.com/AlexNa-Holdings/web3pro/ui"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/rs/zerolog/log"
@@ -18,6 +19,15 @@ func NewSendCommand() *Command {
 		ShortCommand: "",
 		Usage: `
 Usage: send [BLOCKCHAIN] [TOKEN/ADDRESS] [FROM] [TO] amount
+       send batch FILE FROM [sequential|disperse]
+
+The batch file is a CSV with the rows: chain, token, to, amount
+(the header line is optional). The payments are sent one by one
+(sequential, default) or with one Disperse contract call per token.
+The status of every row is written to FILE_result.csv
+
+TO is a hex address, a wallet address name or an ENS name (resolved on
+the Ethereum blockchain of the wallet).
 `,
 		Help:             `Send tokens`,
 		Process:          Send_Process,
@@ -46,6 +56,20 @@ func Send_AutoComplete(input string) (string, *[]ui.ACOption, string) {
 		last_param++
 	}
 
+	if bchain == "batch" {
+		if last_param == 3 {
+			for _, a := range w.Addresses {
+				if cmn.Contains(a.Name+a.Address.String(), from) {
+					options = append(options, ui.ACOption{
+						Name:   cmn.ShortAddress(a.Address) + " " + a.Name,
+						Result: command + " batch " + token + " " + a.Address.String() + " "})
+				}
+			}
+			return "from", &options, from
+		}
+		return "", nil, val
+	}
+
 	b := w.GetBlockchainByName(bchain)
 
 	var t *cmn.Token
@@ -61,6 +85,9 @@ func Send_AutoComplete(input string) (string, *[]ui.ACOption, string) {
 					Name: chain.Name, Result: command + " '" + chain.Name + "' "})
 			}
 		}
+		if cmn.Contains("batch", bchain) {
+			options = append(options, ui.ACOption{Name: "batch FILE", Result: command + " batch "})
+		}
 		return "blockchain", &options, bchain
 	case 2:
 		if b != nil {
@@ -121,6 +148,12 @@ func Send_Process(c *Command, input string) {
 
 	//parse command subcommand parameters
 	p := cmn.SplitN(input, 6)
+
+	if p[1] == "batch" {
+		sendBatch(p[2], p[3], p[4])
+		return
+	}
+
 	//execute command
 	bchain, token, from, to, amount := p[1], p[2], p[3], p[4], p[5]
 
@@ -152,13 +185,9 @@ func Send_Process(c *Command, input string) {
 		return
 	}
 
-	if !common.IsHexAddress(from) {
-		ui.PrintErrorf("Invalid address from: %s", from)
-		return
-	}
-
-	if !common.IsHexAddress(to) {
-		ui.PrintErrorf("Invalid address to: %s", to)
+	to_addr, err := parseAddress(w, to)
+	if err != nil {
+		ui.PrintErrorf("Invalid address to: %v", err)
 		return
 	}
 
@@ -173,8 +202,55 @@ func Send_Process(c *Command, input string) {
 		ChainId: b.ChainId,
 		Token:   t.Symbol,
 		From:    a_from.Address,
-		To:      common.HexToAddress(to),
+		To:      to_addr,
 		Amount:  amt,
 	})
 
 }
+
+func sendBatch(file, from, mode string) {
+	w := cmn.CurrentWallet
+
+	if file == "" || from == "" {
+		ui.PrintErrorf("Usage: send batch FILE FROM [sequential|disperse]")
+		return
+	}
+
+	if mode == "" {
+		mode = eth.BATCH_MODE_SEQUENTIAL
+	}
+
+	if mode != eth.BATCH_MODE_SEQUENTIAL && mode != eth.BATCH_MODE_DISPERSE {
+		ui.PrintErrorf("Invalid batch mode: %s", mode)
+		return
+	}
+
+	a_from := w.GetAddress(from)
+	if a_from == nil {
+		a_from = w.GetAddressByName(from)
+	}
+	if a_from == nil {
+		ui.PrintErrorf("Address not found: %s", from)
+		return
+	}
+
+	rows, errs := eth.LoadBatch(file)
+	if len(errs) == 0 {
+		_, errs = eth.BatchTotals(a_from.Address, rows)
+	}
+
+	if len(errs) > 0 {
+		for _, err := range errs {
+			ui.PrintErrorf("%v", err)
+		}
+		return
+	}
+
+	ui.Printf("Sending %d payments from %s\n", len(rows), a_from.Name)
+
+	bus.Send("eth", "send-batch", &bus.B_EthSendBatch{
+		File: file,
+		From: a_from.Address,
+		Mode: mode,
+	})
+}
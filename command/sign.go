@@ -0,0 +1,246 @@
+package command
+
+import (
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/AlexNa-Holdings/web3pro/bus"
+	"github.com/AlexNa-Holdings/web3pro/cmn"
+	"github.com/AlexNa-Holdings/web3pro/eth"
+	"github.com/AlexNa-Holdings/web3pro/ui"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+var sign_subcommands = []string{"message", "typed", "verify"}
+
+func NewSignCommand() *Command {
+	return &Command{
+		Command:      "sign",
+		ShortCommand: "",
+		Subcommands:  sign_subcommands,
+		Usage: `
+Usage: sign [COMMAND]
+
+Sign messages and verify signatures
+
+Commands:
+  message ADDRESS TEXT|FILE         - Sign the message (personal_sign)
+  typed ADDRESS FILE                - Sign the EIP-712 typed data (eth_signTypedData_v4)
+  verify SIG MESSAGE|JSON [ADDRESS] - Verify the signature
+
+Note: The text with spaces and the JSON are given in quotes, the file is
+read if it exists. Without ADDRESS the verification prints the recovered
+signer. The contract wallets (Safe) are verified with EIP-1271
+isValidSignature on the current blockchain, or the domain chain of the
+typed data.
+`,
+		Help:             `Sign messages and verify signatures`,
+		Process:          Sign_Process,
+		AutoCompleteFunc: Sign_AutoComplete,
+	}
+}
+
+func Sign_AutoComplete(input string) (string, *[]ui.ACOption, string) {
+	options := []ui.ACOption{}
+
+	if cmn.CurrentWallet == nil {
+		return "", &options, ""
+	}
+
+	w := cmn.CurrentWallet
+
+	p := cmn.SplitN(input, 3)
+	command, subcommand, param := p[0], p[1], p[2]
+
+	if !cmn.IsInArray(sign_subcommands, subcommand) {
+		for _, sc := range sign_subcommands {
+			if input == "" || strings.Contains(sc, subcommand) {
+				options = append(options, ui.ACOption{Name: sc, Result: command + " " + sc + " "})
+			}
+		}
+		return "action", &options, subcommand
+	}
+
+	if (subcommand == "message" || subcommand == "typed") && !strings.HasSuffix(input, "' ") {
+		for _, a := range w.Addresses {
+			if a.Signer != "" && cmn.Contains(a.Name+a.Address.String(), param) {
+				options = append(options, ui.ACOption{
+					Name:   cmn.ShortAddress(a.Address) + " " + a.Name,
+					Result: command + " " + subcommand + " '" + a.Name + "' "})
+			}
+		}
+		return "address", &options, param
+	}
+
+	return "", &options, ""
+}
+
+func Sign_Process(c *Command, input string) {
+	if cmn.CurrentWallet == nil {
+		ui.PrintErrorf("No wallet open")
+		return
+	}
+
+	w := cmn.CurrentWallet
+
+	b := w.GetBlockchain(w.CurrentChainId)
+	if b == nil {
+		ui.PrintErrorf("No current blockchain")
+		return
+	}
+
+	// the messages keep the punctuation
+	p := splitContractArgs(input)
+	for len(p) < 5 {
+		p = append(p, "")
+	}
+	subcommand := p[1]
+
+	switch subcommand {
+	case "message", "typed":
+		a := w.GetAddressByName(p[2])
+		if a == nil {
+			a = w.GetAddress(p[2])
+		}
+		if a == nil {
+			ui.PrintErrorf("Address not found: %s", p[2])
+			return
+		}
+
+		if p[3] == "" {
+			ui.PrintErrorf("Usage: sign message ADDRESS TEXT|FILE, sign typed ADDRESS FILE")
+			return
+		}
+
+		data := readArgOrFile(p[3])
+
+		var req any = &bus.B_EthSign{
+			Blockchain: b.Name,
+			Address:    a.Address,
+			Data:       data,
+		}
+		t := "sign"
+
+		if subcommand == "typed" {
+			td, err := eth.ParseTypedData(data)
+			if err != nil {
+				ui.PrintErrorf("Invalid typed data: %v", err)
+				return
+			}
+
+			req = &bus.B_EthSignTypedData_v4{
+				Blockchain: b.Name,
+				Address:    a.Address,
+				TypedData:  *td,
+			}
+			t = "sign-typed-data-v4"
+		}
+
+		// the hail waits for the user, the terminal is not blocked
+		go func() {
+			res := bus.Fetch("eth", t, req)
+			if res.Error != nil {
+				ui.PrintErrorf("Error signing: %v", res.Error)
+				ui.Flush()
+				return
+			}
+
+			sig, _ := res.Data.(string)
+			ui.Printf("\nSignature of ")
+			cmn.AddAddressShortLink(ui.Terminal.Screen, a.Address)
+			ui.Printf(" %s:\n", a.Name)
+			ui.Terminal.Screen.AddLink(sig, "copy "+sig, "Copy signature", "")
+			ui.Printf("\n")
+			ui.Flush()
+		}()
+	case "verify":
+		sig, err := hexutil.Decode(p[2])
+		if err != nil || p[3] == "" {
+			ui.PrintErrorf("Usage: sign verify SIG MESSAGE|JSON [ADDRESS]")
+			return
+		}
+
+		data := readArgOrFile(p[3])
+
+		hash := eth.MessageHash(data)
+		kind := "message"
+		if td, err := eth.ParseTypedData(data); err == nil {
+			hash, err = eth.TypedDataHash(td)
+			if err != nil {
+				ui.PrintErrorf("Invalid typed data: %v", err)
+				return
+			}
+			kind = "typed data"
+
+			if td.Domain.ChainId != nil {
+				if db := w.GetBlockchain(int((*big.Int)(td.Domain.ChainId).Int64())); db != nil {
+					b = db
+				}
+			}
+		}
+
+		recovered, rerr := eth.RecoverSigner(hash, sig)
+
+		if p[4] == "" {
+			if rerr != nil {
+				ui.PrintErrorf("Cannot recover the signer: %v", rerr)
+				return
+			}
+
+			ui.Printf("\nThe %s is signed by ", kind)
+			cmn.AddAddressLink(ui.Terminal.Screen, recovered)
+			if a := w.GetAddress(recovered); a != nil {
+				ui.Printf(" %s", a.Name)
+			}
+			ui.Printf("\n")
+			return
+		}
+
+		address, err := parseAddress(w, p[4])
+		if err != nil {
+			ui.PrintErrorf("Invalid address: %v", err)
+			return
+		}
+
+		if rerr == nil && recovered == address {
+			printVerified(kind, address, "ECDSA")
+			return
+		}
+
+		valid, err := eth.IsValidSignature(b, address, hash, sig)
+		if err == nil && valid {
+			printVerified(kind, address, "EIP-1271 on "+b.Name)
+			return
+		}
+
+		ui.PrintErrorf("Invalid signature of the %s by %s", kind, address.Hex())
+		if rerr == nil {
+			ui.Printf("Recovered signer: ")
+			cmn.AddAddressLink(ui.Terminal.Screen, recovered)
+			ui.Printf("\n")
+		}
+		if err != nil {
+			ui.Printf("EIP-1271 on %s: %v\n", b.Name, err)
+		}
+	default:
+		ui.PrintErrorf("Invalid subcommand: %s", subcommand)
+	}
+}
+
+func printVerified(kind string, address common.Address, method string) {
+	ui.Printf("\nValid signature of the %s by ", kind)
+	cmn.AddAddressLink(ui.Terminal.Screen, address)
+	ui.Printf(" (%s)\n", method)
+}
+
+// readArgOrFile returns the content of the file, or the argument itself
+func readArgOrFile(s string) []byte {
+	if info, err := os.Stat(s); err == nil && info.Mode().IsRegular() {
+		if data, err := os.ReadFile(s); err == nil {
+			return data
+		}
+	}
+	return []byte(s)
+}
@@ -1,19 +1,26 @@
 package command
 
 import (
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/AlexNa-Holdings/web3pro/bus"
 	"github.com/AlexNa-Holdings/web3pro/cmn"
 	"github.com/AlexNa-Holdings/web3pro/eth"
+	"github.com/AlexNa-Holdings/web3pro/sw/external"
+	"github.com/AlexNa-Holdings/web3pro/sw/privkey"
+	"github.com/AlexNa-Holdings/web3pro/sw/slip39"
 	"github.com/AlexNa-Holdings/web3pro/ui"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/rs/zerolog/log"
 )
 
-var signer_subcommands = []string{"list", "remove", "promote", "add", "edit", "addresses"}
+var signer_subcommands = []string{"list", "remove", "promote", "add", "edit", "addresses", "export-keystore", "unlock", "lock", "backup-shares", "restore-shares"}
 
 func NewSignerCommand() *Command {
 	return &Command{
@@ -27,11 +34,17 @@ Manage signers
 
 Commands:
   add [TYPE] [SERIAL]                    - Add new signer
+  add external [ENDPOINT]                - Add Clef compatible signer ('/path/clef.ipc' or URL)
   list                                   - List signers
   remove [SIGNER]                        - Remove signer
   edit [SIGNER]                          - Edit signer
   promote [SIGNER]                       - Promote signer to main signer
   addresses [SIGNER] [DERIVATION] [FROM] - List addresses
+  export-keystore [SIGNER] [FILE]        - Export privkey signer as keystore V3 file
+  unlock [SIGNER]                        - Enter BIP39 passphrase for this session
+  lock [SIGNER]                          - Forget session passphrase
+  backup-shares [SIGNER] [M] [N]         - Split mnemonics into M-of-N SLIP-39 shares
+  restore-shares [SIGNER]                - Restore or verify mnemonics from shares
 		`,
 		Help:             `Manage signers`,
 		Process:          Signer_Process,
@@ -84,6 +97,58 @@ func Signer_AutoComplete(input string) (string, *[]ui.ACOption, string) {
 		return "signer", &options, subcommand
 	}
 
+	if subcommand == "unlock" || subcommand == "lock" {
+		for _, s := range cmn.CurrentWallet.Signers {
+			if s.AskPassphrase && cmn.Contains(s.Name, param) {
+				options = append(options, ui.ACOption{
+					Name: s.Name, Result: command + " " + subcommand + " '" + s.Name + "'"})
+			}
+		}
+		return "signer", &options, subcommand
+	}
+
+	if subcommand == "backup-shares" {
+		for _, s := range cmn.CurrentWallet.Signers {
+			if s.Type == "mnemonics" && cmn.Contains(s.Name, param) {
+				options = append(options, ui.ACOption{
+					Name: s.Name, Result: command + " " + subcommand + " '" + s.Name + "' "})
+			}
+		}
+		return "signer", &options, subcommand
+	}
+
+	if subcommand == "restore-shares" {
+		names := []string{}
+		for _, s := range cmn.CurrentWallet.Signers {
+			if s.Type == "mnemonics" {
+				names = append(names, s.Name)
+			}
+		}
+		for _, a := range cmn.CurrentWallet.Addresses { // addresses of removed signers
+			if a.Signer != "" && cmn.CurrentWallet.GetSigner(a.Signer) == nil && !cmn.IsInArray(names, a.Signer) {
+				names = append(names, a.Signer)
+			}
+		}
+
+		for _, n := range names {
+			if cmn.Contains(n, param) {
+				options = append(options, ui.ACOption{
+					Name: n, Result: command + " " + subcommand + " '" + n + "'"})
+			}
+		}
+		return "signer", &options, subcommand
+	}
+
+	if subcommand == "export-keystore" {
+		for _, s := range cmn.CurrentWallet.Signers {
+			if s.Type == privkey.SIGNER_TYPE && cmn.Contains(s.Name, param) {
+				options = append(options, ui.ACOption{
+					Name: s.Name, Result: command + " " + subcommand + " '" + s.Name + "' "})
+			}
+		}
+		return "signer", &options, subcommand
+	}
+
 	if subcommand == "promote" {
 		if cmn.CurrentWallet != nil {
 			for _, s := range cmn.CurrentWallet.Signers {
@@ -164,7 +229,26 @@ func Signer_Process(c *Command, input string) {
 		for _, s := range cmn.CurrentWallet.Signers {
 			ui.Printf("%-13s %-9s ", s.Name, s.Type)
 
-			if s.Type == "mnemonics" {
+			if s.HasPassphrase() {
+				cmn.AddAddressShortLink(ui.Terminal.Screen, s.Fingerprint)
+				ui.Printf(" ")
+				if s.AskPassphrase {
+					if s.IsUnlocked() {
+						ui.Terminal.Screen.AddLink("unlocked", "command s lock '"+s.Name+"'", "Lock signer '"+s.Name+"'", "")
+					} else {
+						ui.Terminal.Screen.AddLink("locked", "command s unlock '"+s.Name+"'", "Unlock signer '"+s.Name+"'", "")
+					}
+					ui.Printf(" ")
+				}
+			}
+
+			if s.Type == external.SIGNER_TYPE {
+				ui.Printf("%s ", s.MasterKey)
+			}
+			if s.Type == privkey.SIGNER_TYPE {
+				ui.Terminal.Screen.AddLink(cmn.ICON_DOWNLOAD, "command s export-keystore '"+s.Name+"'", "Export keystore of '"+s.Name+"'", "")
+			}
+			if s.Type == "mnemonics" || s.Type == privkey.SIGNER_TYPE || s.Type == external.SIGNER_TYPE {
 				ui.Terminal.Screen.AddLink(cmn.ICON_EDIT, "command s edit '"+s.Name+"'", "Edit signer '"+s.Name+"'", "")
 			}
 			ui.Terminal.Screen.AddLink(cmn.ICON_DELETE, "command s remove '"+s.Name+"'", "Remove signer '"+s.Name+"'", "")
@@ -261,7 +345,7 @@ func Signer_Process(c *Command, input string) {
 
 	case "add":
 		// For hardware wallets, check if device is connected when no device name provided
-		if p1 != "mnemonics" && p2 == "" {
+		if p1 != "mnemonics" && p1 != privkey.SIGNER_TYPE && p1 != external.SIGNER_TYPE && p2 == "" {
 			r := bus.Fetch("signer", "list", &bus.B_SignerList{Type: p1})
 			if r.Error != nil {
 				ui.PrintErrorf("Error listing %s devices: %v", p1, r.Error)
@@ -367,6 +451,95 @@ func Signer_Process(c *Command, input string) {
 		}
 
 		ui.PrintErrorf("Signer not found: %s", p1)
+
+	case "unlock", "lock":
+		s := cmn.CurrentWallet.GetSigner(p1)
+		if s == nil {
+			ui.PrintErrorf("Signer not found: %s", p1)
+			return
+		}
+
+		if !s.AskPassphrase {
+			ui.PrintErrorf("Signer %s has no session passphrase", p1)
+			return
+		}
+
+		if subcommand == "lock" {
+			s.Lock()
+			ui.Printf("Signer %s locked\n", s.Name)
+			return
+		}
+
+		bus.Send("ui", "popup", ui.DlgSignerUnlock(s))
+
+	case "backup-shares":
+		s := cmn.CurrentWallet.GetSigner(p1)
+		if s == nil {
+			ui.PrintErrorf("Signer not found: %s", p1)
+			return
+		}
+
+		if s.Type != "mnemonics" {
+			ui.PrintErrorf("Only mnemonics signers can be split into shares")
+			return
+		}
+
+		m, _ := strconv.Atoi(p2)
+		n, _ := strconv.Atoi(p3)
+		if m < 1 || n < m {
+			ui.PrintErrorf("Usage: signer backup-shares [SIGNER] [M] [N], e.g. 2 3")
+			return
+		}
+
+		entropy, err := hex.DecodeString(s.MasterKey)
+		if err != nil {
+			ui.PrintErrorf("Error decoding signer: %v", err)
+			return
+		}
+
+		shares, err := slip39.Split(entropy, m, n, nil)
+		if err != nil {
+			ui.PrintErrorf("Error splitting: %v", err)
+			return
+		}
+
+		bus.Send("ui", "popup", ui.DlgSignerShares(s, m, shares))
+
+	case "restore-shares":
+		bus.Send("ui", "popup", ui.DlgSignerRestoreShares(p1))
+
+	case "export-keystore":
+		s := cmn.CurrentWallet.GetSigner(p1)
+		if s == nil {
+			ui.PrintErrorf("Signer not found: %s", p1)
+			return
+		}
+
+		if s.Type != privkey.SIGNER_TYPE {
+			ui.PrintErrorf("Only %s signers can be exported", privkey.SIGNER_TYPE)
+			return
+		}
+
+		file := p2
+		if file == "" {
+			pk, err := privkey.NewFromSN(s.MasterKey)
+			if err != nil {
+				ui.PrintErrorf("Error loading key: %v", err)
+				return
+			}
+
+			// same naming as geth
+			file = fmt.Sprintf("%s/keystore/UTC--%s--%x", cmn.DataFolder,
+				time.Now().UTC().Format("2006-01-02T15-04-05.000000000Z"), pk.Address().Bytes())
+		}
+
+		if _, err := os.Stat(file); err == nil {
+			ui.PrintErrorf("File already exists: %s", file)
+			return
+		}
+
+		bus.Send("ui", "popup", ui.DlgKeystoreExport(s, file))
+
 	default:
 		ui.PrintErrorf("Unknown command: %s", subcommand)
 	}
@@ -0,0 +1,386 @@
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/AlexNa-Holdings/web3pro/bus"
+	"github.com/AlexNa-Holdings/web3pro/cmn"
+	"github.com/AlexNa-Holdings/web3pro/eth"
+	"github.com/AlexNa-Holdings/web3pro/ui"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+var tx_subcommands = []string{"list", "show", "speedup", "cancel", "export-unsigned", "sign-file", "broadcast-file", "qr", "on", "off"}
+
+func NewTxCommand() *Command {
+	return &Command{
+		Command:      "tx",
+		ShortCommand: "",
+		Subcommands:  tx_subcommands,
+		Usage: `
+Usage: tx [COMMAND]
+
+Transaction history
+
+Commands:
+  list [N]             - List last N transactions (default 20)
+  show [HASH]          - Show transaction (hash or hash prefix)
+  speedup [HASH]       - Resend pending transaction with higher fees
+  cancel [HASH]        - Replace pending transaction with 0 transfer to self
+  export-unsigned FILE BLOCKCHAIN FROM TO [VALUE] [DATA]
+                       - Write the unsigned transaction to FILE
+  sign-file FILE [OUT] - Sign the transaction file (offline wallet)
+  broadcast-file FILE  - Send the signed transaction file
+  qr FILE              - Show the transaction file as animated QR
+  on                   - Open history pane
+  off                  - Close history pane
+
+Air-gapped signing: the online wallet fills the nonce, gas and fees
+(export-unsigned, FROM may be watch-only, VALUE is in the native token,
+DATA is the hex calldata), the offline wallet with the same signer and
+'config set offline true' signs the file (sign-file, written to OUT or
+back to FILE), the online wallet broadcasts it (broadcast-file).
+		`,
+		Help:             `Transaction history`,
+		Process:          Tx_Process,
+		AutoCompleteFunc: Tx_AutoComplete,
+	}
+}
+
+func Tx_AutoComplete(input string) (string, *[]ui.ACOption, string) {
+	options := []ui.ACOption{}
+
+	if cmn.CurrentWallet == nil {
+		return "", &options, ""
+	}
+
+	w := cmn.CurrentWallet
+
+	p := cmn.SplitN(input, 3)
+	command, subcommand, param := p[0], p[1], p[2]
+
+	if !cmn.IsInArray(tx_subcommands, subcommand) {
+		for _, sc := range tx_subcommands {
+			if input == "" || strings.Contains(sc, subcommand) {
+				options = append(options, ui.ACOption{Name: sc, Result: command + " " + sc + " "})
+			}
+		}
+		return "action", &options, subcommand
+	}
+
+	if subcommand == "show" || subcommand == "speedup" || subcommand == "cancel" {
+		for i := len(w.Transactions) - 1; i >= 0; i-- {
+			r := w.Transactions[i]
+			if subcommand != "show" && !r.IsPending() {
+				continue
+			}
+			if cmn.Contains(r.Hash.Hex(), param) {
+				options = append(options, ui.ACOption{
+					Name:   r.Hash.Hex()[:10] + " " + r.Status + " " + r.Method,
+					Result: command + " " + subcommand + " " + r.Hash.Hex()})
+			}
+		}
+		return "transaction", &options, param
+	}
+
+	return "", &options, ""
+}
+
+func Tx_Process(c *Command, input string) {
+	if cmn.CurrentWallet == nil {
+		ui.PrintErrorf("No wallet open")
+		return
+	}
+
+	w := cmn.CurrentWallet
+
+	//parse command subcommand parameters
+	tokens := cmn.SplitN(input, 3)
+	_, subcommand, p0 := tokens[0], tokens[1], tokens[2]
+
+	// the file paths keep the leading slash
+	args := splitContractArgs(input)
+	for len(args) < 8 {
+		args = append(args, "")
+	}
+
+	switch subcommand {
+	case "list", "":
+		n := 20
+		if p0 != "" {
+			var err error
+			n, err = strconv.Atoi(p0)
+			if err != nil || n <= 0 {
+				ui.PrintErrorf("Invalid number: %s", p0)
+				return
+			}
+		}
+
+		ui.Printf("\nTransactions:\n")
+		for i := len(w.Transactions) - 1; i >= 0 && i >= len(w.Transactions)-n; i-- {
+			r := w.Transactions[i]
+
+			chain := fmt.Sprintf("%d", r.ChainId)
+			if b := w.GetBlockchain(r.ChainId); b != nil {
+				chain = b.GetShortName()
+			}
+
+			method := r.Method
+			if method == "" {
+				method = "transfer"
+			}
+
+			ui.Printf("%s %-6s ", r.Time.Format("2006-01-02 15:04"), chain)
+			ui.Terminal.Screen.AddLink(r.Hash.Hex()[:10], "command tx show "+r.Hash.Hex(), "Show transaction", "")
+			ui.Printf(" %-9s %s\n", r.Status, method)
+		}
+	case "show":
+		r := w.GetTxRecord(p0)
+		if r == nil {
+			ui.PrintErrorf("Transaction not found: %s", p0)
+			return
+		}
+
+		printTxRecord(w, r)
+	case "speedup", "cancel":
+		r := w.GetTxRecord(p0)
+		if r == nil {
+			ui.PrintErrorf("Transaction not found: %s", p0)
+			return
+		}
+
+		if !r.IsPending() {
+			ui.PrintErrorf("Transaction is %s", r.Status)
+			return
+		}
+
+		bus.Send("eth", "replace-tx", &bus.B_EthReplaceTx{
+			Hash:   r.Hash,
+			Cancel: subcommand == "cancel",
+		})
+	case "export-unsigned":
+		exportUnsignedTx(w, args[2], args[3], args[4], args[5], args[6], args[7])
+	case "sign-file":
+		if args[2] == "" {
+			ui.PrintErrorf("Usage: tx sign-file FILE [OUT]")
+			return
+		}
+
+		bus.Send("eth", "sign-tx-file", &bus.B_EthSignTxFile{
+			File: args[2],
+			Out:  args[3],
+		})
+	case "broadcast-file":
+		o, err := eth.ReadOfflineTx(args[2])
+		if err != nil {
+			ui.PrintErrorf("Error reading transaction file: %v", err)
+			return
+		}
+
+		hash, err := eth.BroadcastOfflineTx(o)
+		if err != nil {
+			ui.PrintErrorf("Error sending transaction: %v", err)
+			return
+		}
+
+		ui.Printf("Transaction sent: ")
+		ui.Terminal.Screen.AddLink(hash, "command tx show "+hash, "Show transaction", "")
+		ui.Printf("\n")
+	case "qr":
+		o, err := eth.ReadOfflineTx(args[2])
+		if err != nil {
+			ui.PrintErrorf("Error reading transaction file: %v", err)
+			return
+		}
+
+		data, err := json.Marshal(o)
+		if err != nil {
+			ui.PrintErrorf("Error: %v", err)
+			return
+		}
+
+		title := "Unsigned Tx"
+		if len(o.Signed) > 0 {
+			title = "Signed Tx"
+		}
+		bus.Send("ui", "popup", ui.DlgQR(title, data))
+	case "on":
+		w.HistoryPaneOn = true
+		if err := w.Save(); err != nil {
+			ui.PrintErrorf("Error saving wallet: %v", err)
+			return
+		}
+		ui.Printf("History pane enabled\n")
+	case "off":
+		w.HistoryPaneOn = false
+		if err := w.Save(); err != nil {
+			ui.PrintErrorf("Error saving wallet: %v", err)
+			return
+		}
+		ui.Printf("History pane disabled\n")
+	default:
+		ui.PrintErrorf("Invalid subcommand: %s", subcommand)
+	}
+}
+
+func exportUnsignedTx(w *cmn.Wallet, file, chain, from_s, to_s, value_s, data_s string) {
+	if file == "" || to_s == "" {
+		ui.PrintErrorf("Usage: tx export-unsigned FILE BLOCKCHAIN FROM TO [VALUE] [DATA]")
+		return
+	}
+
+	b := w.GetBlockchainByName(chain)
+	if b == nil {
+		ui.PrintErrorf("Blockchain not found: %s", chain)
+		return
+	}
+
+	from := w.GetAddressByName(from_s)
+	if from == nil {
+		from = w.GetAddress(from_s)
+	}
+	if from == nil {
+		ui.PrintErrorf("Address not found: %s", from_s)
+		return
+	}
+
+	to, err := parseAddress(w, to_s)
+	if err != nil {
+		ui.PrintErrorf("Invalid address to: %v", err)
+		return
+	}
+
+	nt, err := w.GetNativeToken(b)
+	if err != nil {
+		ui.PrintErrorf("Error: %v", err)
+		return
+	}
+
+	value := big.NewInt(0)
+	if value_s != "" {
+		value, err = nt.Str2Wei(value_s)
+		if err != nil {
+			ui.PrintErrorf("Invalid value: %s", value_s)
+			return
+		}
+	}
+
+	var data []byte
+	if data_s != "" {
+		data, err = hexutil.Decode(data_s)
+		if err != nil {
+			ui.PrintErrorf("Invalid data: %v", err)
+			return
+		}
+	}
+
+	o, tx, err := eth.ExportUnsignedTx(b, from.Address, to, value, data)
+	if err != nil {
+		ui.PrintErrorf("Error building transaction: %v", err)
+		return
+	}
+
+	if err := o.Save(file); err != nil {
+		ui.PrintErrorf("Error writing %s: %v", file, err)
+		return
+	}
+
+	ui.Printf("\nUnsigned transaction written to %s\n", file)
+	ui.Printf("Nonce: %d Gas: %d Max fee: %s %s\n", tx.Nonce(), tx.Gas(),
+		nt.Value2Str(new(big.Int).Mul(tx.GasFeeCap(), new(big.Int).SetUint64(tx.Gas()))), nt.Symbol)
+	ui.Terminal.Screen.AddLink("QR", "command tx qr '"+file+"'", "Show the animated QR", "")
+	ui.Printf("\n")
+
+	qr, err := json.Marshal(o)
+	if err == nil {
+		bus.Send("ui", "popup", ui.DlgQR("Unsigned Tx", qr))
+	}
+}
+
+func printTxRecord(w *cmn.Wallet, r *cmn.TxRecord) {
+	b := w.GetBlockchain(r.ChainId)
+
+	ui.Printf("\nHash: ")
+	ui.Terminal.Screen.AddLink(r.Hash.Hex(), "copy "+r.Hash.Hex(), "Copy hash", "")
+	if b != nil && b.ExplorerUrl != "" {
+		ui.Printf(" ")
+		ui.Terminal.Screen.AddLink(cmn.ICON_LINK, "open "+b.ExplorerTxLink(r.Hash), "Open in Explorer", "")
+	}
+	ui.Printf("\n")
+
+	if b != nil {
+		ui.Printf("Chain: %s (%d)\n", b.Name, r.ChainId)
+	} else {
+		ui.Printf("Chain: %d\n", r.ChainId)
+	}
+
+	ui.Printf("Time: %s\n", r.Time.Format("2006-01-02 15:04:05"))
+	ui.Printf("Status: %s", r.Status)
+	if r.IsPending() {
+		ui.Printf(" ")
+		ui.Terminal.Screen.AddLink("speed up", "command tx speedup "+r.Hash.Hex(), "Resend with higher fees", "")
+		ui.Printf(" ")
+		ui.Terminal.Screen.AddLink("cancel", "command tx cancel "+r.Hash.Hex(), "Cancel transaction", "")
+	}
+	ui.Printf("\n")
+
+	ui.Printf("From: ")
+	cmn.AddAddressShortLink(ui.Terminal.Screen, r.From)
+	if a := w.GetAddress(r.From); a != nil {
+		ui.Printf(" %s", a.Name)
+	}
+	ui.Printf("\n")
+
+	ui.Printf("To: ")
+	cmn.AddAddressShortLink(ui.Terminal.Screen, r.To)
+	if a := w.GetAddress(r.To); a != nil {
+		ui.Printf(" %s", a.Name)
+	} else if c := w.GetContract(r.To); c != nil {
+		ui.Printf(" %s", c.Name)
+	}
+	ui.Printf("\n")
+
+	ui.Printf("Nonce: %d\n", r.Nonce)
+
+	var nt *cmn.Token
+	if b != nil {
+		nt, _ = w.GetNativeToken(b)
+	}
+
+	if nt != nil && r.Value != nil {
+		ui.Printf("Value: %s %s\n", nt.Value2Str(r.Value), nt.Symbol)
+	}
+
+	if r.Method != "" {
+		ui.Printf("Method: %s\n", r.Method)
+	}
+
+	if r.Origin != "" {
+		ui.Printf("Origin: %s\n", r.Origin)
+	}
+
+	if r.Replaces != (common.Hash{}) {
+		ui.Printf("Replaces: ")
+		ui.Terminal.Screen.AddLink(r.Replaces.Hex(), "command tx show "+r.Replaces.Hex(), "Show transaction", "")
+		ui.Printf("\n")
+	}
+
+	if r.ReplacedBy != (common.Hash{}) {
+		ui.Printf("Replaced by: ")
+		ui.Terminal.Screen.AddLink(r.ReplacedBy.Hex(), "command tx show "+r.ReplacedBy.Hex(), "Show transaction", "")
+		ui.Printf("\n")
+	}
+
+	if r.Block != 0 {
+		ui.Printf("Block: %d\n", r.Block)
+		ui.Printf("Gas used: %d\n", r.GasUsed)
+		if nt != nil && r.Fee != nil {
+			ui.Printf("Fee: %s %s\n", nt.Value2Str(r.Fee), nt.Symbol)
+		}
+	}
+}
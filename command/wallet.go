@@ -1,19 +1,15 @@
 package command
 
 import (
-	"fmt"
-	"io"
-	"os"
 	"path/filepath"
 	"strings"
-	"time"
 
 	"github.com/AlexNa-Holdings/web3pro/bus"
 	"github.com/AlexNa-Holdings/web3pro/cmn"
 	"github.com/AlexNa-Holdings/web3pro/ui"
 )
 
-var wallet_subcommands = []string{"backup", "close", "create", "list", "password", "restore", "open"}
+var wallet_subcommands = []string{"backup", "close", "create", "history", "list", "password", "restore", "rollback", "open"}
 
 func NewWalletCommand() *Command {
 	return &Command{
@@ -32,6 +28,8 @@ Commands:
   list             List wallets
   backup           Backup current wallet
   restore <backup> Restore wallet from backup
+  history          List backups of current wallet
+  rollback <backup> Roll current wallet back to a backup
   password         Change wallet password
 
 		`,
@@ -76,17 +74,23 @@ func Wallet_AutoComplete(input string) (string, *[]ui.ACOption, string) {
 		return "file", &options, param
 	}
 
-	if subcommand == "restore" {
+	if subcommand == "restore" || subcommand == "rollback" {
 
 		if param != "" && !strings.HasSuffix(param, " ") {
 			return "", nil, param
 		}
 
 		files := cmn.BackupList()
+		if subcommand == "rollback" {
+			if cmn.CurrentWallet == nil {
+				return "", &options, ""
+			}
+			files = cmn.WalletBackupList(filepath.Base(cmn.CurrentWallet.GetFilePath()))
+		}
 
 		for _, file := range files {
 			if param == "" || strings.Contains(file, param) {
-				options = append(options, ui.ACOption{Name: file, Result: command + " restore " + file + " "})
+				options = append(options, ui.ACOption{Name: file, Result: command + " " + subcommand + " " + file + " "})
 			}
 		}
 
@@ -115,6 +119,7 @@ func Wallet_Process(c *Command, input string) {
 	case "close":
 		if cmn.CurrentWallet != nil {
 			cmn.CurrentWallet = nil
+			cmn.ClearSessionPassphrases()
 			ui.Terminal.SetCommandPrefix(ui.DEFAULT_COMMAND_PREFIX)
 			ui.Notification.Show("Wallet closed")
 		} else {
@@ -142,42 +147,61 @@ func Wallet_Process(c *Command, input string) {
 			return
 		}
 
-		walletPath := cmn.CurrentWallet.GetFilePath()
-		walletName := filepath.Base(walletPath)
-		backupDir := cmn.DataFolder + "/wallets/backups"
+		backupPath, err := cmn.BackupWallet(cmn.CurrentWallet.GetFilePath(), false)
+		if err != nil {
+			ui.PrintErrorf("Backup failed: %s", err)
+			return
+		}
+
+		ui.Printf("Wallet backed up to: %s\n", backupPath)
 
-		// Create backup directory if it doesn't exist
-		if err := os.MkdirAll(backupDir, 0755); err != nil {
-			ui.PrintErrorf("Failed to create backup directory: %s", err)
+	case "history":
+		if cmn.CurrentWallet == nil {
+			ui.PrintErrorf("No wallet open")
 			return
 		}
 
-		// Generate backup filename with timestamp
-		timestamp := time.Now().Format("2006-01-02_15-04-05")
-		backupPath := fmt.Sprintf("%s/%s_%s", backupDir, walletName, timestamp)
+		walletName := filepath.Base(cmn.CurrentWallet.GetFilePath())
+		backups := cmn.WalletBackupList(walletName)
 
-		// Copy the wallet file
-		srcFile, err := os.Open(walletPath)
-		if err != nil {
-			ui.PrintErrorf("Failed to open wallet file: %s", err)
+		ui.Printf("\nBackups of %s:\n", walletName)
+
+		for _, b := range backups {
+			t, _ := cmn.BackupTime(b)
+			kind := "manual"
+			if cmn.IsAutoBackup(b) {
+				kind = "auto"
+			}
+
+			ui.Printf("%s %-6s ", t.Format("2006-01-02 15:04:05"), kind)
+			ui.Terminal.Screen.AddLink(b, "command w rollback "+b, "Roll back to "+b, "")
+			ui.Printf("\n")
+		}
+
+		if len(backups) == 0 {
+			ui.Printf("(no backups)\n")
+		}
+
+		ui.Printf("\n")
+
+	case "rollback":
+		if cmn.CurrentWallet == nil {
+			ui.PrintErrorf("No wallet open")
 			return
 		}
-		defer srcFile.Close()
 
-		dstFile, err := os.Create(backupPath)
-		if err != nil {
-			ui.PrintErrorf("Failed to create backup file: %s", err)
+		if len(tokens) != 3 || tokens[2] == "" {
+			ui.PrintErrorf("Please specify backup file name")
 			return
 		}
-		defer dstFile.Close()
 
-		_, err = io.Copy(dstFile, srcFile)
-		if err != nil {
-			ui.PrintErrorf("Failed to copy wallet file: %s", err)
+		backupFile := tokens[2]
+		if cmn.BackupWalletName(backupFile) != filepath.Base(cmn.CurrentWallet.GetFilePath()) {
+			ui.PrintErrorf("Backup %s does not belong to the current wallet", backupFile)
 			return
 		}
 
-		ui.Printf("Wallet backed up to: %s\n", backupPath)
+		bus.Send("ui", "popup", ui.DlgWalletRollback(backupFile))
 
 	case "restore":
 		if cmn.CurrentWallet != nil {
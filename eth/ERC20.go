@@ -165,7 +165,7 @@ func BuildTxERC20Transfer(b *cmn.Blockchain, t *cmn.Token, s *cmn.Signer, from *
 		return nil, err
 	}
 
-	nonce, err := client.PendingNonceAt(context.Background(), from.Address)
+	nonce, err := nextNonce(b, from.Address, false)
 	if err != nil {
 		log.Error().Err(err).Str("chain", b.GetShortName()).Msg("BuildTxERC20Transfer: Cannot get nonce")
 		return nil, err
@@ -184,44 +184,13 @@ func BuildTxERC20Transfer(b *cmn.Blockchain, t *cmn.Token, s *cmn.Signer, from *
 
 	log.Debug().Msgf("BuildTxERC20Transfer: Gas limit: %v", gasLimit)
 
-	// // Suggest gas price
-	// gasPrice, err := client.SuggestGasPrice(context.Background())
-	// if err != nil {
-	// 	log.Error().Msgf("BuildTxTransfer: Cannot suggest gas price. Error:(%v)", err)
-	// 	return nil, err
-	// }
-
-	priorityFee, err := client.SuggestGasTipCap(context.Background())
+	fee, err := GetFeePreset(b, "")
 	if err != nil {
-		log.Error().Err(err).Str("chain", b.GetShortName()).Msg("BuildTxERC20Transfer: Failed to suggest gas tip cap")
+		log.Error().Err(err).Str("chain", b.GetShortName()).Msg("BuildTxERC20Transfer: Failed to get the fee preset")
 		return nil, err
 	}
 
-	// Get the latest block to determine the base fee
-	block, err := client.BlockByNumber(context.Background(), nil) // Get the latest block
-	if err != nil {
-		log.Error().Err(err).Str("chain", b.GetShortName()).Msg("BuildTxERC20Transfer: Failed to get the latest block")
-		return nil, err
-	}
-
-	// Base fee is included in the block header (introduced in EIP-1559)
-	baseFee := block.BaseFee()
-	// Calculate the MaxFeePerGas based on base fee and priority fee
-	// For example, you might want to set MaxFeePerGas to be slightly higher than baseFee + priorityFee
-	maxFeePerGas := new(big.Int).Add(baseFee, priorityFee)
-	buffer := big.NewInt(2) // Set a buffer (optional) to ensure transaction gets processed
-	maxFeePerGas = maxFeePerGas.Mul(maxFeePerGas, buffer)
-
-	tx := types.NewTx(&types.DynamicFeeTx{
-		ChainID:   big.NewInt(int64(b.ChainId)),
-		Nonce:     nonce,
-		To:        &t.Address,
-		Value:     big.NewInt(0),
-		Gas:       gasLimit,
-		GasFeeCap: maxFeePerGas,
-		GasTipCap: priorityFee,
-		Data:      data,
-	})
+	tx := newTx(b, nonce, t.Address, big.NewInt(0), gasLimit, data, fee)
 
 	return tx, nil
 
@@ -432,28 +401,7 @@ func ERC20Transfer(msg *bus.Message, b *cmn.Blockchain, t *cmn.Token, s *cmn.Sig
 		return err
 	}
 
-	res := msg.Fetch("signer", "sign-tx", &bus.B_SignerSignTx{
-		Type:      s.Type,
-		Name:      s.Name,
-		MasterKey: s.MasterKey,
-		Chain:     b.Name,
-		Tx:        tx,
-		From:      from.Address,
-		Path:      from.Path,
-	})
-
-	if res.Error != nil {
-		log.Error().Err(res.Error).Msg("ERC20Transfer: Cannot sign tx")
-		return res.Error
-	}
-
-	signedTx, ok := res.Data.(*types.Transaction)
-	if !ok {
-		log.Error().Msgf("ERC20Transfer: Cannot convert to sig. Data:(%v)", res.Data)
-		return errors.New("cannot convert to transaction")
-	}
-
-	hash, err := SendSignedTx(signedTx)
+	hash, err := signAndSendTx(msg, b, s, from, tx)
 	if err != nil {
 		log.Error().Err(err).Msg("ERC20Transfer: Cannot send tx")
 		return err
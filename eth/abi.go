@@ -1,10 +1,16 @@
 package eth
 
 import (
+	"bytes"
 	_ "embed"
 	"encoding/json"
+	"os"
+	"path/filepath"
 
+	"github.com/AlexNa-Holdings/web3pro/cmn"
 	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/rs/zerolog/log"
 )
 
@@ -16,6 +22,42 @@ var ERC20_ABI abi.ABI
 var MULTICALL2_ABI_JSON []byte
 var MULTICALL2_ABI abi.ABI
 
+//go:embed ABI/Safe.json
+var SAFE_ABI_JSON []byte
+var SAFE_ABI abi.ABI
+
+//go:embed ABI/Permit2.json
+var PERMIT2_ABI_JSON []byte
+var PERMIT2_ABI abi.ABI
+
+//go:embed ABI/GasPriceOracle.json
+var GAS_PRICE_ORACLE_ABI_JSON []byte
+var GAS_PRICE_ORACLE_ABI abi.ABI
+
+//go:embed ABI/NodeInterface.json
+var NODE_INTERFACE_ABI_JSON []byte
+var NODE_INTERFACE_ABI abi.ABI
+
+//go:embed ABI/ERC721.json
+var ERC721_ABI_JSON []byte
+var ERC721_ABI abi.ABI
+
+//go:embed ABI/ERC1155.json
+var ERC1155_ABI_JSON []byte
+var ERC1155_ABI abi.ABI
+
+//go:embed ABI/ENS.json
+var ENS_ABI_JSON []byte
+var ENS_ABI abi.ABI
+
+//go:embed ABI/ERC1271.json
+var ERC1271_ABI_JSON []byte
+var ERC1271_ABI abi.ABI
+
+//go:embed ABI/selectors.json
+var SELECTORS_JSON []byte
+var SELECTOR_DB = map[string]*abi.Method{} // 4-byte selector -> method
+
 func LoadABIs() {
 	err := json.Unmarshal(ERC20_ABI_JSON, &ERC20_ABI)
 	if err != nil {
@@ -26,4 +68,95 @@ func LoadABIs() {
 	if err != nil {
 		log.Fatal().Msgf("Error unmarshaling MULTICALL2 ABI: %v\n", err)
 	}
+
+	err = json.Unmarshal(SAFE_ABI_JSON, &SAFE_ABI)
+	if err != nil {
+		log.Fatal().Msgf("Error unmarshaling SAFE ABI: %v\n", err)
+	}
+
+	err = json.Unmarshal(PERMIT2_ABI_JSON, &PERMIT2_ABI)
+	if err != nil {
+		log.Fatal().Msgf("Error unmarshaling PERMIT2 ABI: %v\n", err)
+	}
+
+	err = json.Unmarshal(GAS_PRICE_ORACLE_ABI_JSON, &GAS_PRICE_ORACLE_ABI)
+	if err != nil {
+		log.Fatal().Msgf("Error unmarshaling GAS PRICE ORACLE ABI: %v\n", err)
+	}
+
+	err = json.Unmarshal(NODE_INTERFACE_ABI_JSON, &NODE_INTERFACE_ABI)
+	if err != nil {
+		log.Fatal().Msgf("Error unmarshaling NODE INTERFACE ABI: %v\n", err)
+	}
+
+	err = json.Unmarshal(ERC721_ABI_JSON, &ERC721_ABI)
+	if err != nil {
+		log.Fatal().Msgf("Error unmarshaling ERC721 ABI: %v\n", err)
+	}
+
+	err = json.Unmarshal(ERC1155_ABI_JSON, &ERC1155_ABI)
+	if err != nil {
+		log.Fatal().Msgf("Error unmarshaling ERC1155 ABI: %v\n", err)
+	}
+
+	err = json.Unmarshal(ENS_ABI_JSON, &ENS_ABI)
+	if err != nil {
+		log.Fatal().Msgf("Error unmarshaling ENS ABI: %v\n", err)
+	}
+
+	err = json.Unmarshal(ERC1271_ABI_JSON, &ERC1271_ABI)
+	if err != nil {
+		log.Fatal().Msgf("Error unmarshaling ERC1271 ABI: %v\n", err)
+	}
+
+	signatures := []string{}
+	err = json.Unmarshal(SELECTORS_JSON, &signatures)
+	if err != nil {
+		log.Fatal().Msgf("Error unmarshaling selectors: %v\n", err)
+	}
+
+	for _, s := range signatures {
+		m, err := ParseMethodSignature(s)
+		if err != nil {
+			log.Fatal().Msgf("Error parsing selector %s: %v\n", s, err)
+		}
+		SELECTOR_DB[hexutil.Encode(m.ID)] = m
+	}
+}
+
+// LoadContractABI reads the ABI of a downloaded contract
+func LoadContractABI(a common.Address) (abi.ABI, error) {
+	abiJSON, err := os.ReadFile(cmn.DataFolder + "/contracts/" + a.String() + "/abi.json")
+	if err != nil {
+		return abi.ABI{}, err
+	}
+
+	return abi.JSON(bytes.NewReader(abiJSON))
+}
+
+// knownABIs returns the ABI of the contract first (if downloaded), then the
+// embedded ABIs. With all, the other downloaded contracts are added too.
+func knownABIs(to common.Address, all bool) []abi.ABI {
+	list := []abi.ABI{}
+
+	if a, err := LoadContractABI(to); err == nil {
+		list = append(list, a)
+	}
+
+	list = append(list, ERC20_ABI, ERC721_ABI, ERC1155_ABI, MULTICALL2_ABI, SAFE_ABI, PERMIT2_ABI)
+
+	if all {
+		files, _ := filepath.Glob(cmn.DataFolder + "/contracts/*/abi.json")
+		for _, f := range files {
+			address := common.HexToAddress(filepath.Base(filepath.Dir(f)))
+			if address == to {
+				continue
+			}
+			if a, err := LoadContractABI(address); err == nil {
+				list = append(list, a)
+			}
+		}
+	}
+
+	return list
 }
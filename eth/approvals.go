@@ -0,0 +1,336 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"slices"
+	"time"
+
+	"github.com/AlexNa-Holdings/web3pro/bus"
+	"github.com/AlexNa-Holdings/web3pro/cmn"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/rs/zerolog/log"
+)
+
+// Allowance inspector. The token/spender pairs come from the Approval events
+// of the owner (explorer API, eth_getLogs as fallback), the current values
+// are read with allowance() in one multicall.
+
+// Permit2 is deployed at the same address on all chains
+var PERMIT2_ADDRESS = common.HexToAddress("0x000000000022D473030F116dDEE9F6B43aC78BA3")
+
+var (
+	TOPIC_PERMIT2_APPROVAL = crypto.Keccak256Hash([]byte("Approval(address,address,address,uint160,uint48)"))
+	TOPIC_PERMIT2_PERMIT   = crypto.Keccak256Hash([]byte("Permit(address,address,address,uint160,uint48,uint48)"))
+)
+
+// blocks scanned with eth_getLogs if the node refuses the full range
+const APPROVAL_SCAN_BLOCKS = 200_000
+const APPROVAL_SCAN_CHUNK = 10_000
+
+type TokenAllowance struct {
+	Owner      common.Address
+	Token      common.Address
+	Spender    common.Address
+	Permit2    bool // allowance kept by Permit2
+	Amount     *big.Int
+	Expiration uint64   // Permit2 only, unix time
+	Balance    *big.Int // balance of the owner, nil if unknown
+}
+
+// BlockRange is the part of the chain scanned when the full range is refused
+type BlockRange struct {
+	From uint64
+	To   uint64
+}
+
+type allowanceKey struct {
+	token   common.Address
+	spender common.Address
+	permit2 bool
+}
+
+// GetAllowances returns the non zero allowances granted by the owner. The
+// range is set if only the recent blocks were scanned, the older approvals
+// are missing then.
+func GetAllowances(b *cmn.Blockchain, owner common.Address) ([]*TokenAllowance, *BlockRange, error) {
+	list, scanned, err := findAllowances(b, owner)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(list) == 0 {
+		return list, scanned, nil
+	}
+
+	readAllowances(b, list)
+
+	now := uint64(time.Now().Unix())
+	list = slices.DeleteFunc(list, func(a *TokenAllowance) bool {
+		return a.Amount == nil || a.Amount.Sign() == 0 || (a.Permit2 && a.Expiration < now)
+	})
+
+	slices.SortFunc(list, func(x, y *TokenAllowance) int {
+		if c := x.Token.Cmp(y.Token); c != 0 {
+			return c
+		}
+		if c := x.Spender.Cmp(y.Spender); c != 0 {
+			return c
+		}
+		if x.Permit2 == y.Permit2 {
+			return 0
+		}
+		if x.Permit2 {
+			return 1
+		}
+		return -1
+	})
+
+	return list, scanned, nil
+}
+
+// BuildRevokeCall returns the contract and the calldata setting the
+// allowance to 0
+func BuildRevokeCall(a *TokenAllowance) (common.Address, []byte, error) {
+	if a.Permit2 {
+		data, err := PERMIT2_ABI.Pack("approve", a.Token, a.Spender, big.NewInt(0), big.NewInt(0))
+		return PERMIT2_ADDRESS, data, err
+	}
+
+	data, err := ERC20_ABI.Pack("approve", a.Spender, big.NewInt(0))
+	return a.Token, data, err
+}
+
+// findAllowances collects the token/spender pairs the owner has approved,
+// within the returned range if the scan is partial
+func findAllowances(b *cmn.Blockchain, owner common.Address) ([]*TokenAllowance, *BlockRange, error) {
+	owner_topic := common.BytesToHash(owner.Bytes())
+
+	logs, scanned, err := getLogs(b, nil, []common.Hash{TOPIC_APPROVAL, owner_topic})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, topic := range []common.Hash{TOPIC_PERMIT2_APPROVAL, TOPIC_PERMIT2_PERMIT} {
+		p2, r, err := getLogs(b, &PERMIT2_ADDRESS, []common.Hash{topic, owner_topic})
+		if err != nil {
+			log.Debug().Err(err).Msg("findAllowances: cannot get Permit2 logs")
+			continue
+		}
+		if r != nil && (scanned == nil || r.From > scanned.From) {
+			scanned = r
+		}
+		logs = append(logs, p2...)
+	}
+
+	keys := map[allowanceKey]bool{}
+	list := []*TokenAllowance{}
+
+	for _, l := range logs {
+		// ERC-721 Approval has the token id indexed
+		if len(l.Topics) < 3 || l.Topics[1] != owner_topic {
+			continue
+		}
+
+		k := allowanceKey{}
+		switch {
+		case l.Address == PERMIT2_ADDRESS && len(l.Topics) == 4:
+			k = allowanceKey{
+				token:   common.BytesToAddress(l.Topics[2].Bytes()),
+				spender: common.BytesToAddress(l.Topics[3].Bytes()),
+				permit2: true,
+			}
+		case l.Topics[0] == TOPIC_APPROVAL && len(l.Topics) == 3 && len(l.Data) == 32:
+			k = allowanceKey{
+				token:   l.Address,
+				spender: common.BytesToAddress(l.Topics[2].Bytes()),
+			}
+		default:
+			continue
+		}
+
+		if keys[k] {
+			continue
+		}
+		keys[k] = true
+
+		list = append(list, &TokenAllowance{
+			Owner:   owner,
+			Token:   k.token,
+			Spender: k.spender,
+			Permit2: k.permit2,
+		})
+	}
+
+	return list, scanned, nil
+}
+
+// getLogs asks the explorer first, the nodes usually limit the block range.
+// If only the last APPROVAL_SCAN_BLOCKS could be scanned, their range is
+// returned.
+func getLogs(b *cmn.Blockchain, address *common.Address, topics []common.Hash) ([]types.Log, *BlockRange, error) {
+	if cmn.Config.Offline {
+		return nil, nil, ErrOffline
+	}
+
+	resp := bus.Fetch("explorer", "get-logs", &bus.B_ExplorerGetLogs{
+		ChainId: b.ChainId,
+		Address: address,
+		Topics:  topics,
+	})
+
+	if resp.Error == nil {
+		if logs, ok := resp.Data.([]types.Log); ok {
+			return logs, nil, nil
+		}
+	}
+	log.Debug().Err(resp.Error).Msg("getLogs: explorer failed, using eth_getLogs")
+
+	client, err := getEthClient(b)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	q := ethereum.FilterQuery{Topics: [][]common.Hash{}}
+	if address != nil {
+		q.Addresses = []common.Address{*address}
+	}
+	for _, t := range topics {
+		if t == (common.Hash{}) {
+			q.Topics = append(q.Topics, nil)
+		} else {
+			q.Topics = append(q.Topics, []common.Hash{t})
+		}
+	}
+
+	acquireRateLimit(b.ChainId)
+	q.FromBlock = big.NewInt(0)
+	logs, err := client.FilterLogs(context.Background(), q)
+	handleRPCResult(b.ChainId, err)
+	if err == nil {
+		return logs, nil, nil
+	}
+	log.Debug().Err(err).Msgf("getLogs: full range refused, scanning the last %d blocks", APPROVAL_SCAN_BLOCKS)
+
+	acquireRateLimit(b.ChainId)
+	head, err := client.BlockNumber(context.Background())
+	handleRPCResult(b.ChainId, err)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	scanned := &BlockRange{To: head}
+	logs = []types.Log{}
+	for to := head; to+APPROVAL_SCAN_BLOCKS > head; to -= APPROVAL_SCAN_CHUNK {
+		from := uint64(0)
+		if to >= APPROVAL_SCAN_CHUNK {
+			from = to - APPROVAL_SCAN_CHUNK + 1
+		}
+
+		q.FromBlock = new(big.Int).SetUint64(from)
+		q.ToBlock = new(big.Int).SetUint64(to)
+
+		acquireRateLimit(b.ChainId)
+		chunk, err := client.FilterLogs(context.Background(), q)
+		handleRPCResult(b.ChainId, err)
+		if err != nil {
+			return nil, nil, err
+		}
+		logs = append(logs, chunk...)
+		scanned.From = from
+
+		if from == 0 {
+			return logs, nil, nil // the whole chain is scanned
+		}
+	}
+
+	return logs, scanned, nil
+}
+
+// readAllowances reads the current allowances and the owner balances
+func readAllowances(b *cmn.Blockchain, list []*TokenAllowance) {
+	calls := []bus.B_EthMultiCall_Call{}
+
+	for _, a := range list {
+		if a.Permit2 {
+			data, _ := PERMIT2_ABI.Pack("allowance", a.Owner, a.Token, a.Spender)
+			calls = append(calls, bus.B_EthMultiCall_Call{To: PERMIT2_ADDRESS, Data: data})
+		} else {
+			data, _ := ERC20_ABI.Pack("allowance", a.Owner, a.Spender)
+			calls = append(calls, bus.B_EthMultiCall_Call{To: a.Token, Data: data})
+		}
+
+		data, _ := ERC20_ABI.Pack("balanceOf", a.Owner)
+		calls = append(calls, bus.B_EthMultiCall_Call{To: a.Token, Data: data})
+	}
+
+	results, err := multiCallResults(b, calls)
+	if err != nil {
+		log.Debug().Err(err).Str("chain", b.GetShortName()).Msg("readAllowances: multicall failed, falling back to individual calls")
+		results = individualCallResults(b, calls)
+	}
+
+	for i, a := range list {
+		if r := results[2*i]; len(r) > 0 {
+			if a.Permit2 {
+				if values, err := PERMIT2_ABI.Unpack("allowance", r); err == nil && len(values) == 3 {
+					a.Amount, _ = values[0].(*big.Int)
+					if e, ok := values[1].(*big.Int); ok {
+						a.Expiration = e.Uint64()
+					}
+				}
+			} else if values, err := ERC20_ABI.Unpack("allowance", r); err == nil && len(values) == 1 {
+				a.Amount, _ = values[0].(*big.Int)
+			}
+		}
+
+		if r := results[2*i+1]; len(r) > 0 {
+			if values, err := ERC20_ABI.Unpack("balanceOf", r); err == nil && len(values) == 1 {
+				a.Balance, _ = values[0].(*big.Int)
+			}
+		}
+	}
+}
+
+func multiCallResults(b *cmn.Blockchain, calls []bus.B_EthMultiCall_Call) ([][]byte, error) {
+	resp := bus.Fetch("eth", "multi-call", &bus.B_EthMultiCall{
+		ChainId: b.ChainId,
+		Calls:   calls,
+	})
+	if resp.Error != nil {
+		return nil, resp.Error
+	}
+
+	results, ok := resp.Data.([][]byte)
+	if !ok || len(results) != len(calls) {
+		return nil, errors.New("invalid multicall result")
+	}
+	return results, nil
+}
+
+// individualCallResults leaves the failed results empty
+func individualCallResults(b *cmn.Blockchain, calls []bus.B_EthMultiCall_Call) [][]byte {
+	results := make([][]byte, len(calls))
+
+	client, err := getEthClient(b)
+	if err != nil {
+		return results
+	}
+
+	for i, c := range calls {
+		acquireRateLimit(b.ChainId)
+		out, err := client.CallContract(context.Background(), ethereum.CallMsg{To: &c.To, Data: c.Data}, nil)
+		handleRPCResult(b.ChainId, err)
+		if err != nil {
+			log.Debug().Err(err).Msgf("individualCallResults: call to %s failed", c.To.Hex())
+			continue
+		}
+		results[i] = out
+	}
+
+	return results
+}
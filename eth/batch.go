@@ -0,0 +1,586 @@
+package eth
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/AlexNa-Holdings/web3pro/bus"
+	"github.com/AlexNa-Holdings/web3pro/cmn"
+	"github.com/AlexNa-Holdings/web3pro/gocui"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Batch payouts. Every CSV row (chain, token, to, amount) is either sent as
+// its own transaction in nonce order, or the rows of the same chain and
+// token are paid with one call of the Disperse contract.
+
+const (
+	BATCH_MODE_SEQUENTIAL = "sequential"
+	BATCH_MODE_DISPERSE   = "disperse"
+)
+
+const (
+	BATCH_STATUS_SENT    = "sent"
+	BATCH_STATUS_FAILED  = "failed"
+	BATCH_STATUS_SKIPPED = "skipped"
+)
+
+// Disperse (disperse.app) is deployed at the same address on most chains
+var DISPERSE_ADDRESS = common.HexToAddress("0xD152f549545093347A162Dce210e7293f1452150")
+
+// how long the approval of the Disperse contract is waited for
+const BATCH_APPROVE_TIMEOUT = 5 * time.Minute
+
+// rows shown in the confirmation hail
+const BATCH_HAIL_ROWS = 20
+
+type BatchRow struct {
+	Line   int
+	Fields []string
+	Chain  *cmn.Blockchain
+	Token  *cmn.Token
+	To     common.Address
+	Amount *big.Int
+	Status string
+	Hash   string
+	Error  string
+}
+
+// BatchTotal is the sum paid in one token
+type BatchTotal struct {
+	Chain   *cmn.Blockchain
+	Token   *cmn.Token
+	Rows    []*BatchRow
+	Amount  *big.Int
+	Balance *big.Int
+}
+
+// LoadBatch reads the payout CSV. The header line is optional. All the
+// invalid rows are reported.
+func LoadBatch(file string) ([]*BatchRow, []error) {
+	w := cmn.CurrentWallet
+	if w == nil {
+		return nil, []error{errors.New("no wallet")}
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, []error{err}
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	r.Comment = '#'
+
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, []error{err}
+	}
+
+	rows := []*BatchRow{}
+	errs := []error{}
+
+	for i, rec := range records {
+		line := i + 1
+		if i == 0 && len(rec) > 0 && strings.EqualFold(strings.TrimSpace(rec[0]), "chain") {
+			continue // header
+		}
+
+		if len(rec) < 4 {
+			errs = append(errs, fmt.Errorf("line %d: expected chain, token, to, amount", line))
+			continue
+		}
+
+		for j := range rec {
+			rec[j] = strings.TrimSpace(rec[j])
+		}
+
+		row := &BatchRow{Line: line, Fields: rec[:4]}
+
+		row.Chain = w.GetBlockchainByName(rec[0])
+		if row.Chain == nil {
+			errs = append(errs, fmt.Errorf("line %d: blockchain not found: %s", line, rec[0]))
+			continue
+		}
+
+		row.Token = w.GetToken(row.Chain.ChainId, rec[1])
+		if row.Token == nil {
+			errs = append(errs, fmt.Errorf("line %d: token not found: %s", line, rec[1]))
+			continue
+		}
+
+		if common.IsHexAddress(rec[2]) {
+			row.To = common.HexToAddress(rec[2])
+		} else if a := w.GetAddressByName(rec[2]); a != nil {
+			row.To = a.Address
+		} else {
+			errs = append(errs, fmt.Errorf("line %d: invalid address to: %s", line, rec[2]))
+			continue
+		}
+
+		row.Amount, err = row.Token.Str2Wei(rec[3])
+		if err != nil || row.Amount.Sign() <= 0 {
+			errs = append(errs, fmt.Errorf("line %d: invalid amount: %s", line, rec[3]))
+			continue
+		}
+
+		rows = append(rows, row)
+	}
+
+	if len(rows) == 0 && len(errs) == 0 {
+		errs = append(errs, errors.New("no payments in the file"))
+	}
+
+	return rows, errs
+}
+
+// BatchTotals sums the rows per token and checks the balances of from
+func BatchTotals(from common.Address, rows []*BatchRow) ([]*BatchTotal, []error) {
+	totals := []*BatchTotal{}
+	errs := []error{}
+
+	for _, r := range rows {
+		var total *BatchTotal
+		for _, t := range totals {
+			if t.Token == r.Token {
+				total = t
+				break
+			}
+		}
+
+		if total == nil {
+			total = &BatchTotal{Chain: r.Chain, Token: r.Token, Amount: big.NewInt(0)}
+			totals = append(totals, total)
+		}
+
+		total.Rows = append(total.Rows, r)
+		total.Amount.Add(total.Amount, r.Amount)
+	}
+
+	for _, t := range totals {
+		balance, err := BalanceOf(t.Chain, t.Token, from)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s %s: cannot get balance: %v", t.Chain.Name, t.Token.Symbol, err))
+			continue
+		}
+		t.Balance = balance
+
+		if balance.Cmp(t.Amount) < 0 {
+			errs = append(errs, fmt.Errorf("%s %s: insufficient balance %s, need %s",
+				t.Chain.Name, t.Token.Symbol, t.Token.Value2Str(balance), t.Token.Value2Str(t.Amount)))
+		}
+	}
+
+	return totals, errs
+}
+
+func sendBatch(msg *bus.Message) error {
+	req, ok := msg.Data.(*bus.B_EthSendBatch)
+	if !ok {
+		return bus.ErrInvalidMessageData
+	}
+
+	w := cmn.CurrentWallet
+	if w == nil {
+		return errors.New("no wallet")
+	}
+
+	if req.Mode != BATCH_MODE_SEQUENTIAL && req.Mode != BATCH_MODE_DISPERSE {
+		return fmt.Errorf("invalid batch mode: %s", req.Mode)
+	}
+
+	from := w.GetAddress(req.From.String())
+	if from == nil {
+		return fmt.Errorf("address from not found: %v", req.From)
+	}
+
+	if from.Safe != nil || from.Signer == "" {
+		return fmt.Errorf("batch can be sent from a signer address only")
+	}
+
+	s := w.GetSigner(from.Signer)
+	if s == nil {
+		return fmt.Errorf("signer not found: %s", from.Signer)
+	}
+
+	rows, errs := LoadBatch(req.File)
+	if len(errs) > 0 {
+		return errs[0]
+	}
+
+	totals, errs := BatchTotals(from.Address, rows)
+	if len(errs) > 0 {
+		return errs[0]
+	}
+
+	requests, err := batchPolicyRequests(from, req.Mode, rows, totals)
+	if err != nil {
+		return err
+	}
+
+	if err := enforcePolicies(msg, w.CheckPolicies(requests...)); err != nil {
+		return err
+	}
+
+	confirmed := false
+	msg.Fetch("ui", "hail", &bus.B_Hail{
+		Title:    "Batch Payout",
+		Template: buildBatchTemplate(from, s, req.Mode, rows, totals),
+		OnOk: func(m *bus.Message, v *gocui.View) bool {
+			confirmed = true
+			return true
+		},
+		OnOverHotspot: func(m *bus.Message, v *gocui.View, hs *gocui.Hotspot) {
+			cmn.StandardOnOverHotspot(v, hs)
+		},
+		OnClickHotspot: func(m *bus.Message, v *gocui.View, hs *gocui.Hotspot) {
+			cmn.StandardOnClickHotspot(v, hs)
+		},
+	})
+
+	if !confirmed {
+		return nil
+	}
+
+	// signing every transaction and waiting for the approvals takes minutes,
+	// each step opens its own hail
+	go executeBatch(req.File, req.Mode, s, from, rows, totals)
+	return nil
+}
+
+// executeBatch sends the confirmed batch and writes the result file
+func executeBatch(file string, mode string, s *cmn.Signer, from *cmn.Address, rows []*BatchRow, totals []*BatchTotal) {
+	bus.Send("ui", "notify", fmt.Sprintf("Batch: sending %d payments, sign every transaction", len(rows)))
+
+	if mode == BATCH_MODE_DISPERSE {
+		for _, t := range totals {
+			disperse(s, from, t)
+		}
+	} else {
+		sendSequential(s, from, rows)
+	}
+
+	sent := 0
+	for _, r := range rows {
+		if r.Status == BATCH_STATUS_SENT {
+			recordPolicySpend(batchPolicyRequest(from, r))
+			sent++
+		}
+	}
+
+	result, err := writeBatchResult(file, rows)
+	if err != nil {
+		log.Error().Err(err).Msg("executeBatch: cannot write result")
+		bus.Send("ui", "notify-error", fmt.Sprintf("Cannot write batch result: %v", err))
+	}
+
+	if sent < len(rows) {
+		bus.Send("ui", "notify-error", fmt.Sprintf("Batch: %d of %d payments sent, see %s", sent, len(rows), result))
+	} else {
+		bus.Send("ui", "notify", fmt.Sprintf("Batch: all %d payments sent, see %s", sent, result))
+	}
+}
+
+func batchPolicyRequest(from *cmn.Address, r *BatchRow) *cmn.PolicyRequest {
+	pr := &cmn.PolicyRequest{ChainId: r.Chain.ChainId, From: from.Address, To: r.To, Value: r.Amount}
+	if !r.Token.Native {
+		data, _ := ERC20_ABI.Pack("transfer", r.To, r.Amount)
+		pr.To, pr.Value, pr.Data = r.Token.Address, big.NewInt(0), data
+	}
+	return pr
+}
+
+// batchPolicyRequests returns the transactions the batch sends: a transfer
+// per row, or the approval and the Disperse call per token. The payments
+// of a Disperse call are its nested calls.
+func batchPolicyRequests(from *cmn.Address, mode string, rows []*BatchRow, totals []*BatchTotal) ([]*cmn.PolicyRequest, error) {
+	res := []*cmn.PolicyRequest{}
+
+	if mode != BATCH_MODE_DISPERSE {
+		for _, r := range rows {
+			res = append(res, batchPolicyRequest(from, r))
+		}
+		return res, nil
+	}
+
+	for _, t := range totals {
+		data, err := disperseData(t)
+		if err != nil {
+			return nil, err
+		}
+
+		pr := &cmn.PolicyRequest{ChainId: t.Chain.ChainId, From: from.Address, To: DISPERSE_ADDRESS, Data: data}
+		if t.Token.Native {
+			pr.Value = t.Amount
+			for _, r := range t.Rows {
+				pr.Nested = append(pr.Nested, cmn.PolicyCall{To: r.To, Value: r.Amount})
+			}
+		} else {
+			res = append(res, &cmn.PolicyRequest{ChainId: t.Chain.ChainId, From: from.Address,
+				To: t.Token.Address, Data: packERC20("approve", DISPERSE_ADDRESS, t.Amount)})
+
+			pr.Value = big.NewInt(0)
+			for _, r := range t.Rows {
+				pr.Nested = append(pr.Nested, cmn.PolicyCall{To: t.Token.Address,
+					Data: packERC20("transferFrom", from.Address, r.To, r.Amount)})
+			}
+		}
+		res = append(res, pr)
+	}
+
+	return res, nil
+}
+
+// disperseData packs the Disperse call paying all the rows of the token
+func disperseData(t *BatchTotal) ([]byte, error) {
+	recipients := []common.Address{}
+	values := []*big.Int{}
+	for _, r := range t.Rows {
+		recipients = append(recipients, r.To)
+		values = append(values, r.Amount)
+	}
+
+	if t.Token.Native {
+		return packSelectorCall("disperseEther(address[],uint256[])", recipients, values)
+	}
+	return packSelectorCall("disperseToken(address,address[],uint256[])", t.Token.Address, recipients, values)
+}
+
+// sendSequential sends one transaction per row. A row that cannot be built
+// is skipped, a signing or broadcast failure stops the batch.
+func sendSequential(s *cmn.Signer, from *cmn.Address, rows []*BatchRow) {
+	stopped := false
+
+	for _, r := range rows {
+		if stopped {
+			r.Status = BATCH_STATUS_SKIPPED
+			continue
+		}
+
+		var tx *types.Transaction
+		var err error
+		if r.Token.Native {
+			tx, err = BuildTxTransfer(r.Chain, s, from, r.To, r.Amount)
+		} else {
+			tx, err = BuildTxERC20Transfer(r.Chain, r.Token, s, from, r.To, r.Amount)
+		}
+		if err != nil {
+			r.Status, r.Error = BATCH_STATUS_FAILED, err.Error()
+			continue
+		}
+
+		r.Hash, err = signAndSendTx(nil, r.Chain, s, from, tx)
+		if err != nil {
+			r.Status, r.Error = BATCH_STATUS_FAILED, err.Error()
+			stopped = true
+			continue
+		}
+
+		r.Status = BATCH_STATUS_SENT
+	}
+}
+
+// disperse pays all the rows of the token with one call, the contract is
+// approved first if needed
+func disperse(s *cmn.Signer, from *cmn.Address, t *BatchTotal) {
+	hash, err := disperseTotal(s, from, t)
+	for _, r := range t.Rows {
+		if err != nil {
+			r.Status, r.Error = BATCH_STATUS_FAILED, err.Error()
+		} else {
+			r.Status, r.Hash = BATCH_STATUS_SENT, hash
+		}
+	}
+}
+
+func disperseTotal(s *cmn.Signer, from *cmn.Address, t *BatchTotal) (string, error) {
+	b := t.Chain
+
+	client, err := getEthClient(b)
+	if err != nil {
+		return "", err
+	}
+
+	code, err := client.CodeAt(context.Background(), DISPERSE_ADDRESS, nil)
+	if err != nil {
+		return "", err
+	}
+	if len(code) == 0 {
+		return "", fmt.Errorf("disperse contract is not deployed on %s", b.Name)
+	}
+
+	data, err := disperseData(t)
+	if err != nil {
+		return "", err
+	}
+
+	if t.Token.Native {
+		tx, err := BuildTx(b, s, from, DISPERSE_ADDRESS, t.Amount, data)
+		if err != nil {
+			return "", err
+		}
+		return signAndSendTx(nil, b, s, from, tx)
+	}
+
+	out, err := client.CallContract(context.Background(), ethereum.CallMsg{
+		To:   &t.Token.Address,
+		Data: packERC20("allowance", from.Address, DISPERSE_ADDRESS),
+	}, nil)
+	if err != nil {
+		return "", err
+	}
+
+	if new(big.Int).SetBytes(out).Cmp(t.Amount) < 0 {
+		tx, err := BuildTx(b, s, from, t.Token.Address, big.NewInt(0), packERC20("approve", DISPERSE_ADDRESS, t.Amount))
+		if err != nil {
+			return "", err
+		}
+
+		hash, err := signAndSendTx(nil, b, s, from, tx)
+		if err != nil {
+			return "", err
+		}
+
+		// the disperse gas can't be estimated before the approval is mined
+		if err := waitMined(b, common.HexToHash(hash), BATCH_APPROVE_TIMEOUT); err != nil {
+			return "", fmt.Errorf("approval %s: %v", hash, err)
+		}
+	}
+
+	tx, err := BuildTx(b, s, from, DISPERSE_ADDRESS, big.NewInt(0), data)
+	if err != nil {
+		return "", err
+	}
+	return signAndSendTx(nil, b, s, from, tx)
+}
+
+func packERC20(method string, args ...interface{}) []byte {
+	data, err := ERC20_ABI.Pack(method, args...)
+	if err != nil {
+		log.Error().Err(err).Msgf("packERC20: cannot pack %s", method)
+	}
+	return data
+}
+
+// packSelectorCall packs the call of a method of the 4-byte database
+func packSelectorCall(signature string, args ...interface{}) ([]byte, error) {
+	m, err := ParseMethodSignature(signature)
+	if err != nil {
+		return nil, err
+	}
+
+	params, err := m.Inputs.Pack(args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(m.ID, params...), nil
+}
+
+func waitMined(b *cmn.Blockchain, hash common.Hash, timeout time.Duration) error {
+	client, err := getEthClient(b)
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		receipt, err := client.TransactionReceipt(context.Background(), hash)
+		if err == nil {
+			if receipt.Status != types.ReceiptStatusSuccessful {
+				return errors.New("reverted")
+			}
+			return nil
+		}
+
+		if !errors.Is(err, ethereum.NotFound) {
+			return err
+		}
+
+		time.Sleep(3 * time.Second)
+	}
+
+	return errors.New("not mined in time")
+}
+
+// writeBatchResult writes the rows with their status next to the source file
+func writeBatchResult(file string, rows []*BatchRow) (string, error) {
+	path := strings.TrimSuffix(file, ".csv") + "_result.csv"
+
+	f, err := os.Create(path)
+	if err != nil {
+		return path, err
+	}
+	defer f.Close()
+
+	cw := csv.NewWriter(f)
+	cw.Write([]string{"chain", "token", "to", "amount", "status", "tx", "error"})
+	for _, r := range rows {
+		cw.Write(append(append([]string{}, r.Fields...), r.Status, r.Hash, r.Error))
+	}
+	cw.Flush()
+
+	return path, cw.Error()
+}
+
+func buildBatchTemplate(from *cmn.Address, s *cmn.Signer, mode string, rows []*BatchRow, totals []*BatchTotal) string {
+	txs := len(rows)
+	if mode == BATCH_MODE_DISPERSE {
+		txs = 0
+		for _, t := range totals {
+			txs++
+			if !t.Token.Native {
+				txs++ // approval, if not approved yet
+			}
+		}
+	}
+
+	r := `        From: ` + cmn.TagAddressShortLink(from.Address) + " " + from.Name + `
+      Signer: ` + s.Name + " (" + s.Type + ")" + `
+        Mode: ` + mode + fmt.Sprintf(" (up to %d transactions)", txs) + `
+    Payments: ` + fmt.Sprintf("%d", len(rows)) + `
+<line text:Totals>
+`
+	total_dollars := 0.0
+	for _, t := range totals {
+		r += fmt.Sprintf("%12s: ", t.Chain.Name) + cmn.TagValueSymbolLink(t.Amount, t.Token)
+		if t.Token.Price > 0 {
+			d := t.Token.Price * t.Token.Float64(t.Amount)
+			total_dollars += d
+			r += " " + cmn.TagShortDollarLink(d)
+		}
+		r += "\n"
+	}
+	r += "   Total ($): " + cmn.TagShortDollarLink(total_dollars) + " (fees not included)\n"
+
+	r += "<line text:Payments>\n"
+	for i, row := range rows {
+		if i == BATCH_HAIL_ROWS {
+			r += fmt.Sprintf("  ... and %d more\n", len(rows)-BATCH_HAIL_ROWS)
+			break
+		}
+
+		name := ""
+		if a := cmn.CurrentWallet.GetAddress(row.To.String()); a != nil {
+			name = " " + a.Name
+		}
+
+		r += fmt.Sprintf("%4d ", row.Line) + cmn.TagAddressShortLink(row.To) + name + " " +
+			row.Token.Value2Str(row.Amount) + " " + row.Token.Symbol + "\n"
+	}
+
+	return r + "\n" +
+		`<c><button text:Send id:ok bgcolor:g.HelpBgColor color:g.HelpFgColor tip:"send the payments">  ` +
+		`<button text:Reject id:cancel bgcolor:g.ErrorFgColor tip:"reject the batch">`
+}
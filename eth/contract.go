@@ -0,0 +1,357 @@
+package eth
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/AlexNa-Holdings/web3pro/bus"
+	"github.com/AlexNa-Holdings/web3pro/cmn"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// Generic contract calls. The methods come from the ABI of the contract
+// (explorer download or an imported file), then from the embedded ABIs.
+// The arrays and tuples are given as JSON.
+
+// ContractMethods returns the methods known for the contract, sorted by name
+func ContractMethods(to common.Address) []*abi.Method {
+	list := []*abi.Method{}
+	sigs := map[string]bool{}
+
+	for _, a := range knownABIs(to, false) {
+		for _, m := range a.Methods {
+			if sigs[m.Sig] {
+				continue
+			}
+			sigs[m.Sig] = true
+			list = append(list, &m)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Sig < list[j].Sig
+	})
+
+	return list
+}
+
+// FindContractMethod accepts a method name or, for the overloaded methods,
+// the signature like "safeTransferFrom(address,address,uint256)"
+func FindContractMethod(to common.Address, name string) (*abi.Method, error) {
+	found := []*abi.Method{}
+	for _, m := range ContractMethods(to) {
+		if m.Sig == name {
+			return m, nil
+		}
+		if m.RawName == name {
+			found = append(found, m)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return nil, fmt.Errorf("method not found: %s (download the ABI: explorer download, or import it: contract abi)", name)
+	case 1:
+		return found[0], nil
+	}
+
+	sigs := []string{}
+	for _, m := range found {
+		sigs = append(sigs, m.Sig)
+	}
+	return nil, fmt.Errorf("overloaded method, use the signature: %s", strings.Join(sigs, " "))
+}
+
+// ImportContractABI stores the ABI file as if it was downloaded from the
+// explorer. Plain ABI JSON and the build artifacts with an "abi" field are
+// accepted.
+func ImportContractABI(to common.Address, file string) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	var artifact struct {
+		ABI json.RawMessage `json:"abi"`
+	}
+	if err := json.Unmarshal(data, &artifact); err == nil && len(artifact.ABI) > 0 {
+		data = artifact.ABI
+	}
+
+	if _, err := abi.JSON(bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("invalid ABI: %v", err)
+	}
+
+	folder := cmn.DataFolder + "/contracts/" + to.String()
+	if err := os.MkdirAll(folder, 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(folder+"/abi.json", data, 0644)
+}
+
+// ParseContractArgs converts the command line arguments to the method inputs
+func ParseContractArgs(m *abi.Method, args []string) ([]interface{}, error) {
+	if len(args) != len(m.Inputs) {
+		return nil, fmt.Errorf("%s expects %d arguments, got %d", m.Sig, len(m.Inputs), len(args))
+	}
+
+	r := []interface{}{}
+	for i, in := range m.Inputs {
+		v, err := parseContractArg(in.Type, args[i])
+		if err != nil {
+			return nil, fmt.Errorf("argument %d (%s %s): %v", i+1, in.Type.String(), in.Name, err)
+		}
+		r = append(r, v)
+	}
+	return r, nil
+}
+
+func parseContractArg(t abi.Type, s string) (interface{}, error) {
+	var j interface{} = s
+
+	switch t.T {
+	case abi.SliceTy, abi.ArrayTy, abi.TupleTy:
+		d := json.NewDecoder(strings.NewReader(s))
+		d.UseNumber() // keep the uint256 precision
+		if err := d.Decode(&j); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %v", err)
+		}
+	}
+
+	v, err := abiValue(t, j)
+	if err != nil {
+		return nil, err
+	}
+	return v.Interface(), nil
+}
+
+// abiValue builds the value of the Go type the abi package packs for t
+func abiValue(t abi.Type, j interface{}) (reflect.Value, error) {
+	switch t.T {
+	case abi.IntTy, abi.UintTy:
+		s := strings.TrimSpace(fmt.Sprint(j))
+		n, ok := new(big.Int).SetString(s, 0)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("invalid number: %s", s)
+		}
+
+		if t.T == abi.UintTy && (n.Sign() < 0 || n.BitLen() > t.Size) {
+			return reflect.Value{}, fmt.Errorf("out of range: %s", s)
+		}
+		if t.T == abi.IntTy {
+			// -2^(size-1) .. 2^(size-1)-1
+			limit := new(big.Int).Lsh(big.NewInt(1), uint(t.Size-1))
+			if n.Cmp(new(big.Int).Neg(limit)) < 0 || n.Cmp(limit) >= 0 {
+				return reflect.Value{}, fmt.Errorf("out of range: %s", s)
+			}
+		}
+
+		gt := t.GetType()
+		switch gt.Kind() {
+		case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return reflect.ValueOf(n.Uint64()).Convert(gt), nil
+		case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return reflect.ValueOf(n.Int64()).Convert(gt), nil
+		}
+		return reflect.ValueOf(n), nil
+	case abi.BoolTy:
+		b, err := strconv.ParseBool(strings.TrimSpace(fmt.Sprint(j)))
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(b), nil
+	case abi.StringTy:
+		s, ok := j.(string)
+		if !ok {
+			return reflect.Value{}, errors.New("string expected")
+		}
+		return reflect.ValueOf(s), nil
+	case abi.AddressTy:
+		s, ok := j.(string)
+		if !ok || !common.IsHexAddress(s) {
+			return reflect.Value{}, fmt.Errorf("invalid address: %v", j)
+		}
+		return reflect.ValueOf(common.HexToAddress(s)), nil
+	case abi.BytesTy:
+		s, _ := j.(string)
+		b, err := hexutil.Decode(s)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("invalid hex: %v", j)
+		}
+		return reflect.ValueOf(b), nil
+	case abi.FixedBytesTy:
+		s, _ := j.(string)
+		b, err := hexutil.Decode(s)
+		if err != nil || len(b) != t.Size {
+			return reflect.Value{}, fmt.Errorf("%d bytes hex expected: %v", t.Size, j)
+		}
+		v := reflect.New(t.GetType()).Elem()
+		reflect.Copy(v, reflect.ValueOf(b))
+		return v, nil
+	case abi.SliceTy, abi.ArrayTy:
+		list, ok := j.([]interface{})
+		if !ok {
+			return reflect.Value{}, errors.New("array expected")
+		}
+
+		var v reflect.Value
+		if t.T == abi.SliceTy {
+			v = reflect.MakeSlice(t.GetType(), len(list), len(list))
+		} else {
+			if len(list) != t.Size {
+				return reflect.Value{}, fmt.Errorf("%d elements expected", t.Size)
+			}
+			v = reflect.New(t.GetType()).Elem()
+		}
+
+		for i, e := range list {
+			ev, err := abiValue(*t.Elem, e)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("[%d]: %v", i, err)
+			}
+			v.Index(i).Set(ev)
+		}
+		return v, nil
+	case abi.TupleTy:
+		// positional [a, b] or by name {"a": .., "b": ..}
+		var list []interface{}
+		switch tj := j.(type) {
+		case []interface{}:
+			list = tj
+		case map[string]interface{}:
+			for _, name := range t.TupleRawNames {
+				e, ok := tj[name]
+				if !ok {
+					return reflect.Value{}, fmt.Errorf("missing field: %s", name)
+				}
+				list = append(list, e)
+			}
+		default:
+			return reflect.Value{}, errors.New("tuple expected")
+		}
+
+		if len(list) != len(t.TupleElems) {
+			return reflect.Value{}, fmt.Errorf("%d fields expected", len(t.TupleElems))
+		}
+
+		v := reflect.New(t.GetType()).Elem()
+		for i, e := range list {
+			ev, err := abiValue(*t.TupleElems[i], e)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("%s: %v", t.TupleRawNames[i], err)
+			}
+			v.Field(i).Set(ev)
+		}
+		return v, nil
+	}
+
+	return reflect.Value{}, fmt.Errorf("unsupported type: %s", t.String())
+}
+
+// ReadContract calls the view method and decodes the outputs
+func ReadContract(b *cmn.Blockchain, from common.Address, to common.Address, m *abi.Method, args []interface{}) ([]interface{}, error) {
+	data, err := m.Inputs.Pack(args...)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := bus.Fetch("eth", "call", &bus.B_EthCall{
+		ChainId: b.ChainId,
+		From:    from,
+		To:      to,
+		Amount:  big.NewInt(0),
+		Data:    append(m.ID, data...),
+	})
+	if resp.Error != nil {
+		return nil, errors.New(RevertReason(resp.Error, to))
+	}
+
+	out, err := hexutil.Decode(resp.Data.(string))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(out) == 0 && len(m.Outputs) > 0 {
+		return nil, errors.New("empty result, no contract at the address?")
+	}
+
+	return m.Outputs.Unpack(out)
+}
+
+// ContractCallData packs the method call
+func ContractCallData(m *abi.Method, args []interface{}) ([]byte, error) {
+	data, err := m.Inputs.Pack(args...)
+	if err != nil {
+		return nil, err
+	}
+	return append(m.ID, data...), nil
+}
+
+// FormatContractValue prints the decoded value, the arrays and tuples as JSON
+func FormatContractValue(t abi.Type, v interface{}) string {
+	switch t.T {
+	case abi.IntTy, abi.UintTy, abi.BoolTy:
+		return fmt.Sprint(v)
+	case abi.StringTy:
+		return strconv.Quote(fmt.Sprint(v))
+	case abi.AddressTy:
+		return v.(common.Address).Hex()
+	case abi.BytesTy, abi.FixedBytesTy, abi.HashTy:
+		return hexutil.Encode(toBytes(v))
+	case abi.SliceTy, abi.ArrayTy:
+		pv := reflect.ValueOf(v)
+		items := []string{}
+		for i := 0; i < pv.Len(); i++ {
+			items = append(items, jsonItem(*t.Elem, FormatContractValue(*t.Elem, pv.Index(i).Interface())))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case abi.TupleTy:
+		pv := reflect.ValueOf(v)
+		items := []string{}
+		for i := 0; i < pv.NumField(); i++ {
+			items = append(items, strconv.Quote(t.TupleRawNames[i])+": "+
+				jsonItem(*t.TupleElems[i], FormatContractValue(*t.TupleElems[i], pv.Field(i).Interface())))
+		}
+		return "{" + strings.Join(items, ", ") + "}"
+	}
+	return fmt.Sprint(v)
+}
+
+// jsonItem quotes the scalars that are not JSON literals
+func jsonItem(t abi.Type, s string) string {
+	switch t.T {
+	case abi.AddressTy, abi.BytesTy, abi.FixedBytesTy, abi.HashTy:
+		return strconv.Quote(s)
+	case abi.IntTy, abi.UintTy:
+		if t.Size > 64 {
+			return strconv.Quote(s) // JSON numbers lose the precision
+		}
+	}
+	return s
+}
+
+// ContractValueToken returns the token of an amount returned by the called
+// token contract, nil if the value is not an amount
+func ContractValueToken(b *cmn.Blockchain, to common.Address, m *abi.Method, name string, t abi.Type) *cmn.Token {
+	if tk := amountToken(b, to, m, name, t); tk != nil {
+		return tk
+	}
+
+	// unnamed outputs of the ERC-20 views
+	if name == "" && cmn.IsInArray([]string{"balanceOf", "totalSupply", "allowance"}, m.RawName) {
+		return amountToken(b, to, m, "amount", t)
+	}
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package eth
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+)
+
+func TestContractArgRange(t *testing.T) {
+	tests := []struct {
+		typ   string
+		value string
+		ok    bool
+	}{
+		{"int8", "-128", true},
+		{"int8", "127", true},
+		{"int8", "-129", false},
+		{"int8", "128", false},
+		{"int256", "-57896044618658097711785492504343953926634992332820282019728792003956564819968", true},
+		{"int256", "57896044618658097711785492504343953926634992332820282019728792003956564819967", true},
+		{"int256", "57896044618658097711785492504343953926634992332820282019728792003956564819968", false},
+		{"uint8", "255", true},
+		{"uint8", "256", false},
+		{"uint8", "-1", false},
+	}
+
+	for _, tt := range tests {
+		typ, err := abi.NewType(tt.typ, "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = parseContractArg(typ, tt.value)
+		if (err == nil) != tt.ok {
+			t.Errorf("%s %s: %v", tt.typ, tt.value, err)
+		}
+	}
+}
@@ -0,0 +1,561 @@
+package eth
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/AlexNa-Holdings/web3pro/cmn"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Calldata decoding for the confirmation hails. The method is looked up in
+// the downloaded contract ABI, the embedded ABIs and the bundled 4-byte
+// database. Calls nested in bytes parameters (multicall, Safe execTransaction,
+// multiSend) are decoded against the target found next to them.
+
+// nested calls deeper than this are shown as raw bytes
+const MAX_CALL_DEPTH = 4
+
+// tuple field names given by ParseMethodSignature
+var unnamedField = regexp.MustCompile(`^arg\d+$`)
+
+// FindMethod returns the method of the selector and where it was found:
+// "contract", "ABI" or "4byte"
+func FindMethod(to common.Address, selector []byte) (*abi.Method, string) {
+	if a, err := LoadContractABI(to); err == nil {
+		if m, err := a.MethodById(selector); err == nil {
+			return m, "contract"
+		}
+	}
+
+	for _, a := range []abi.ABI{ERC20_ABI, MULTICALL2_ABI, SAFE_ABI, PERMIT2_ABI} {
+		if m, err := a.MethodById(selector); err == nil {
+			return m, "ABI"
+		}
+	}
+
+	if m, ok := SELECTOR_DB[hexutil.Encode(selector)]; ok {
+		return m, "4byte"
+	}
+
+	return nil, ""
+}
+
+// ParseMethodSignature builds a method without parameter names from a
+// signature like "aggregate((address,bytes)[])"
+func ParseMethodSignature(sig string) (*abi.Method, error) {
+	sig = strings.ReplaceAll(sig, " ", "")
+
+	open := strings.Index(sig, "(")
+	if open <= 0 || !strings.HasSuffix(sig, ")") {
+		return nil, fmt.Errorf("invalid signature: %s", sig)
+	}
+
+	name := sig[:open]
+	list, err := splitSignatureTypes(sig[open+1 : len(sig)-1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature %s: %v", sig, err)
+	}
+
+	inputs := abi.Arguments{}
+	for _, t := range list {
+		am, err := signatureMarshaling("", t)
+		if err != nil {
+			return nil, fmt.Errorf("invalid signature %s: %v", sig, err)
+		}
+
+		typ, err := abi.NewType(am.Type, "", am.Components)
+		if err != nil {
+			return nil, fmt.Errorf("invalid signature %s: %v", sig, err)
+		}
+		inputs = append(inputs, abi.Argument{Type: typ})
+	}
+
+	m := abi.NewMethod(name, name, abi.Function, "", false, false, inputs, nil)
+	return &m, nil
+}
+
+// splitSignatureTypes splits the parameter list at the top level commas
+func splitSignatureTypes(s string) ([]string, error) {
+	if s == "" {
+		return []string{}, nil
+	}
+
+	list := []string{}
+	depth, start := 0, 0
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("unbalanced parentheses")
+			}
+		case ',':
+			if depth == 0 {
+				list = append(list, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced parentheses")
+	}
+
+	return append(list, s[start:]), nil
+}
+
+func signatureMarshaling(name string, t string) (abi.ArgumentMarshaling, error) {
+	if !strings.HasPrefix(t, "(") {
+		return abi.ArgumentMarshaling{Name: name, Type: t}, nil
+	}
+
+	end := strings.LastIndex(t, ")")
+	fields, err := splitSignatureTypes(t[1:end])
+	if err != nil {
+		return abi.ArgumentMarshaling{}, err
+	}
+
+	components := []abi.ArgumentMarshaling{}
+	for i, f := range fields {
+		c, err := signatureMarshaling(fmt.Sprintf("arg%d", i), f)
+		if err != nil {
+			return abi.ArgumentMarshaling{}, err
+		}
+		components = append(components, c)
+	}
+
+	return abi.ArgumentMarshaling{Name: name, Type: "tuple" + t[end+1:], Components: components}, nil
+}
+
+func buildCallDetails(b *cmn.Blockchain, tx *types.Transaction, to common.Address) string {
+	data := tx.Data()
+	if len(data) == 0 {
+		return ""
+	}
+
+	if len(data) < 4 {
+		return "\n        Data: " + cmn.TagBytesLink(data)
+	}
+
+	return callDetails(b, to, data, 0)
+}
+
+func callDetails(b *cmn.Blockchain, to common.Address, data []byte, indent int) string {
+	pad := strings.Repeat(" ", indent*2)
+
+	r := "\n" + pad + "      Method: "
+
+	method, source := FindMethod(to, data[:4])
+	if method == nil {
+		return r + cmn.TagBytesLink(data[:4]) + " (unknown)\n" +
+			pad + "  Parameters: " + cmn.TagLink(cmn.ICON_COPY, "copy "+hexutil.Encode(data[4:]), "Copy data") + "\n"
+	}
+
+	r += fmt.Sprintf("<l text:'%v' tip:'Copy function name' action:'copy %v'>", method.RawName, method.RawName)
+	if source == "4byte" {
+		r += " (by selector)"
+	}
+
+	r += "\n" + pad + "  Parameters: " + cmn.TagLink(cmn.ICON_COPY, "copy "+hexutil.Encode(data[4:]), "Copy data") + " \n"
+
+	params, err := method.Inputs.Unpack(data[4:])
+	if err != nil {
+		return r + pad + "(error unpacking)\n"
+	}
+
+	names := []string{}
+	for _, in := range method.Inputs {
+		names = append(names, in.Name)
+	}
+	target := callTarget(names, params, to)
+
+	for i, param := range params {
+		r += printValue(b, to, target, method, method.Inputs[i].Name, method.Inputs[i].Type, param, indent)
+	}
+
+	return r
+}
+
+// callTarget returns the address the nested calldata is sent to: a "to" or
+// "target" parameter next to it, or the called contract itself (multicall).
+// Without parameter names (4-byte database) the first address is taken.
+func callTarget(names []string, values []interface{}, def common.Address) common.Address {
+	var unnamed *common.Address
+
+	for i, n := range names {
+		if i >= len(values) {
+			break
+		}
+
+		a, ok := values[i].(common.Address)
+		if !ok {
+			continue
+		}
+
+		switch strings.ToLower(strings.TrimPrefix(n, "_")) {
+		case "to", "target", "destination":
+			return a
+		}
+
+		if unnamed == nil && (n == "" || unnamedField.MatchString(n)) {
+			unnamed = &a
+		}
+	}
+
+	if unnamed != nil {
+		return *unnamed
+	}
+	return def
+}
+
+// decodableCall returns the method if the data is a valid call of target
+func decodableCall(target common.Address, data []byte) *abi.Method {
+	if len(data) < 4 {
+		return nil
+	}
+
+	m, _ := FindMethod(target, data[:4])
+	if m == nil {
+		return nil
+	}
+
+	if _, err := m.Inputs.Unpack(data[4:]); err != nil {
+		return nil
+	}
+	return m
+}
+
+// printValue prints one parameter of a call to the contract to. Nested
+// calldata is decoded against target.
+func printValue(b *cmn.Blockchain, to common.Address, target common.Address, method *abi.Method,
+	name string, v abi.Type, param interface{}, indent int) string {
+
+	if name == "" {
+		name = v.String()
+	}
+
+	r := fmt.Sprintf("%s%12s: ", strings.Repeat(" ", indent*2), name)
+
+	switch v.T {
+	case abi.IntTy, abi.UintTy:
+		s := fmt.Sprintf("%v", param)
+		if t := amountToken(b, to, method, name, v); t != nil {
+			r += cmn.TagLink(t.Value2Str(param.(*big.Int)), "copy "+s, "Copy value") + " " + t.Symbol + "\n"
+		} else {
+			r += cmn.TagLink(s, "copy "+s, "Copy value") + "\n"
+		}
+	case abi.BoolTy:
+		r += fmt.Sprintf("%t\n", param)
+	case abi.StringTy:
+		r += fmt.Sprintf("%q\n", param)
+	case abi.AddressTy:
+		address := param.(common.Address)
+		r += cmn.TagAddressShortLink(address) + " " + addressName(b, address) + "\n"
+	case abi.BytesTy:
+		data := param.([]byte)
+		switch {
+		case method.RawName == "multiSend" && indent < MAX_CALL_DEPTH:
+			r += "\n" + multiSendDetails(b, data, indent+1)
+		case indent < MAX_CALL_DEPTH && decodableCall(target, data) != nil:
+			r += callDetails(b, target, data, indent+1)
+		default:
+			r += cmn.TagBytesLink(data) + "\n"
+		}
+	case abi.FixedBytesTy, abi.HashTy:
+		r += cmn.TagBytesLink(toBytes(param)) + "\n"
+	case abi.SliceTy, abi.ArrayTy:
+		if v.Elem.T == abi.UintTy && v.Elem.Size == 8 {
+			// If it's a byte array, display as hex string
+			r += formatBytesAsHex(toBytes(param)) + "\n"
+			break
+		}
+
+		r += "[\n"
+		pv := reflect.ValueOf(param)
+		for j := 0; j < pv.Len(); j++ {
+			r += printValue(b, to, target, method, fmt.Sprintf("[%d]", j), *v.Elem, pv.Index(j).Interface(), indent+1)
+		}
+		r += strings.Repeat(" ", 14+indent*2) + "]\n"
+	case abi.TupleTy:
+		// Handling tuples, including structs
+		r += v.TupleRawName + "{\n"
+
+		pv := reflect.ValueOf(param)
+		values := []interface{}{}
+		for j := 0; j < pv.NumField(); j++ {
+			values = append(values, pv.Field(j).Interface())
+		}
+
+		// a call struct like Multicall's {target, callData}
+		tuple_target := callTarget(v.TupleRawNames, values, target)
+
+		for j, value := range values {
+			r += printValue(b, to, tuple_target, method, v.TupleRawNames[j], *v.TupleElems[j], value, indent+1)
+		}
+		r, _ = strings.CutSuffix(r, "\n")
+		r += " }\n"
+	case abi.FixedPointTy:
+		r += fmt.Sprintf("%v (FixedPoint)\n", param)
+	case abi.FunctionTy:
+		functionData := param.([24]byte)
+		r += fmt.Sprintf("0x%x\n", functionData)
+	default:
+		r += "(unknown type)\n"
+	}
+	return r
+}
+
+type multiSendTx struct {
+	Operation uint8
+	To        common.Address
+	Value     *big.Int
+	Data      []byte
+}
+
+// parseMultiSend splits the packed transactions of the Safe MultiSend:
+// operation (1 byte), to (20), value (32), data length (32), data. The
+// transactions before an invalid one are returned with the error.
+func parseMultiSend(data []byte) ([]multiSendTx, error) {
+	res := []multiSendTx{}
+
+	for i := 0; i < len(data); {
+		if i+85 > len(data) {
+			return res, errors.New("invalid multiSend data")
+		}
+
+		length := new(big.Int).SetBytes(data[i+53 : i+85])
+		if !length.IsInt64() || i+85+int(length.Int64()) > len(data) {
+			return res, errors.New("invalid multiSend data")
+		}
+
+		tx := multiSendTx{
+			Operation: data[i],
+			To:        common.BytesToAddress(data[i+1 : i+21]),
+			Value:     new(big.Int).SetBytes(data[i+21 : i+53]),
+			Data:      data[i+85 : i+85+int(length.Int64())],
+		}
+		i += 85 + len(tx.Data)
+
+		res = append(res, tx)
+	}
+
+	return res, nil
+}
+
+// multiSendDetails decodes the packed transactions of the Safe MultiSend
+func multiSendDetails(b *cmn.Blockchain, data []byte, indent int) string {
+	pad := strings.Repeat(" ", indent*2)
+	r := ""
+
+	txs, err := parseMultiSend(data)
+	for n, tx := range txs {
+		op := "CALL"
+		if tx.Operation == 1 {
+			op = "DELEGATECALL"
+		}
+
+		r += fmt.Sprintf("%s%12s: ", pad, fmt.Sprintf("[%d]", n)) + op + " " +
+			cmn.TagAddressShortLink(tx.To) + " " + addressName(b, tx.To) + "\n"
+
+		if tx.Value.Sign() > 0 {
+			r += fmt.Sprintf("%s%12s: ", pad, "value")
+			if nt := nativeToken(b); nt != nil {
+				r += nt.Value2Str(tx.Value) + " " + nt.Symbol + "\n"
+			} else {
+				r += tx.Value.String() + "\n"
+			}
+		}
+
+		if len(tx.Data) > 0 {
+			r += fmt.Sprintf("%s%12s: ", pad, "data")
+			if indent < MAX_CALL_DEPTH && decodableCall(tx.To, tx.Data) != nil {
+				r += callDetails(b, tx.To, tx.Data, indent+1)
+			} else {
+				r += cmn.TagBytesLink(tx.Data) + "\n"
+			}
+		}
+	}
+
+	if err != nil {
+		r += pad + "(" + err.Error() + ")\n"
+	}
+
+	return r
+}
+
+// NestedCalls returns the calls nested in the calldata the way the hails
+// decode them: the bytes parameters that are calls of the target next to
+// them (multicall, Safe execTransaction) and the MultiSend transactions
+func NestedCalls(to common.Address, data []byte) []cmn.PolicyCall {
+	return nestedCalls(to, data, 0)
+}
+
+func nestedCalls(to common.Address, data []byte, depth int) []cmn.PolicyCall {
+	res := []cmn.PolicyCall{}
+
+	method := decodableCall(to, data)
+	if method == nil || depth >= MAX_CALL_DEPTH {
+		return res
+	}
+
+	params, _ := method.Inputs.Unpack(data[4:])
+
+	names := []string{}
+	for _, in := range method.Inputs {
+		names = append(names, in.Name)
+	}
+	target := callTarget(names, params, to)
+
+	for i, param := range params {
+		res = append(res, nestedValueCalls(method, target, method.Inputs[i].Type, param, depth)...)
+	}
+
+	return res
+}
+
+func nestedValueCalls(method *abi.Method, target common.Address, v abi.Type, param interface{}, depth int) []cmn.PolicyCall {
+	res := []cmn.PolicyCall{}
+
+	switch v.T {
+	case abi.BytesTy:
+		data := param.([]byte)
+		if method.RawName == "multiSend" {
+			txs, _ := parseMultiSend(data)
+			for _, tx := range txs {
+				res = append(res, cmn.PolicyCall{To: tx.To, Value: tx.Value, Data: tx.Data})
+				res = append(res, nestedCalls(tx.To, tx.Data, depth+1)...)
+			}
+		} else if decodableCall(target, data) != nil {
+			res = append(res, cmn.PolicyCall{To: target, Data: data})
+			res = append(res, nestedCalls(target, data, depth+1)...)
+		}
+	case abi.SliceTy, abi.ArrayTy:
+		if v.Elem.T == abi.UintTy && v.Elem.Size == 8 {
+			break
+		}
+
+		pv := reflect.ValueOf(param)
+		for j := 0; j < pv.Len(); j++ {
+			res = append(res, nestedValueCalls(method, target, *v.Elem, pv.Index(j).Interface(), depth)...)
+		}
+	case abi.TupleTy:
+		pv := reflect.ValueOf(param)
+		values := []interface{}{}
+		for j := 0; j < pv.NumField(); j++ {
+			values = append(values, pv.Field(j).Interface())
+		}
+
+		tuple_target := callTarget(v.TupleRawNames, values, target)
+		for j, value := range values {
+			res = append(res, nestedValueCalls(method, tuple_target, *v.TupleElems[j], value, depth)...)
+		}
+	}
+
+	return res
+}
+
+// addressName returns the wallet name of the address, contract or token
+func addressName(b *cmn.Blockchain, a common.Address) string {
+	w := cmn.CurrentWallet
+	if w == nil {
+		return ""
+	}
+
+	if wa := w.GetAddress(a); wa != nil {
+		return wa.Name
+	}
+
+	if c := w.GetContract(a); c != nil && c.Name != "" {
+		return c.Name
+	}
+
+	if a != (common.Address{}) {
+		if t := w.GetTokenByAddress(b.ChainId, a); t != nil {
+			return t.Symbol
+		}
+	}
+
+	return ""
+}
+
+func nativeToken(b *cmn.Blockchain) *cmn.Token {
+	if cmn.CurrentWallet == nil {
+		return nil
+	}
+
+	nt, err := cmn.CurrentWallet.GetNativeToken(b)
+	if err != nil {
+		return nil
+	}
+	return nt
+}
+
+// amountToken returns the token of the parameter if it is an amount of the
+// called token contract
+func amountToken(b *cmn.Blockchain, to common.Address, method *abi.Method, name string, v abi.Type) *cmn.Token {
+	w := cmn.CurrentWallet
+	if w == nil || v.T != abi.UintTy || v.Size != 256 || to == (common.Address{}) {
+		return nil
+	}
+
+	t := w.GetTokenByAddress(b.ChainId, to)
+	if t == nil || t.Native {
+		return nil
+	}
+
+	n := strings.ToLower(name)
+	if strings.Contains(n, "amount") || strings.Contains(n, "value") || n == "wad" {
+		return t
+	}
+
+	// the 4-byte database has no parameter names
+	if n == "uint256" && cmn.IsInArray([]string{"transfer", "transferFrom", "approve",
+		"increaseAllowance", "decreaseAllowance", "mint", "burn", "burnFrom", "withdraw"}, method.Name) {
+		return t
+	}
+
+	return nil
+}
+
+func toBytes(param interface{}) []byte {
+	if b, ok := param.([]byte); ok {
+		return b
+	}
+
+	v := reflect.ValueOf(param)
+	r := make([]byte, v.Len())
+	reflect.Copy(reflect.ValueOf(r), v)
+	return r
+}
+
+func formatBytesAsHex(bytesValue []byte) string {
+	MAX_IN_LINE := 16
+
+	result := "\n"
+
+	for i := 0; i < len(bytesValue); i += MAX_IN_LINE {
+		end := i + MAX_IN_LINE
+		if end > len(bytesValue) {
+			end = len(bytesValue)
+		}
+
+		line := "0x"
+		if i > 0 {
+			line = ""
+			result += "\n  "
+		}
+		result += line + fmt.Sprintf("%x", bytesValue[i:end])
+	}
+
+	return result
+}
@@ -0,0 +1,558 @@
+package eth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AlexNa-Holdings/web3pro/cmn"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/rs/zerolog/log"
+)
+
+// ENS resolution on the Ethereum blockchain of the wallet. The resolver is
+// found in the registry walking up to the parent names (ENSIP-10 wildcards).
+// Extended resolvers are called with resolve() and may answer with an
+// OffchainLookup revert, served by the gateway (EIP-3668 CCIP-read).
+
+var ENS_REGISTRY_ADDRESS = common.HexToAddress("0x00000000000C2E074eC69A0dFb2997BA6C7d2e1e")
+
+// ENSIP-10 IExtendedResolver
+var INTERFACE_EXTENDED_RESOLVER = [4]byte{0x90, 0x61, 0xb9, 0x23}
+
+const ENS_CACHE_TTL = time.Hour
+const CCIP_MAX_REDIRECTS = 4
+const CCIP_TIMEOUT = 10 * time.Second
+
+type ensCacheEntry struct {
+	value   string // name or hex address, empty if not found
+	expires time.Time
+}
+
+var ensNames = map[common.Address]*ensCacheEntry{} // reverse
+var ensAddresses = map[string]*ensCacheEntry{}     // forward
+var ensPending = map[common.Address]bool{}
+var ensMutex sync.Mutex
+
+// IsENSName tells if the string looks like a name to resolve
+func IsENSName(s string) bool {
+	return strings.Contains(s, ".") && !strings.HasPrefix(s, ".") && !strings.HasSuffix(s, ".") && !strings.ContainsAny(s, " /:")
+}
+
+// NormalizeENSName lowercases the name. Only the ASCII names are supported,
+// for them it is the ENSIP-15 normalization.
+func NormalizeENSName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// isNormalizedENSName tells if the name passes the ENSIP-15 rules of the
+// ASCII labels: lowercase letters, digits, hyphens but not at the 3rd and
+// 4th position, underscores at the start only. Emoji, confusables, control
+// and bidi characters are rejected.
+func isNormalizedENSName(name string) bool {
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || (len(label) >= 4 && label[2:4] == "--") {
+			return false
+		}
+
+		leading := true
+		for _, c := range []byte(label) {
+			switch {
+			case c == '_':
+				if !leading {
+					return false
+				}
+			case c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '-':
+				leading = false
+			default:
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// ENSNamehash returns the EIP-137 node of the name
+func ENSNamehash(name string) common.Hash {
+	node := common.Hash{}
+	if name == "" {
+		return node
+	}
+
+	labels := strings.Split(name, ".")
+	for i := len(labels) - 1; i >= 0; i-- {
+		node = crypto.Keccak256Hash(node[:], crypto.Keccak256([]byte(labels[i])))
+	}
+	return node
+}
+
+// dnsEncode returns the name in the DNS wire format used by resolve()
+func dnsEncode(name string) ([]byte, error) {
+	r := []byte{}
+	for _, label := range strings.Split(name, ".") {
+		if len(label) == 0 || len(label) > 255 {
+			return nil, fmt.Errorf("invalid name: %s", name)
+		}
+		r = append(r, byte(len(label)))
+		r = append(r, label...)
+	}
+	return append(r, 0), nil
+}
+
+// ResolveENS returns the address of the name, cached for ENS_CACHE_TTL
+func ResolveENS(name string) (common.Address, error) {
+	name = NormalizeENSName(name)
+	if !isNormalizedENSName(name) {
+		return common.Address{}, fmt.Errorf("unsupported ENS name: %q", name)
+	}
+
+	ensMutex.Lock()
+	e, ok := ensAddresses[name]
+	ensMutex.Unlock()
+	if ok && time.Now().Before(e.expires) {
+		if e.value == "" {
+			return common.Address{}, fmt.Errorf("ENS name not found: %s", name)
+		}
+		return common.HexToAddress(e.value), nil
+	}
+
+	a, err := resolveENS(name)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	ensMutex.Lock()
+	ensAddresses[name] = &ensCacheEntry{value: a.Hex(), expires: time.Now().Add(ENS_CACHE_TTL)}
+	ensMutex.Unlock()
+
+	return a, nil
+}
+
+// LookupENS returns the primary name of the address, verified by the
+// forward resolution. Empty if no name is set.
+func LookupENS(a common.Address) (string, error) {
+	ensMutex.Lock()
+	e, ok := ensNames[a]
+	ensMutex.Unlock()
+	if ok && time.Now().Before(e.expires) {
+		return e.value, nil
+	}
+
+	name, err := lookupENS(a)
+	if err != nil {
+		return "", err
+	}
+
+	ensMutex.Lock()
+	ensNames[a] = &ensCacheEntry{value: name, expires: time.Now().Add(ENS_CACHE_TTL)}
+	ensMutex.Unlock()
+
+	return name, nil
+}
+
+// ENSName returns the cached primary name of a counterparty address and
+// refreshes the cache in background. It never blocks, the template helpers
+// call it through cmn.AddressNameLookup.
+func ENSName(a common.Address) string {
+	if !isENSCounterparty(a) {
+		return ""
+	}
+
+	ensMutex.Lock()
+	defer ensMutex.Unlock()
+
+	e, ok := ensNames[a]
+	if (!ok || time.Now().After(e.expires)) && !ensPending[a] {
+		ensPending[a] = true
+		go func() {
+			if _, err := LookupENS(a); err != nil {
+				log.Debug().Err(err).Msgf("ENSName: lookup of %s failed", a.Hex())
+				ensMutex.Lock()
+				ensNames[a] = &ensCacheEntry{expires: time.Now().Add(ENS_CACHE_TTL)}
+				ensMutex.Unlock()
+			}
+			ensMutex.Lock()
+			delete(ensPending, a)
+			ensMutex.Unlock()
+		}()
+	}
+
+	if ok {
+		return e.value
+	}
+	return ""
+}
+
+// primeENSName looks the name up before a hail is built, the hail is not
+// rebuilt when a background lookup finishes
+func primeENSName(a common.Address) {
+	if !isENSCounterparty(a) {
+		return
+	}
+
+	if _, err := LookupENS(a); err != nil {
+		log.Debug().Err(err).Msgf("primeENSName: lookup of %s failed", a.Hex())
+	}
+}
+
+// isENSCounterparty skips the own addresses and the wallets without Ethereum
+func isENSCounterparty(a common.Address) bool {
+	w := cmn.CurrentWallet
+	if w == nil || w.GetBlockchain(1) == nil || a == (common.Address{}) {
+		return false
+	}
+
+	return w.GetAddress(a.String()) == nil
+}
+
+func ensBlockchain() (*cmn.Blockchain, error) {
+	w := cmn.CurrentWallet
+	if w == nil {
+		return nil, errors.New("no wallet")
+	}
+
+	b := w.GetBlockchain(1)
+	if b == nil {
+		return nil, errors.New("ENS needs the Ethereum blockchain in the wallet")
+	}
+	return b, nil
+}
+
+func resolveENS(name string) (common.Address, error) {
+	b, err := ensBlockchain()
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	resolver, extended, err := findENSResolver(b, name)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	node := ENSNamehash(name)
+	data, err := ENS_ABI.Pack("addr", node)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	out, err := ensResolverCall(b, resolver, extended, name, data)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	values, err := ENS_ABI.Unpack("addr", out)
+	if err != nil || len(values) != 1 {
+		return common.Address{}, fmt.Errorf("invalid addr result: %v", err)
+	}
+
+	a, ok := values[0].(common.Address)
+	if !ok || a == (common.Address{}) {
+		return common.Address{}, fmt.Errorf("ENS name has no address: %s", name)
+	}
+
+	return a, nil
+}
+
+func lookupENS(a common.Address) (string, error) {
+	b, err := ensBlockchain()
+	if err != nil {
+		return "", err
+	}
+
+	reverse := strings.ToLower(a.Hex()[2:]) + ".addr.reverse"
+	node := ENSNamehash(reverse)
+
+	resolver, err := ensRegistryResolver(b, node)
+	if err != nil {
+		return "", err
+	}
+
+	if resolver == (common.Address{}) {
+		return "", nil
+	}
+
+	data, err := ENS_ABI.Pack("name", node)
+	if err != nil {
+		return "", err
+	}
+
+	out, err := ensCall(b, resolver, data)
+	if err != nil {
+		return "", err
+	}
+
+	values, err := ENS_ABI.Unpack("name", out)
+	if err != nil || len(values) != 1 {
+		return "", fmt.Errorf("invalid name result: %v", err)
+	}
+
+	name, _ := values[0].(string)
+	if name == "" {
+		return "", nil
+	}
+
+	// shown as is, a name that differs from its normalized form may imitate another one
+	if !isNormalizedENSName(name) {
+		log.Debug().Msgf("lookupENS: %q of %s is not normalized", name, a.Hex())
+		return "", nil
+	}
+
+	// the reverse record is set by the owner of the address, anyone can claim any name
+	forward, err := ResolveENS(name)
+	if err != nil || forward != a {
+		log.Debug().Err(err).Msgf("lookupENS: %s does not resolve to %s", name, a.Hex())
+		return "", nil
+	}
+
+	return name, nil
+}
+
+// findENSResolver walks up the name until a resolver is set. A resolver of a
+// parent name must support the wildcard resolution (extended resolver).
+func findENSResolver(b *cmn.Blockchain, name string) (common.Address, bool, error) {
+	for n := name; n != ""; {
+		resolver, err := ensRegistryResolver(b, ENSNamehash(n))
+		if err != nil {
+			return common.Address{}, false, err
+		}
+
+		if resolver != (common.Address{}) {
+			extended := supportsInterface(b, resolver, INTERFACE_EXTENDED_RESOLVER)
+			if n != name && !extended {
+				break
+			}
+			return resolver, extended, nil
+		}
+
+		_, parent, found := strings.Cut(n, ".")
+		if !found {
+			break
+		}
+		n = parent
+	}
+
+	return common.Address{}, false, fmt.Errorf("ENS name not found: %s", name)
+}
+
+func ensRegistryResolver(b *cmn.Blockchain, node common.Hash) (common.Address, error) {
+	data, err := ENS_ABI.Pack("resolver", node)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	out, err := ensCall(b, ENS_REGISTRY_ADDRESS, data)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	values, err := ENS_ABI.Unpack("resolver", out)
+	if err != nil || len(values) != 1 {
+		return common.Address{}, fmt.Errorf("invalid resolver result: %v", err)
+	}
+
+	a, _ := values[0].(common.Address)
+	return a, nil
+}
+
+func supportsInterface(b *cmn.Blockchain, contract common.Address, id [4]byte) bool {
+	data, err := ENS_ABI.Pack("supportsInterface", id)
+	if err != nil {
+		return false
+	}
+
+	out, err := ensCall(b, contract, data)
+	if err != nil {
+		return false
+	}
+
+	values, err := ENS_ABI.Unpack("supportsInterface", out)
+	if err != nil || len(values) != 1 {
+		return false
+	}
+
+	r, _ := values[0].(bool)
+	return r
+}
+
+// ensResolverCall wraps the record call into resolve() for the extended resolvers
+func ensResolverCall(b *cmn.Blockchain, resolver common.Address, extended bool, name string, data []byte) ([]byte, error) {
+	if !extended {
+		return ensCall(b, resolver, data)
+	}
+
+	dns, err := dnsEncode(name)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err = ENS_ABI.Pack("resolve", dns, data)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := ensCall(b, resolver, data)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := ENS_ABI.Unpack("resolve", out)
+	if err != nil || len(values) != 1 {
+		return nil, fmt.Errorf("invalid resolve result: %v", err)
+	}
+
+	r, _ := values[0].([]byte)
+	return r, nil
+}
+
+// ensCall is eth_call following the OffchainLookup reverts
+func ensCall(b *cmn.Blockchain, to common.Address, data []byte) ([]byte, error) {
+	client, err := getEthClient(b)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i <= CCIP_MAX_REDIRECTS; i++ {
+		acquireRateLimit(b.ChainId)
+		out, err := client.CallContract(context.Background(), ethereum.CallMsg{To: &to, Data: data}, nil)
+		handleRPCResult(b.ChainId, err)
+		if err == nil {
+			return out, nil
+		}
+
+		lookup := offchainLookup(err)
+		if lookup == nil {
+			return nil, err
+		}
+
+		if lookup.sender != to {
+			return nil, errors.New("OffchainLookup: sender does not match the contract")
+		}
+
+		response, err := ccipRead(lookup)
+		if err != nil {
+			return nil, err
+		}
+
+		// callback(bytes response, bytes extraData)
+		bytes_type, _ := abi.NewType("bytes", "", nil)
+		params, err := abi.Arguments{{Type: bytes_type}, {Type: bytes_type}}.Pack(response, lookup.extraData)
+		if err != nil {
+			return nil, err
+		}
+
+		data = append(lookup.callback[:], params...)
+	}
+
+	return nil, errors.New("OffchainLookup: too many redirects")
+}
+
+type ccipLookup struct {
+	sender    common.Address
+	urls      []string
+	callData  []byte
+	callback  [4]byte
+	extraData []byte
+}
+
+// offchainLookup decodes the OffchainLookup revert of the node error, if any
+func offchainLookup(err error) *ccipLookup {
+	var de rpc.DataError
+	if !errors.As(err, &de) {
+		return nil
+	}
+
+	s, ok := de.ErrorData().(string)
+	if !ok {
+		return nil
+	}
+
+	data, derr := hexutil.Decode(s)
+	if derr != nil || len(data) < 4 {
+		return nil
+	}
+
+	e := ENS_ABI.Errors["OffchainLookup"]
+	if !bytes.Equal(data[:4], e.ID[:4]) {
+		return nil
+	}
+
+	values, uerr := e.Inputs.Unpack(data[4:])
+	if uerr != nil || len(values) != 5 {
+		return nil
+	}
+
+	l := &ccipLookup{}
+	l.sender, _ = values[0].(common.Address)
+	l.urls, _ = values[1].([]string)
+	l.callData, _ = values[2].([]byte)
+	l.callback, _ = values[3].([4]byte)
+	l.extraData, _ = values[4].([]byte)
+	return l
+}
+
+// ccipRead asks the gateways in order, a 4xx answer stops the lookup
+func ccipRead(l *ccipLookup) ([]byte, error) {
+	client := &http.Client{Timeout: CCIP_TIMEOUT}
+	sender := strings.ToLower(l.sender.Hex())
+	call_data := hexutil.Encode(l.callData)
+
+	err := errors.New("OffchainLookup: no gateway")
+	for _, u := range l.urls {
+		u = strings.ReplaceAll(u, "{sender}", sender)
+
+		var resp *http.Response
+		var rerr error
+		if strings.Contains(u, "{data}") {
+			resp, rerr = client.Get(strings.ReplaceAll(u, "{data}", call_data))
+		} else {
+			body, _ := json.Marshal(map[string]string{"data": call_data, "sender": sender})
+			resp, rerr = client.Post(u, "application/json", bytes.NewReader(body))
+		}
+
+		if rerr != nil {
+			log.Debug().Err(rerr).Msgf("ccipRead: gateway %s failed", u)
+			err = rerr
+			continue
+		}
+
+		body, rerr := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if rerr != nil {
+			err = rerr
+			continue
+		}
+
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 {
+			return nil, fmt.Errorf("OffchainLookup: gateway error %d: %s", resp.StatusCode, string(body))
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			err = fmt.Errorf("OffchainLookup: gateway error %d", resp.StatusCode)
+			continue
+		}
+
+		var result struct {
+			Data string `json:"data"`
+		}
+		if rerr := json.Unmarshal(body, &result); rerr != nil {
+			err = rerr
+			continue
+		}
+
+		return hexutil.Decode(result.Data)
+	}
+
+	return nil, err
+}
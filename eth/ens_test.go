@@ -0,0 +1,33 @@
+package eth
+
+import "testing"
+
+func TestNormalizedENSName(t *testing.T) {
+	tests := []struct {
+		name string
+		ok   bool
+	}{
+		{"vitalik.eth", true},
+		{"my-name.eth", true},
+		{"__sub.name.eth", true},
+		{"0x1234.eth", true},
+		{"Vitalik.eth", false},
+		{"vitalik..eth", false},
+		{"vitalik.eth.", false},
+		{"", false},
+		{"xn--80ak6aa92e.eth", false},
+		{"na_me.eth", false},
+		{"vita lik.eth", false},
+		{"vitalik<b>.eth", false},
+		{"vit\u0430lik.eth", false},     // cyrillic a
+		{"vitalik\u202egnp.eth", false}, // right-to-left override
+		{"vitalik\x00.eth", false},
+		{"\U0001f4a9.eth", false},
+	}
+
+	for _, tt := range tests {
+		if got := isNormalizedENSName(tt.name); got != tt.ok {
+			t.Errorf("%q: %v, want %v", tt.name, got, tt.ok)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package eth
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/big"
 	"strings"
@@ -24,6 +25,11 @@ type con struct {
 var cons map[int]*con = make(map[int]*con) // chainId -> client
 var consMutex = sync.Mutex{}
 
+var ErrOffline = errors.New("offline mode, no network access")
+
+// the requests served by the offline (air-gapped) instance
+var offlineTypes = []string{"sign-typed-data-v4", "sign", "sign-tx-file", "reconnect"}
+
 // Rate limiter per chain with auto-tuning
 type rateLimiter struct {
 	chainId       int
@@ -323,6 +329,7 @@ func handleRPCResult(chainId int, err error) {
 
 func Init() {
 	LoadABIs()
+	cmn.AddressNameLookup = ENSName
 	go Loop()
 }
 
@@ -339,6 +346,11 @@ func Loop() {
 func process(msg *bus.Message) {
 	switch msg.Topic {
 	case "eth":
+		if cmn.Config.Offline && !cmn.IsInArray(offlineTypes, msg.Type) {
+			msg.Respond(nil, ErrOffline)
+			return
+		}
+
 		// Apply rate limiting for RPC calls
 		chainId := getChainIdFromMessage(msg)
 		if chainId > 0 {
@@ -350,10 +362,19 @@ func process(msg *bus.Message) {
 			err := send(msg)
 			handleRPCResult(chainId, err)
 			msg.Respond(nil, err)
+		case "send-batch":
+			err := sendBatch(msg)
+			msg.Respond(nil, err)
 		case "send-tx":
 			hash, err := sendTx(msg)
 			handleRPCResult(chainId, err)
 			msg.Respond(hash, err)
+		case "safe-tx":
+			hash, err := safeTx(msg)
+			msg.Respond(hash, err)
+		case "replace-tx":
+			hash, err := replaceTx(msg)
+			msg.Respond(hash, err)
 		case "call":
 			data, err := call(msg)
 			handleRPCResult(chainId, err)
@@ -380,6 +401,15 @@ func process(msg *bus.Message) {
 			tx, err := getTxByHash(msg)
 			handleRPCResult(chainId, err)
 			msg.Respond(tx, err)
+		case "get-tx-receipt":
+			receipt, err := getTxReceipt(msg)
+			handleRPCResult(chainId, err)
+			msg.Respond(receipt, err)
+		case "sign-tx-file":
+			hash, err := signTxFile(msg)
+			msg.Respond(hash, err)
+		case "reconnect":
+			initConnections()
 		}
 	case "wallet":
 		switch msg.Type {
@@ -402,6 +432,8 @@ func getChainIdFromMessage(msg *bus.Message) int {
 		return req.ChainId
 	case *bus.B_EthSendTx:
 		return req.ChainId
+	case *bus.B_EthTxReceipt:
+		return req.ChainId
 	case *bus.B_EthEstimateGas:
 		w := cmn.CurrentWallet
 		if w != nil {
@@ -496,6 +528,10 @@ func updateConnections() {
 }
 
 func openClient_locked(b *cmn.Blockchain) error {
+	if cmn.Config.Offline {
+		return ErrOffline
+	}
+
 	client, err := ethclient.Dial(b.Url)
 	if err != nil {
 		log.Error().Err(err).Str("chain", b.GetShortName()).Str("url", b.Url).Msg("OpenClient: Cannot dial")
@@ -508,6 +544,10 @@ func openClient_locked(b *cmn.Blockchain) error {
 }
 
 func getEthClient(b *cmn.Blockchain) (*ethclient.Client, error) {
+	if cmn.Config.Offline {
+		return nil, ErrOffline
+	}
+
 	consMutex.Lock()
 	defer consMutex.Unlock()
 
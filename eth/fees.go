@@ -0,0 +1,427 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/AlexNa-Holdings/web3pro/cmn"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Fee presets. The priority fee of a preset is a percentile of the rewards
+// paid in the recent blocks, the max fee covers the base fee trend for the
+// expected waiting time with a margin.
+
+const FEE_HISTORY_BLOCKS = 20
+const FEE_PRESETS_TTL = 15 * time.Second
+
+// used if the block time can't be measured
+const DEFAULT_BLOCK_TIME = 12 * time.Second
+
+type FeePreset struct {
+	Name    string
+	TipCap  *big.Int
+	FeeCap  *big.Int
+	BaseFee *big.Int      // expected base fee of the next block
+	Wait    time.Duration // expected inclusion time, 0 if unknown
+}
+
+type feePresetParams struct {
+	name       string
+	percentile float64 // of the priority fees paid in the recent blocks
+	blocks     int     // expected blocks to inclusion
+	margin     int64   // over the projected base fee, percents
+}
+
+var FEE_PRESET_PARAMS = []feePresetParams{
+	{cmn.FEE_PRESET_SLOW, 10, 10, 110},
+	{cmn.FEE_PRESET_NORMAL, 40, 3, 125},
+	{cmn.FEE_PRESET_FAST, 70, 1, 150},
+	{cmn.FEE_PRESET_URGENT, 95, 1, 200},
+}
+
+// legacy fee presets, percents of the suggested gas price
+var LEGACY_FEE_PRESET_PARAMS = []feePresetParams{
+	{cmn.FEE_PRESET_SLOW, 0, 10, 90},
+	{cmn.FEE_PRESET_NORMAL, 0, 3, 100},
+	{cmn.FEE_PRESET_FAST, 0, 1, 125},
+	{cmn.FEE_PRESET_URGENT, 0, 1, 150},
+}
+
+type feePresetsCache struct {
+	presets []*FeePreset
+	time    time.Time
+}
+
+var feePresetsMutex sync.Mutex
+var feePresets = map[int]*feePresetsCache{}
+
+// detected chains without the base fee
+var legacyChainsMutex sync.Mutex
+var legacyChains = map[int]bool{}
+
+// GasPrice is the expected price per gas, not the max one
+func (p *FeePreset) GasPrice() *big.Int {
+	price := new(big.Int).Add(p.BaseFee, p.TipCap)
+	if price.Cmp(p.FeeCap) > 0 {
+		return new(big.Int).Set(p.FeeCap)
+	}
+	return price
+}
+
+func (p *FeePreset) WaitString() string {
+	switch {
+	case p.Wait == 0:
+		return "?"
+	case p.Wait < time.Minute:
+		return fmt.Sprintf("~%ds", int(p.Wait.Seconds()))
+	default:
+		return fmt.Sprintf("~%d min", int(math.Round(p.Wait.Minutes())))
+	}
+}
+
+// GetFeePreset returns the preset by name, the chain default if the name is empty
+func GetFeePreset(b *cmn.Blockchain, name string) (*FeePreset, error) {
+	if name == "" {
+		name = b.FeePreset
+	}
+	if name == "" {
+		name = cmn.FEE_PRESET_NORMAL
+	}
+
+	presets, err := GetFeePresets(b)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, p := range presets {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown fee preset: %s", name)
+}
+
+// GetFeePresets returns all the presets from the slowest to the fastest
+func GetFeePresets(b *cmn.Blockchain) ([]*FeePreset, error) {
+	feePresetsMutex.Lock()
+	c, ok := feePresets[b.ChainId]
+	feePresetsMutex.Unlock()
+
+	if ok && time.Since(c.time) < FEE_PRESETS_TTL {
+		return c.presets, nil
+	}
+
+	// the node is queried without the lock, concurrent misses of the same
+	// chain both fetch and the last one is cached
+	var presets []*FeePreset
+	var err error
+	if IsLegacyChain(b) {
+		presets, err = feePresetsLegacy(b)
+	} else {
+		presets, err = feePresetsFromHistory(b)
+		if err != nil {
+			log.Debug().Err(err).Str("chain", b.GetShortName()).Msg("GetFeePresets: fee history failed, using the suggested tip")
+			presets, err = feePresetsSuggested(b)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	feePresetsMutex.Lock()
+	feePresets[b.ChainId] = &feePresetsCache{presets: presets, time: time.Now()}
+	feePresetsMutex.Unlock()
+
+	return presets, nil
+}
+
+func feePresetsFromHistory(b *cmn.Blockchain) ([]*FeePreset, error) {
+	client, err := getEthClient(b)
+	if err != nil {
+		return nil, err
+	}
+
+	percentiles := []float64{}
+	for _, p := range FEE_PRESET_PARAMS {
+		percentiles = append(percentiles, p.percentile)
+	}
+
+	acquireRateLimit(b.ChainId)
+	h, err := client.FeeHistory(context.Background(), FEE_HISTORY_BLOCKS, nil, percentiles)
+	handleRPCResult(b.ChainId, err)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(h.BaseFee) == 0 || len(h.Reward) == 0 {
+		return nil, errors.New("empty fee history")
+	}
+
+	// the last base fee is the one of the next block
+	next_base := h.BaseFee[len(h.BaseFee)-1]
+	if next_base == nil {
+		return nil, errors.New("no base fee")
+	}
+
+	// the base fee moves up to 12.5% per block depending on how full the
+	// blocks are, only the growth is projected
+	used := 0.
+	for _, r := range h.GasUsedRatio {
+		used += r
+	}
+	trend := 1.
+	if len(h.GasUsedRatio) > 0 {
+		trend = max(1, 1+0.125*(used/float64(len(h.GasUsedRatio))-0.5)/0.5)
+	}
+
+	block_time := blockTime(b, h.OldestBlock, len(h.GasUsedRatio))
+
+	presets := []*FeePreset{}
+	prev_tip := big.NewInt(0)
+	for i, p := range FEE_PRESET_PARAMS {
+		tips := []*big.Int{}
+		for j, rewards := range h.Reward {
+			if j < len(h.GasUsedRatio) && h.GasUsedRatio[j] == 0 {
+				continue // empty block
+			}
+			if i < len(rewards) && rewards[i] != nil {
+				tips = append(tips, rewards[i])
+			}
+		}
+
+		tip := big.NewInt(0)
+		if len(tips) > 0 {
+			slices.SortFunc(tips, func(x, y *big.Int) int { return x.Cmp(y) })
+			tip = new(big.Int).Set(tips[len(tips)/2])
+		}
+
+		// a faster preset never pays less
+		if tip.Cmp(prev_tip) < 0 {
+			tip.Set(prev_tip)
+		}
+		prev_tip = tip
+
+		projected, _ := new(big.Float).Mul(
+			new(big.Float).SetInt(next_base),
+			big.NewFloat(math.Pow(trend, float64(p.blocks))),
+		).Int(nil)
+
+		fee_cap := new(big.Int).Div(new(big.Int).Mul(projected, big.NewInt(p.margin)), big.NewInt(100))
+		fee_cap.Add(fee_cap, tip)
+
+		presets = append(presets, &FeePreset{
+			Name:    p.name,
+			TipCap:  tip,
+			FeeCap:  fee_cap,
+			BaseFee: new(big.Int).Set(next_base),
+			Wait:    time.Duration(p.blocks) * block_time,
+		})
+	}
+
+	return presets, nil
+}
+
+// feePresetsLegacy has the gas price in both caps, no base fee
+func feePresetsLegacy(b *cmn.Blockchain) ([]*FeePreset, error) {
+	client, err := getEthClient(b)
+	if err != nil {
+		return nil, err
+	}
+
+	acquireRateLimit(b.ChainId)
+	price, err := client.SuggestGasPrice(context.Background())
+	handleRPCResult(b.ChainId, err)
+	if err != nil {
+		return nil, err
+	}
+
+	block_time := DEFAULT_BLOCK_TIME
+	acquireRateLimit(b.ChainId)
+	header, err := client.HeaderByNumber(context.Background(), nil)
+	handleRPCResult(b.ChainId, err)
+	if err == nil && header.Number.Int64() >= FEE_HISTORY_BLOCKS {
+		block_time = blockTime(b, new(big.Int).Sub(header.Number, big.NewInt(FEE_HISTORY_BLOCKS-1)), FEE_HISTORY_BLOCKS)
+	}
+
+	presets := []*FeePreset{}
+	for _, p := range LEGACY_FEE_PRESET_PARAMS {
+		gas_price := new(big.Int).Div(new(big.Int).Mul(price, big.NewInt(p.margin)), big.NewInt(100))
+
+		presets = append(presets, &FeePreset{
+			Name:    p.name,
+			TipCap:  gas_price,
+			FeeCap:  gas_price,
+			BaseFee: big.NewInt(0),
+			Wait:    time.Duration(p.blocks) * block_time,
+		})
+	}
+
+	return presets, nil
+}
+
+// feePresetsSuggested is used on the chains without eth_feeHistory
+func feePresetsSuggested(b *cmn.Blockchain) ([]*FeePreset, error) {
+	client, err := getEthClient(b)
+	if err != nil {
+		return nil, err
+	}
+
+	acquireRateLimit(b.ChainId)
+	tip, err := client.SuggestGasTipCap(context.Background())
+	handleRPCResult(b.ChainId, err)
+	if err != nil {
+		return nil, err
+	}
+
+	acquireRateLimit(b.ChainId)
+	header, err := client.HeaderByNumber(context.Background(), nil)
+	handleRPCResult(b.ChainId, err)
+	if err != nil {
+		return nil, err
+	}
+
+	base := big.NewInt(0)
+	if header.BaseFee != nil {
+		base = header.BaseFee
+	}
+
+	presets := []*FeePreset{}
+	for _, p := range FEE_PRESET_PARAMS {
+		fee_cap := new(big.Int).Div(new(big.Int).Mul(base, big.NewInt(p.margin)), big.NewInt(100))
+		fee_cap.Add(fee_cap, tip)
+
+		presets = append(presets, &FeePreset{
+			Name:    p.name,
+			TipCap:  new(big.Int).Set(tip),
+			FeeCap:  fee_cap,
+			BaseFee: new(big.Int).Set(base),
+		})
+	}
+
+	return presets, nil
+}
+
+// blockTime is the average time of the blocks since oldest
+func blockTime(b *cmn.Blockchain, oldest *big.Int, blocks int) time.Duration {
+	client, err := getEthClient(b)
+	if err != nil || oldest == nil || blocks == 0 {
+		return DEFAULT_BLOCK_TIME
+	}
+
+	acquireRateLimit(b.ChainId)
+	first, err := client.HeaderByNumber(context.Background(), oldest)
+	handleRPCResult(b.ChainId, err)
+	if err != nil {
+		return DEFAULT_BLOCK_TIME
+	}
+
+	acquireRateLimit(b.ChainId)
+	last, err := client.HeaderByNumber(context.Background(), new(big.Int).Add(oldest, big.NewInt(int64(blocks-1))))
+	handleRPCResult(b.ChainId, err)
+	if err != nil || blocks < 2 || last.Time <= first.Time {
+		return DEFAULT_BLOCK_TIME
+	}
+
+	return time.Duration(last.Time-first.Time) * time.Second / time.Duration(blocks-1)
+}
+
+// IsLegacyChain tells if the chain takes type-0 transactions only. In the
+// auto mode it is detected from the base fee of the latest header.
+func IsLegacyChain(b *cmn.Blockchain) bool {
+	switch b.TxType {
+	case cmn.TX_TYPE_LEGACY:
+		return true
+	case cmn.TX_TYPE_EIP1559:
+		return false
+	}
+
+	legacyChainsMutex.Lock()
+	defer legacyChainsMutex.Unlock()
+
+	if legacy, ok := legacyChains[b.ChainId]; ok {
+		return legacy
+	}
+
+	client, err := getEthClient(b)
+	if err != nil {
+		return false
+	}
+
+	acquireRateLimit(b.ChainId)
+	header, err := client.HeaderByNumber(context.Background(), nil)
+	handleRPCResult(b.ChainId, err)
+	if err != nil {
+		log.Debug().Err(err).Str("chain", b.GetShortName()).Msg("IsLegacyChain: cannot get the latest header")
+		return false // not cached, try next time
+	}
+
+	legacyChains[b.ChainId] = header.BaseFee == nil
+	return header.BaseFee == nil
+}
+
+// newTx creates the transaction of the chain type with the preset fee
+func newTx(b *cmn.Blockchain, nonce uint64, to common.Address, amount *big.Int, gas uint64, data []byte, fee *FeePreset) *types.Transaction {
+	if IsLegacyChain(b) {
+		return types.NewTx(&types.LegacyTx{
+			Nonce:    nonce,
+			GasPrice: fee.GasPrice(),
+			Gas:      gas,
+			To:       &to,
+			Value:    amount,
+			Data:     data,
+		})
+	}
+
+	return types.NewTx(&types.DynamicFeeTx{
+		ChainID:   big.NewInt(int64(b.ChainId)),
+		Nonce:     nonce,
+		To:        &to,
+		Value:     amount,
+		Gas:       gas,
+		GasFeeCap: fee.FeeCap,
+		GasTipCap: fee.TipCap,
+		Data:      data,
+	})
+}
+
+// withGasPrice returns a copy of the unsigned transaction with the gas
+// price set by the user, it is the max fee of a type-2 transaction
+func withGasPrice(tx *types.Transaction, price *big.Int) *types.Transaction {
+	if tx.Type() == types.LegacyTxType {
+		return types.NewTx(&types.LegacyTx{
+			Nonce:    tx.Nonce(),
+			GasPrice: price,
+			Gas:      tx.Gas(),
+			To:       tx.To(),
+			Value:    tx.Value(),
+			Data:     tx.Data(),
+		})
+	}
+
+	tip := tx.GasTipCap()
+	if tip.Cmp(price) > 0 {
+		tip = price
+	}
+
+	return types.NewTx(&types.DynamicFeeTx{
+		ChainID:    tx.ChainId(),
+		Nonce:      tx.Nonce(),
+		GasTipCap:  tip,
+		GasFeeCap:  price,
+		Gas:        tx.Gas(),
+		To:         tx.To(),
+		Value:      tx.Value(),
+		Data:       tx.Data(),
+		AccessList: tx.AccessList(),
+	})
+}
@@ -0,0 +1,161 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/AlexNa-Holdings/web3pro/cmn"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/rs/zerolog/log"
+)
+
+// L2 fee models. On the rollups the fee has two parts: the L2 execution
+// (gas * price) and the L1 data fee. OP-stack charges the data fee on top
+// of the gas, Arbitrum adds the L1 part to the gas limit.
+
+// OP-stack predeploy
+var GAS_PRICE_ORACLE_ADDRESS = common.HexToAddress("0x420000000000000000000000000000000000000F")
+
+// Arbitrum virtual contract, available for eth_call only
+var NODE_INTERFACE_ADDRESS = common.HexToAddress("0x00000000000000000000000000000000000000C8")
+
+type TxFee struct {
+	Model string
+	L2    *big.Int // execution
+	L1    *big.Int // data fee, nil if unknown or not applicable
+}
+
+func (f *TxFee) Total() *big.Int {
+	total := new(big.Int).Set(f.L2)
+	if f.L1 != nil {
+		total.Add(total, f.L1)
+	}
+	return total
+}
+
+// HasL1 tells if the L1 part is shown separately
+func (f *TxFee) HasL1() bool {
+	return f.Model == cmn.FEE_MODEL_OP_STACK || f.Model == cmn.FEE_MODEL_ARBITRUM
+}
+
+// EstimateTxFee splits the fee of the unsigned transaction paid with
+// gas_price according to the fee model of the chain
+func EstimateTxFee(b *cmn.Blockchain, from common.Address, tx *types.Transaction, gas_price *big.Int) *TxFee {
+	fee := &TxFee{
+		Model: b.FeeModel,
+		L2:    new(big.Int).Mul(new(big.Int).SetUint64(tx.Gas()), gas_price),
+	}
+
+	var err error
+	switch b.FeeModel {
+	case cmn.FEE_MODEL_OP_STACK:
+		fee.L1, err = opL1Fee(b, tx)
+	case cmn.FEE_MODEL_ARBITRUM:
+		var l1_gas uint64
+		l1_gas, err = arbitrumL1Gas(b, from, tx)
+		if err == nil {
+			// the L1 gas is a part of the gas limit
+			l1_gas = min(l1_gas, tx.Gas())
+			fee.L1 = new(big.Int).Mul(new(big.Int).SetUint64(l1_gas), gas_price)
+			fee.L2.Sub(fee.L2, fee.L1)
+		}
+	default:
+		fee.Model = cmn.FEE_MODEL_L1
+	}
+
+	if err != nil {
+		log.Debug().Err(err).Str("chain", b.GetShortName()).Msgf("EstimateTxFee: cannot get the L1 fee (%s)", b.FeeModel)
+	}
+
+	return fee
+}
+
+// opL1Fee asks the GasPriceOracle, the oracle adds the signature overhead
+func opL1Fee(b *cmn.Blockchain, tx *types.Transaction) (*big.Int, error) {
+	client, err := getEthClient(b)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := GAS_PRICE_ORACLE_ABI.Pack("getL1Fee", raw)
+	if err != nil {
+		return nil, err
+	}
+
+	acquireRateLimit(b.ChainId)
+	out, err := client.CallContract(context.Background(), ethereum.CallMsg{To: &GAS_PRICE_ORACLE_ADDRESS, Data: data}, nil)
+	handleRPCResult(b.ChainId, err)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := GAS_PRICE_ORACLE_ABI.Unpack("getL1Fee", out)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(values) != 1 {
+		return nil, errors.New("invalid getL1Fee result")
+	}
+
+	fee, ok := values[0].(*big.Int)
+	if !ok {
+		return nil, errors.New("invalid getL1Fee result")
+	}
+
+	return fee, nil
+}
+
+// arbitrumL1Gas returns the part of the gas limit paying for the L1 data
+func arbitrumL1Gas(b *cmn.Blockchain, from common.Address, tx *types.Transaction) (uint64, error) {
+	client, err := getEthClient(b)
+	if err != nil {
+		return 0, err
+	}
+
+	to := common.Address{}
+	if tx.To() != nil {
+		to = *tx.To()
+	}
+
+	data, err := NODE_INTERFACE_ABI.Pack("gasEstimateComponents", to, tx.To() == nil, tx.Data())
+	if err != nil {
+		return 0, err
+	}
+
+	acquireRateLimit(b.ChainId)
+	out, err := client.CallContract(context.Background(), ethereum.CallMsg{
+		From:  from,
+		To:    &NODE_INTERFACE_ADDRESS,
+		Value: tx.Value(),
+		Data:  data,
+	}, nil)
+	handleRPCResult(b.ChainId, err)
+	if err != nil {
+		return 0, err
+	}
+
+	values, err := NODE_INTERFACE_ABI.Unpack("gasEstimateComponents", out)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(values) != 4 {
+		return 0, errors.New("invalid gasEstimateComponents result")
+	}
+
+	l1_gas, ok := values[1].(uint64)
+	if !ok {
+		return 0, errors.New("invalid gasEstimateComponents result")
+	}
+
+	return l1_gas, nil
+}
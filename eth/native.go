@@ -46,7 +46,7 @@ func BuildTxTransfer(b *cmn.Blockchain, s *cmn.Signer, from *cmn.Address, to com
 		return nil, err
 	}
 
-	nonce, err := client.PendingNonceAt(context.Background(), from.Address)
+	nonce, err := nextNonce(b, from.Address, false)
 	if err != nil {
 		log.Error().Msgf("BuildTxTransfer: Cannot get nonce. Error:(%v)", err)
 		return nil, err
@@ -64,37 +64,13 @@ func BuildTxTransfer(b *cmn.Blockchain, s *cmn.Signer, from *cmn.Address, to com
 		return nil, err
 	}
 
-	priorityFee, err := client.SuggestGasTipCap(context.Background())
+	fee, err := GetFeePreset(b, "")
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to suggest gas tip cap")
+		log.Error().Err(err).Msg("Failed to get the fee preset")
 		return nil, err
 	}
 
-	// Get the latest block to determine the base fee
-	block, err := client.BlockByNumber(context.Background(), nil) // Get the latest block
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to get the latest block")
-		return nil, err
-	}
-
-	// Base fee is included in the block header (introduced in EIP-1559)
-	baseFee := block.BaseFee()
-	// Calculate the MaxFeePerGas based on base fee and priority fee
-	// For example, you might want to set MaxFeePerGas to be slightly higher than baseFee + priorityFee
-	maxFeePerGas := new(big.Int).Add(baseFee, priorityFee)
-	buffer := big.NewInt(2) // Set a buffer (optional) to ensure transaction gets processed
-	maxFeePerGas = maxFeePerGas.Mul(maxFeePerGas, buffer)
-
-	tx := types.NewTx(&types.DynamicFeeTx{
-		ChainID:   big.NewInt(int64(b.ChainId)),
-		Nonce:     nonce,
-		To:        &to,
-		Value:     amount,
-		Gas:       gasLimit,
-		GasFeeCap: maxFeePerGas,
-		GasTipCap: priorityFee,
-		Data:      nil,
-	})
+	tx := newTx(b, nonce, to, amount, gasLimit, nil, fee)
 
 	return tx, nil
 
@@ -110,28 +86,7 @@ func Transfer(msg *bus.Message, b *cmn.Blockchain, s *cmn.Signer, from *cmn.Addr
 		return err
 	}
 
-	res := msg.Fetch("signer", "sign-tx", &bus.B_SignerSignTx{
-		Type:      s.Type,
-		Name:      s.Name,
-		MasterKey: s.MasterKey,
-		Chain:     b.Name,
-		Tx:        tx,
-		From:      from.Address,
-		Path:      from.Path,
-	})
-
-	if res.Error != nil {
-		log.Error().Err(res.Error).Msg("Transfer: Cannot sign tx")
-		return res.Error
-	}
-
-	signedTx, ok := res.Data.(*types.Transaction)
-	if !ok {
-		log.Error().Msgf("Transfer: Cannot convert to transaction. Data:(%v)", res.Data)
-		return errors.New("cannot convert to transaction")
-	}
-
-	hash, err := SendSignedTx(signedTx)
+	hash, err := signAndSendTx(msg, b, s, from, tx)
 	if err != nil {
 		log.Error().Err(err).Msg("Transfer: Cannot send tx")
 		return err
@@ -0,0 +1,275 @@
+package eth
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"slices"
+
+	"github.com/AlexNa-Holdings/web3pro/bus"
+	"github.com/AlexNa-Holdings/web3pro/cmn"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/rs/zerolog/log"
+)
+
+// NFT discovery. The candidate tokens come from the ERC-721 Transfer and the
+// ERC-1155 TransferSingle/TransferBatch events received by the owner
+// (explorer API, eth_getLogs as fallback), the holdings are confirmed with
+// ownerOf/balanceOf in one multicall.
+
+var (
+	TOPIC_TRANSFER_SINGLE = crypto.Keccak256Hash([]byte("TransferSingle(address,address,address,uint256,uint256)"))
+	TOPIC_TRANSFER_BATCH  = crypto.Keccak256Hash([]byte("TransferBatch(address,address,address,uint256[],uint256[])"))
+)
+
+type nftKey struct {
+	contract common.Address
+	id       string
+}
+
+// DiscoverNFTs returns the ERC-721 and ERC-1155 tokens held by the owner.
+// The LP position NFTs are skipped, they are listed by the LP panes.
+func DiscoverNFTs(b *cmn.Blockchain, owner common.Address) ([]*cmn.NFT, error) {
+	list, err := findNFTs(b, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(list) == 0 {
+		return list, nil
+	}
+
+	readNFTBalances(b, owner, list)
+
+	list = slices.DeleteFunc(list, func(n *cmn.NFT) bool {
+		return n.Balance == nil || n.Balance.Sign() == 0
+	})
+
+	readNFTCollections(b, list)
+
+	slices.SortFunc(list, func(x, y *cmn.NFT) int {
+		if c := x.Contract.Cmp(y.Contract); c != 0 {
+			return c
+		}
+		return x.TokenId.Cmp(y.TokenId)
+	})
+
+	return list, nil
+}
+
+// BuildNFTTransferCall returns the safeTransferFrom calldata, the amount is
+// used by ERC-1155 only
+func BuildNFTTransferCall(n *cmn.NFT, to common.Address, amount *big.Int) ([]byte, error) {
+	if n.IsERC1155() {
+		if amount == nil || amount.Sign() <= 0 {
+			return nil, errors.New("invalid amount")
+		}
+		return ERC1155_ABI.Pack("safeTransferFrom", n.Owner, to, n.TokenId, amount, []byte{})
+	}
+
+	return ERC721_ABI.Pack("safeTransferFrom", n.Owner, to, n.TokenId)
+}
+
+// findNFTs collects the tokens the owner has ever received
+func findNFTs(b *cmn.Blockchain, owner common.Address) ([]*cmn.NFT, error) {
+	owner_topic := common.BytesToHash(owner.Bytes())
+
+	logs, scanned, err := getLogs(b, nil, []common.Hash{TOPIC_TRANSFER, {}, owner_topic})
+	if err != nil {
+		return nil, err
+	}
+	if scanned != nil {
+		log.Debug().Msgf("findNFTs: scanned only blocks %d-%d", scanned.From, scanned.To)
+	}
+
+	for _, topic := range []common.Hash{TOPIC_TRANSFER_SINGLE, TOPIC_TRANSFER_BATCH} {
+		l1155, _, err := getLogs(b, nil, []common.Hash{topic, {}, {}, owner_topic})
+		if err != nil {
+			log.Debug().Err(err).Msg("findNFTs: cannot get ERC-1155 logs")
+			continue
+		}
+		logs = append(logs, l1155...)
+	}
+
+	lp := lpPositionManagers(b.ChainId)
+
+	keys := map[nftKey]bool{}
+	list := []*cmn.NFT{}
+
+	add := func(l types.Log, id *big.Int, standard string) {
+		k := nftKey{contract: l.Address, id: id.String()}
+		if keys[k] || lp[l.Address] {
+			return
+		}
+		keys[k] = true
+
+		list = append(list, &cmn.NFT{
+			ChainId:  b.ChainId,
+			Owner:    owner,
+			Contract: l.Address,
+			TokenId:  id,
+			Standard: standard,
+		})
+	}
+
+	for _, l := range logs {
+		if len(l.Topics) != 4 { // ERC-20 Transfer has the amount in the data
+			continue
+		}
+
+		switch l.Topics[0] {
+		case TOPIC_TRANSFER:
+			add(l, l.Topics[3].Big(), cmn.NFT_ERC721)
+		case TOPIC_TRANSFER_SINGLE:
+			values, err := ERC1155_ABI.Unpack("TransferSingle", l.Data)
+			if err != nil || len(values) != 2 {
+				continue
+			}
+			if id, ok := values[0].(*big.Int); ok {
+				add(l, id, cmn.NFT_ERC1155)
+			}
+		case TOPIC_TRANSFER_BATCH:
+			values, err := ERC1155_ABI.Unpack("TransferBatch", l.Data)
+			if err != nil || len(values) != 2 {
+				continue
+			}
+			if ids, ok := values[0].([]*big.Int); ok {
+				for _, id := range ids {
+					add(l, id, cmn.NFT_ERC1155)
+				}
+			}
+		}
+	}
+
+	return list, nil
+}
+
+func lpPositionManagers(chainId int) map[common.Address]bool {
+	r := map[common.Address]bool{}
+
+	w := cmn.CurrentWallet
+	if w == nil {
+		return r
+	}
+
+	for _, p := range w.LP_V3_Providers {
+		if p.ChainId == chainId {
+			r[p.Provider] = true
+		}
+	}
+
+	for _, p := range w.LP_V4_Providers {
+		if p.ChainId == chainId {
+			r[p.Provider] = true
+		}
+	}
+
+	return r
+}
+
+// readNFTBalances sets the balance to 1 for the ERC-721 tokens still owned
+// and to balanceOf for ERC-1155
+func readNFTBalances(b *cmn.Blockchain, owner common.Address, list []*cmn.NFT) {
+	calls := []bus.B_EthMultiCall_Call{}
+
+	for _, n := range list {
+		if n.IsERC1155() {
+			data, _ := ERC1155_ABI.Pack("balanceOf", owner, n.TokenId)
+			calls = append(calls, bus.B_EthMultiCall_Call{To: n.Contract, Data: data})
+		} else {
+			data, _ := ERC721_ABI.Pack("ownerOf", n.TokenId)
+			calls = append(calls, bus.B_EthMultiCall_Call{To: n.Contract, Data: data})
+		}
+	}
+
+	results, err := multiCallResults(b, calls)
+	if err != nil {
+		// burned tokens revert ownerOf and the whole aggregate with it
+		log.Debug().Err(err).Str("chain", b.GetShortName()).Msg("readNFTBalances: multicall failed, falling back to individual calls")
+		results = individualCallResults(b, calls)
+	}
+
+	for i, n := range list {
+		r := results[i]
+		if len(r) == 0 {
+			continue
+		}
+
+		if n.IsERC1155() {
+			if values, err := ERC1155_ABI.Unpack("balanceOf", r); err == nil && len(values) == 1 {
+				n.Balance, _ = values[0].(*big.Int)
+			}
+		} else if values, err := ERC721_ABI.Unpack("ownerOf", r); err == nil && len(values) == 1 {
+			if a, ok := values[0].(common.Address); ok && a == owner {
+				n.Balance = big.NewInt(1)
+			}
+		}
+	}
+}
+
+// readNFTCollections sets the collection names, ERC-1155 name() is optional
+func readNFTCollections(b *cmn.Blockchain, list []*cmn.NFT) {
+	contracts := []common.Address{}
+	for _, n := range list {
+		if !slices.Contains(contracts, n.Contract) {
+			contracts = append(contracts, n.Contract)
+		}
+	}
+
+	calls := []bus.B_EthMultiCall_Call{}
+	for _, c := range contracts {
+		data, _ := ERC721_ABI.Pack("name")
+		calls = append(calls, bus.B_EthMultiCall_Call{To: c, Data: data})
+	}
+
+	results, err := multiCallResults(b, calls)
+	if err != nil {
+		log.Debug().Err(err).Str("chain", b.GetShortName()).Msg("readNFTCollections: multicall failed, falling back to individual calls")
+		results = individualCallResults(b, calls)
+	}
+
+	names := map[common.Address]string{}
+	for i, c := range contracts {
+		if len(results[i]) == 0 {
+			continue
+		}
+		if values, err := ERC721_ABI.Unpack("name", results[i]); err == nil && len(values) == 1 {
+			names[c], _ = values[0].(string)
+		}
+	}
+
+	for _, n := range list {
+		n.Collection = names[n.Contract]
+	}
+}
+
+// buildRiskDetails warns about the calls giving away control over all the
+// tokens of a collection
+func buildRiskDetails(b *cmn.Blockchain, to common.Address, data []byte) string {
+	if len(data) < 4 || !bytes.Equal(data[:4], cmn.SELECTOR_SET_APPROVAL_FOR_ALL) {
+		return ""
+	}
+
+	values, err := ERC721_ABI.Methods["setApprovalForAll"].Inputs.Unpack(data[4:])
+	if err != nil || len(values) != 2 {
+		return ""
+	}
+
+	operator, _ := values[0].(common.Address)
+	if approved, _ := values[1].(bool); !approved {
+		return ""
+	}
+
+	name := addressName(b, operator)
+	if name != "" {
+		name = " " + name
+	}
+
+	return `
+<c><blink><color fg:red>HIGH RISK</color></blink>
+<c>setApprovalForAll lets ` + cmn.TagAddressShortLink(operator) + name + `
+<c>move ALL your tokens of ` + cmn.TagAddressShortLink(to) + `
+`
+}
@@ -0,0 +1,251 @@
+package eth
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/AlexNa-Holdings/web3pro/cmn"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Local nonce manager. The node's pending nonce lags behind quick sends (or
+// comes from another node behind a load balancer), so the nonces handed out
+// to the transactions being signed and the broadcast ones are tracked per
+// (chain, address) until they are mined.
+
+const (
+	NONCE_RESERVED = "reserved" // being signed
+	NONCE_SENT     = "sent"     // broadcast, not mined yet
+)
+
+// a reserved nonce is released if the tx is not sent in time
+const NONCE_RESERVE_TIMEOUT = 10 * time.Minute
+
+// a sent tx unknown to the node after this time is considered dropped
+const NONCE_DROP_TIMEOUT = 5 * time.Minute
+
+type NonceSlot struct {
+	Nonce uint64
+	State string
+	Hash  common.Hash
+	Time  time.Time
+}
+
+type NonceQueue struct {
+	ChainId int
+	Address common.Address
+	Slots   []*NonceSlot // sorted by nonce
+}
+
+type nonceKey struct {
+	chainId int
+	address common.Address
+}
+
+var nonceQueues = map[nonceKey]*NonceQueue{}
+var nonceMutex sync.Mutex
+
+// GetChainNonces returns the nonce of the next mined tx and the node's
+// pending nonce
+func GetChainNonces(b *cmn.Blockchain, address common.Address) (uint64, uint64, error) {
+	client, err := getEthClient(b)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	mined, err := client.NonceAt(context.Background(), address, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	pending, err := client.PendingNonceAt(context.Background(), address)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return mined, max(mined, pending), nil
+}
+
+// nextNonce returns the first nonce that is free both on the node and
+// locally. With reserve the nonce is taken until it is sent or released.
+func nextNonce(b *cmn.Blockchain, address common.Address, reserve bool) (uint64, error) {
+	mined, pending, err := GetChainNonces(b, address)
+	if err != nil {
+		return 0, err
+	}
+
+	nonceMutex.Lock()
+	defer nonceMutex.Unlock()
+
+	q := getNonceQueue(b.ChainId, address)
+	q.prune(mined, pending)
+
+	n := pending
+	for q.slot(n) != nil {
+		n++
+	}
+
+	if reserve {
+		q.set(&NonceSlot{Nonce: n, State: NONCE_RESERVED, Time: time.Now()})
+	}
+
+	return n, nil
+}
+
+// reserveNonce takes the next free nonce for the transaction about to be
+// signed. The transaction is rebuilt if its nonce is taken meanwhile.
+func reserveNonce(b *cmn.Blockchain, from common.Address, tx *types.Transaction) (*types.Transaction, error) {
+	n, err := nextNonce(b, from, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if n != tx.Nonce() {
+		log.Debug().Msgf("reserveNonce: nonce %d is taken, using %d", tx.Nonce(), n)
+		tx = withNonce(tx, n)
+	}
+	return tx, nil
+}
+
+// releaseNonce returns the reserved nonce of a transaction that was not
+// sent. The chain is given, an unsigned legacy tx has no chain id.
+func releaseNonce(chainId int, from common.Address, tx *types.Transaction) {
+	nonceMutex.Lock()
+	defer nonceMutex.Unlock()
+
+	q, ok := nonceQueues[nonceKey{chainId, from}]
+	if !ok {
+		return
+	}
+
+	if s := q.slot(tx.Nonce()); s != nil && s.State == NONCE_RESERVED {
+		q.remove(tx.Nonce())
+	}
+}
+
+// nonceSent marks the nonce as used by the broadcast transaction. A
+// replacement takes over the slot of the original.
+func nonceSent(chainId int, from common.Address, tx *types.Transaction) {
+	nonceMutex.Lock()
+	defer nonceMutex.Unlock()
+
+	q := getNonceQueue(chainId, from)
+	q.set(&NonceSlot{Nonce: tx.Nonce(), State: NONCE_SENT, Hash: tx.Hash(), Time: time.Now()})
+}
+
+// NonceQueues returns a copy of the queues that have in-flight nonces
+func NonceQueues() []*NonceQueue {
+	nonceMutex.Lock()
+	defer nonceMutex.Unlock()
+
+	list := []*NonceQueue{}
+	for _, q := range nonceQueues {
+		if len(q.Slots) == 0 {
+			continue
+		}
+
+		c := &NonceQueue{ChainId: q.ChainId, Address: q.Address}
+		for _, s := range q.Slots {
+			slot := *s
+			c.Slots = append(c.Slots, &slot)
+		}
+		list = append(list, c)
+	}
+
+	slices.SortFunc(list, func(a, b *NonceQueue) int {
+		if a.ChainId != b.ChainId {
+			return cmp.Compare(a.ChainId, b.ChainId)
+		}
+		return a.Address.Cmp(b.Address)
+	})
+	return list
+}
+
+// ResyncNonces drops the local queue, so the next nonce comes from the chain
+func ResyncNonces(chainId int, address common.Address) {
+	nonceMutex.Lock()
+	defer nonceMutex.Unlock()
+
+	delete(nonceQueues, nonceKey{chainId, address})
+}
+
+func getNonceQueue(chainId int, address common.Address) *NonceQueue {
+	k := nonceKey{chainId, address}
+	q, ok := nonceQueues[k]
+	if !ok {
+		q = &NonceQueue{ChainId: chainId, Address: address}
+		nonceQueues[k] = q
+	}
+	return q
+}
+
+// prune removes the mined nonces, the expired reservations and the sent
+// transactions the node has forgotten
+func (q *NonceQueue) prune(mined, pending uint64) {
+	q.Slots = slices.DeleteFunc(q.Slots, func(s *NonceSlot) bool {
+		switch {
+		case s.Nonce < mined:
+			return true
+		case s.State == NONCE_RESERVED:
+			return time.Since(s.Time) > NONCE_RESERVE_TIMEOUT
+		default:
+			if s.Nonce >= pending && time.Since(s.Time) > NONCE_DROP_TIMEOUT {
+				log.Warn().Msgf("Transaction %s with nonce %d was dropped", s.Hash.Hex(), s.Nonce)
+				return true
+			}
+			return false
+		}
+	})
+}
+
+func (q *NonceQueue) slot(nonce uint64) *NonceSlot {
+	for _, s := range q.Slots {
+		if s.Nonce == nonce {
+			return s
+		}
+	}
+	return nil
+}
+
+func (q *NonceQueue) set(slot *NonceSlot) {
+	q.remove(slot.Nonce)
+	q.Slots = append(q.Slots, slot)
+	slices.SortFunc(q.Slots, func(a, b *NonceSlot) int {
+		return cmp.Compare(a.Nonce, b.Nonce)
+	})
+}
+
+func (q *NonceQueue) remove(nonce uint64) {
+	q.Slots = slices.DeleteFunc(q.Slots, func(s *NonceSlot) bool { return s.Nonce == nonce })
+}
+
+// withNonce returns a copy of the unsigned transaction with another nonce
+func withNonce(tx *types.Transaction, nonce uint64) *types.Transaction {
+	if tx.Type() == types.LegacyTxType {
+		return types.NewTx(&types.LegacyTx{
+			Nonce:    nonce,
+			GasPrice: tx.GasPrice(),
+			Gas:      tx.Gas(),
+			To:       tx.To(),
+			Value:    tx.Value(),
+			Data:     tx.Data(),
+		})
+	}
+
+	return types.NewTx(&types.DynamicFeeTx{
+		ChainID:    tx.ChainId(),
+		Nonce:      nonce,
+		GasTipCap:  tx.GasTipCap(),
+		GasFeeCap:  tx.GasFeeCap(),
+		Gas:        tx.Gas(),
+		To:         tx.To(),
+		Value:      tx.Value(),
+		Data:       tx.Data(),
+		AccessList: tx.AccessList(),
+	})
+}
@@ -0,0 +1,181 @@
+package eth
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/AlexNa-Holdings/web3pro/cmn"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const NONCE_TEST_CHAIN = 424242
+const NONCE_TEST_MINED = 5
+
+// nonceNode reports NONCE_TEST_MINED as the mined and pending nonce and
+// rejects every transaction
+type nonceNode struct{}
+
+func (n *nonceNode) GetTransactionCount(a common.Address, block string) hexutil.Uint64 {
+	return NONCE_TEST_MINED
+}
+
+func (n *nonceNode) SendRawTransaction(data hexutil.Bytes) (common.Hash, error) {
+	return common.Hash{}, errors.New("insufficient funds for gas * price + value")
+}
+
+func startNonceNode(t *testing.T) *cmn.Blockchain {
+	t.Helper()
+
+	srv := rpc.NewServer()
+	if err := srv.RegisterName("eth", &nonceNode{}); err != nil {
+		t.Fatal(err)
+	}
+	client := ethclient.NewClient(rpc.DialInProc(srv))
+
+	consMutex.Lock()
+	cons[NONCE_TEST_CHAIN] = &con{client, "inproc"}
+	consMutex.Unlock()
+
+	t.Cleanup(func() {
+		consMutex.Lock()
+		delete(cons, NONCE_TEST_CHAIN)
+		consMutex.Unlock()
+		client.Close()
+		srv.Stop()
+	})
+
+	return &cmn.Blockchain{Name: "Nonce Test", ChainId: NONCE_TEST_CHAIN}
+}
+
+func nonceTestKey(t *testing.T) (*ecdsa.PrivateKey, common.Address) {
+	t.Helper()
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	t.Cleanup(func() { ResyncNonces(NONCE_TEST_CHAIN, from) })
+	return key, from
+}
+
+// reserveTestTx reserves a nonce for a transfer
+func reserveTestTx(t *testing.T, b *cmn.Blockchain, from common.Address) *types.Transaction {
+	to := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	tx := types.NewTx(&types.DynamicFeeTx{
+		ChainID:   big.NewInt(NONCE_TEST_CHAIN),
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(2),
+		Gas:       21000,
+		To:        &to,
+		Value:     big.NewInt(1),
+	})
+
+	tx, err := reserveNonce(b, from, tx)
+	if err != nil {
+		t.Error(err)
+		return nil
+	}
+	return tx
+}
+
+// failSend signs the transaction and fails to broadcast it
+func failSend(t *testing.T, key *ecdsa.PrivateKey, tx *types.Transaction) {
+	signed, err := types.SignTx(tx, types.LatestSignerForChainID(big.NewInt(NONCE_TEST_CHAIN)), key)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if _, err := sendSignedTx(signed, ""); err == nil {
+		t.Error("the stand-in node accepted the transaction")
+	}
+}
+
+func TestNonceReleasedOnce(t *testing.T) {
+	b := startNonceNode(t)
+	key, from := nonceTestKey(t)
+
+	tx := reserveTestTx(t, b, from)
+	if tx == nil {
+		t.FailNow()
+	}
+	failSend(t, key, tx)
+	if tx.Nonce() != NONCE_TEST_MINED {
+		t.Fatalf("nonce %d, want %d", tx.Nonce(), NONCE_TEST_MINED)
+	}
+
+	// another send reserves before the failed one is released
+	other, err := nextNonce(b, from, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == tx.Nonce() {
+		t.Fatalf("nonce %d reserved while the failed send still holds it", other)
+	}
+
+	releaseNonce(b.ChainId, from, tx)
+
+	next, err := nextNonce(b, from, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next == other {
+		t.Fatalf("nonce %d handed out twice", next)
+	}
+	if next != tx.Nonce() {
+		t.Errorf("released nonce %d not reused, got %d", tx.Nonce(), next)
+	}
+}
+
+func TestNonceConcurrentFailedSends(t *testing.T) {
+	b := startNonceNode(t)
+	key, from := nonceTestKey(t)
+
+	var mu sync.Mutex
+	held := map[uint64]bool{}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 2; j++ {
+				tx := reserveTestTx(t, b, from)
+				if tx == nil {
+					return
+				}
+
+				mu.Lock()
+				if held[tx.Nonce()] {
+					t.Errorf("nonce %d reserved twice", tx.Nonce())
+				}
+				held[tx.Nonce()] = true
+				mu.Unlock()
+
+				failSend(t, key, tx)
+
+				mu.Lock()
+				delete(held, tx.Nonce())
+				mu.Unlock()
+				releaseNonce(b.ChainId, from, tx)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, q := range NonceQueues() {
+		if q.ChainId == NONCE_TEST_CHAIN && q.Address == from {
+			t.Errorf("%d nonces still reserved", len(q.Slots))
+		}
+	}
+}
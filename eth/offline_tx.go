@@ -0,0 +1,315 @@
+package eth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/AlexNa-Holdings/web3pro/bus"
+	"github.com/AlexNa-Holdings/web3pro/cmn"
+	"github.com/AlexNa-Holdings/web3pro/gocui"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Air-gapped signing. The online instance fills the nonce, gas and fees and
+// writes the transaction to a file (tx export-unsigned), the offline instance
+// (config offline) signs it with the signer of the address (tx sign-file),
+// the online instance broadcasts the result (tx broadcast-file).
+
+const OFFLINE_TX_VERSION = 1
+
+type OfflineTx struct {
+	Version  int            `json:"version"`
+	ChainId  int            `json:"chain_id"`
+	From     common.Address `json:"from"`
+	Unsigned hexutil.Bytes  `json:"unsigned"`         // binary (RLP) encoding
+	Signed   hexutil.Bytes  `json:"signed,omitempty"` // set by tx sign-file
+}
+
+// ExportUnsignedTx builds the transaction with the chain default fee, the
+// address may be watch-only
+func ExportUnsignedTx(b *cmn.Blockchain, from common.Address, to common.Address,
+	amount *big.Int, data []byte) (*OfflineTx, *types.Transaction, error) {
+
+	tx, err := buildUnsignedTx(b, from, to, amount, data, nil, "")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	unsigned, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &OfflineTx{
+		Version:  OFFLINE_TX_VERSION,
+		ChainId:  b.ChainId,
+		From:     from,
+		Unsigned: unsigned,
+	}, tx, nil
+}
+
+func ReadOfflineTx(file string) (*OfflineTx, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	o := &OfflineTx{}
+	if err := json.Unmarshal(data, o); err != nil {
+		return nil, fmt.Errorf("invalid transaction file: %v", err)
+	}
+
+	if o.Version != OFFLINE_TX_VERSION {
+		return nil, fmt.Errorf("unsupported transaction file version: %d", o.Version)
+	}
+
+	if len(o.Unsigned) == 0 {
+		return nil, errors.New("no transaction in the file")
+	}
+
+	return o, nil
+}
+
+func (o *OfflineTx) Save(file string) error {
+	data, err := json.MarshalIndent(o, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(file, data, 0644)
+}
+
+func (o *OfflineTx) UnsignedTx() (*types.Transaction, error) {
+	tx := &types.Transaction{}
+	if err := tx.UnmarshalBinary(o.Unsigned); err != nil {
+		return nil, fmt.Errorf("invalid unsigned transaction: %v", err)
+	}
+
+	if tx.To() == nil {
+		return nil, errors.New("contract creation is not supported")
+	}
+
+	// the legacy transactions get the chain id from the signature
+	if tx.Type() != types.LegacyTxType && tx.ChainId().Cmp(big.NewInt(int64(o.ChainId))) != 0 {
+		return nil, fmt.Errorf("the transaction is for chain %v, the file is for chain %d", tx.ChainId(), o.ChainId)
+	}
+
+	return tx, nil
+}
+
+// SignedTx checks the signed transaction is the exported one, signed by
+// the address of the file
+func (o *OfflineTx) SignedTx() (*types.Transaction, error) {
+	if len(o.Signed) == 0 {
+		return nil, errors.New("the transaction is not signed, run tx sign-file on the offline wallet")
+	}
+
+	unsigned, err := o.UnsignedTx()
+	if err != nil {
+		return nil, err
+	}
+
+	tx := &types.Transaction{}
+	if err := tx.UnmarshalBinary(o.Signed); err != nil {
+		return nil, fmt.Errorf("invalid signed transaction: %v", err)
+	}
+
+	signer := types.LatestSignerForChainID(big.NewInt(int64(o.ChainId)))
+	if signer.Hash(tx) != signer.Hash(unsigned) {
+		return nil, errors.New("the signed transaction differs from the unsigned one")
+	}
+
+	from, err := types.Sender(signer, tx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot recover the sender: %v", err)
+	}
+
+	if from != o.From {
+		return nil, fmt.Errorf("signed by %s, expected %s", from.Hex(), o.From.Hex())
+	}
+
+	return tx, nil
+}
+
+// BroadcastOfflineTx sends the signed transaction and records it in the
+// history
+func BroadcastOfflineTx(o *OfflineTx) (string, error) {
+	tx, err := o.SignedTx()
+	if err != nil {
+		return "", err
+	}
+
+	return SendSignedTx(tx)
+}
+
+// signTxFile shows the transaction of the file and signs it, no network
+// access is needed
+func signTxFile(msg *bus.Message) (string, error) {
+	req, ok := msg.Data.(*bus.B_EthSignTxFile)
+	if !ok {
+		return "", fmt.Errorf("invalid request: %v", msg.Data)
+	}
+
+	w := cmn.CurrentWallet
+	if w == nil {
+		return "", errors.New("no wallet")
+	}
+
+	o, err := ReadOfflineTx(req.File)
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := o.UnsignedTx()
+	if err != nil {
+		return "", err
+	}
+
+	b := w.GetBlockchain(o.ChainId)
+	if b == nil {
+		return "", fmt.Errorf("blockchain not found: %d", o.ChainId)
+	}
+
+	from := w.GetAddress(o.From)
+	if from == nil {
+		return "", fmt.Errorf("address not found: %s", o.From.Hex())
+	}
+
+	signer := w.GetSigner(from.Signer)
+	if signer == nil {
+		return "", fmt.Errorf("no signer for %s", from.Name)
+	}
+
+	policy_req := newPolicyRequest(b.ChainId, from.Address, *tx.To(), tx.Value(), tx.Data())
+	if err := enforcePolicies(msg, w.CheckPolicies(policy_req)); err != nil {
+		return "", err
+	}
+
+	out := req.Out
+	if out == "" {
+		out = req.File
+	}
+
+	hash := ""
+	err = errors.New("rejected")
+
+	msg.Fetch("ui", "hail", &bus.B_Hail{
+		Title:    "Sign Tx File",
+		Template: buildSignTxFileTemplate(b, from, signer, tx),
+		OnOk: func(m *bus.Message, v *gocui.View) bool {
+			sign_res := msg.Fetch("signer", "sign-tx", &bus.B_SignerSignTx{
+				Type:      signer.Type,
+				Name:      signer.Name,
+				MasterKey: signer.MasterKey,
+				Chain:     b.Name,
+				Tx:        tx,
+				From:      from.Address,
+				Path:      from.Path,
+			})
+
+			if sign_res.Error != nil {
+				err = fmt.Errorf("error signing transaction: %v", sign_res.Error)
+				bus.Send("ui", "notify-error", err.Error())
+				return false
+			}
+
+			signedTx, ok := sign_res.Data.(*types.Transaction)
+			if !ok {
+				log.Error().Msgf("signTxFile: Cannot convert to transaction. Data:(%v)", sign_res.Data)
+				err = errors.New("cannot convert to transaction")
+				return false
+			}
+
+			o.Signed, err = signedTx.MarshalBinary()
+			if err != nil {
+				return false
+			}
+
+			if _, err = o.SignedTx(); err != nil {
+				bus.Send("ui", "notify-error", fmt.Sprintf("Error: %v", err))
+				return false
+			}
+
+			if err = o.Save(out); err != nil {
+				bus.Send("ui", "notify-error", fmt.Sprintf("Error: %v", err))
+				return false
+			}
+
+			recordPolicySpend(policy_req)
+			hash = signedTx.Hash().Hex()
+			return true
+		},
+		OnCancel: func(m *bus.Message) {
+			bus.Send("timer", "trigger", m.TimerID) // to cancel all nested operations
+		},
+		OnOverHotspot: func(m *bus.Message, v *gocui.View, hs *gocui.Hotspot) {
+			cmn.StandardOnOverHotspot(v, hs)
+		},
+		OnClickHotspot: func(m *bus.Message, v *gocui.View, hs *gocui.Hotspot) {
+			cmn.StandardOnClickHotspot(v, hs)
+		},
+	})
+
+	if hash == "" {
+		return "", err
+	}
+
+	bus.Send("ui", "notify", "Transaction signed: "+out)
+	bus.Send("ui", "command", "tx qr '"+out+"'")
+
+	return hash, nil
+}
+
+// buildSignTxFileTemplate shows what is known offline: no simulation, no
+// balances and no fee estimation
+func buildSignTxFileTemplate(b *cmn.Blockchain, from *cmn.Address, s *cmn.Signer, tx *types.Transaction) string {
+	w := cmn.CurrentWallet
+	to := *tx.To()
+
+	// the native token may be missing in the offline wallet
+	amount := func(v *big.Int) string {
+		return v.String() + " wei"
+	}
+	if nt, err := w.GetNativeToken(b); err == nil {
+		amount = func(v *big.Int) string {
+			return cmn.TagValueSymbolLink(v, nt)
+		}
+	}
+
+	fees := `
+   Gas Price: ` + amount(tx.GasPrice())
+	if tx.Type() == types.DynamicFeeTxType {
+		fees = `
+     Max Fee: ` + amount(tx.GasFeeCap()) + `
+    Priority: ` + amount(tx.GasTipCap())
+	}
+
+	max_fee := new(big.Int).Mul(tx.GasFeeCap(), new(big.Int).SetUint64(tx.Gas()))
+
+	to_name := ""
+	if a := w.GetAddress(to); a != nil {
+		to_name = " " + a.Name
+	} else if c := w.GetContract(to); c != nil {
+		to_name = " " + c.Name
+	}
+
+	return `  Blockchain: ` + b.Name + `
+        From: ` + cmn.TagAddressShortLink(from.Address) + " " + from.Name + `
+          To: ` + cmn.TagAddressShortLink(to) + to_name + `
+       Value: ` + amount(tx.Value()) + `
+      Signer: ` + s.Name + " (" + s.Type + ")" + `
+<line text:Call>` + buildCallDetails(b, tx, to) + buildRiskDetails(b, to, tx.Data()) + `
+<line text:Fee>
+       Nonce: ` + cmn.TagUint64Link(tx.Nonce()) + `
+   Gas Limit: ` + cmn.TagUint64Link(tx.Gas()) + fees + `
+   Total Fee: ` + amount(max_fee) + ` (max)
+<c>
+<button text:Sign id:ok bgcolor:g.HelpBgColor color:g.HelpFgColor tip:"sign the transaction">  ` +
+		`<button text:Reject id:cancel bgcolor:g.ErrorFgColor tip:"reject transaction">`
+}
@@ -0,0 +1,93 @@
+package eth
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/AlexNa-Holdings/web3pro/bus"
+	"github.com/AlexNa-Holdings/web3pro/cmn"
+	"github.com/AlexNa-Holdings/web3pro/gocui"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/rs/zerolog/log"
+)
+
+// enforcePolicies rejects the request if a blocking policy is violated,
+// otherwise asks the user to override the violations. It must be called
+// before the signing hail opens.
+func enforcePolicies(msg *bus.Message, violations []cmn.PolicyViolation) error {
+	if len(violations) == 0 {
+		return nil
+	}
+
+	reasons := []string{}
+	blocked := false
+	for _, v := range violations {
+		reasons = append(reasons, v.Reason)
+		if v.Policy.Mode == cmn.POLICY_MODE_BLOCK {
+			blocked = true
+		}
+	}
+
+	if blocked {
+		err := errors.New("blocked by policy: " + strings.Join(reasons, "; "))
+		log.Warn().Msg(err.Error())
+		bus.Send("ui", "notify-error", err.Error())
+		return err
+	}
+
+	text := ""
+	for _, r := range reasons {
+		text += " " + cmn.ICON_ALERT + r + "\n"
+	}
+
+	override := false
+	msg.Fetch("ui", "hail", &bus.B_Hail{
+		Title: "Policy Violation",
+		Template: `<c><color fg:red>The request violates the signing policies</color>
+
+` + text + `
+<c><button text:Override id:ok bgcolor:g.ErrorFgColor tip:"ignore the policies this time">  ` +
+			`<button text:Reject id:cancel bgcolor:g.HelpBgColor color:g.HelpFgColor tip:"reject the request">`,
+		OnOk: func(m *bus.Message, v *gocui.View) bool {
+			override = true
+			return true
+		},
+		OnCancel: func(m *bus.Message) {
+			bus.Send("timer", "trigger", m.TimerID) // to cancel all nested operations
+		},
+		OnOverHotspot: func(m *bus.Message, v *gocui.View, hs *gocui.Hotspot) {
+			cmn.StandardOnOverHotspot(v, hs)
+		},
+		OnClickHotspot: func(m *bus.Message, v *gocui.View, hs *gocui.Hotspot) {
+			cmn.StandardOnClickHotspot(v, hs)
+		},
+	})
+
+	if !override {
+		return errors.New("rejected by policy")
+	}
+
+	log.Warn().Msgf("Policy violations overridden: %s", strings.Join(reasons, "; "))
+	return nil
+}
+
+// newPolicyRequest builds the request with the calls nested in the data
+func newPolicyRequest(chainId int, from common.Address, to common.Address, value *big.Int, data []byte) *cmn.PolicyRequest {
+	return &cmn.PolicyRequest{
+		ChainId: chainId,
+		From:    from,
+		To:      to,
+		Value:   value,
+		Data:    data,
+		Nested:  NestedCalls(to, data),
+	}
+}
+
+func recordPolicySpend(r *cmn.PolicyRequest) {
+	if w := cmn.CurrentWallet; w != nil {
+		if err := w.RecordPolicySpend(r); err != nil {
+			log.Error().Err(err).Msg("Error recording policy spend")
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/AlexNa-Holdings/web3pro/cmn"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/rs/zerolog/log"
+)
+
+// Balance-change preview. The call is traced with debug_traceCall and the
+// callTracer; nodes without the debug API fall back to eth_simulateV1 with
+// traceTransfers, which reports native transfers as ERC-20 like logs.
+
+var (
+	TOPIC_TRANSFER         = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+	TOPIC_APPROVAL         = crypto.Keccak256Hash([]byte("Approval(address,address,uint256)"))
+	TOPIC_APPROVAL_FOR_ALL = crypto.Keccak256Hash([]byte("ApprovalForAll(address,address,bool)"))
+
+	// pseudo contract of the native transfers reported by eth_simulateV1
+	SIMULATE_NATIVE_TOKEN = common.HexToAddress("0xEeeeeEeeeEeEeeEeEeEeeEEEeeeeEeeeeeeeEEeE")
+)
+
+type TokenDelta struct {
+	Token  common.Address
+	Native bool
+	Delta  *big.Int
+}
+
+type TokenApproval struct {
+	Token   common.Address
+	Spender common.Address
+	Amount  *big.Int // nil for ApprovalForAll
+	All     bool     // ApprovalForAll state
+}
+
+type TxPreview struct {
+	Source    string // "trace" or "simulation"
+	Deltas    []*TokenDelta
+	Approvals []*TokenApproval
+}
+
+type previewCallArgs struct {
+	From  common.Address `json:"from"`
+	To    common.Address `json:"to"`
+	Value *hexutil.Big   `json:"value"`
+	Input hexutil.Bytes  `json:"input"`
+}
+
+type previewLog struct {
+	Address common.Address `json:"address"`
+	Topics  []common.Hash  `json:"topics"`
+	Data    hexutil.Bytes  `json:"data"`
+}
+
+type callFrame struct {
+	Type  string          `json:"type"`
+	From  common.Address  `json:"from"`
+	To    *common.Address `json:"to"`
+	Value *hexutil.Big    `json:"value"`
+	Error string          `json:"error"`
+	Calls []*callFrame    `json:"calls"`
+	Logs  []*previewLog   `json:"logs"`
+}
+
+type simulatedCall struct {
+	Status hexutil.Uint64 `json:"status"`
+	Logs   []*previewLog  `json:"logs"`
+	Error  *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type simulatedBlock struct {
+	Calls []*simulatedCall `json:"calls"`
+}
+
+// PreviewTx returns the expected balance changes and approvals of from
+func PreviewTx(b *cmn.Blockchain, from common.Address, to common.Address, amount *big.Int, data []byte) (*TxPreview, error) {
+	client, err := getEthClient(b)
+	if err != nil {
+		return nil, err
+	}
+
+	return previewTx(client.Client(), from, to, amount, data)
+}
+
+func previewTx(rc *rpc.Client, from common.Address, to common.Address, amount *big.Int, data []byte) (*TxPreview, error) {
+	if amount == nil {
+		amount = big.NewInt(0)
+	}
+
+	args := previewCallArgs{From: from, To: to, Value: (*hexutil.Big)(amount), Input: data}
+
+	p, err := previewByTrace(rc, args)
+	if err == nil {
+		return p, nil
+	}
+	log.Debug().Err(err).Msg("previewTx: trace is not available, simulating")
+
+	return previewBySimulation(rc, args)
+}
+
+func previewByTrace(rc *rpc.Client, args previewCallArgs) (*TxPreview, error) {
+	var frame callFrame
+	err := rc.CallContext(context.Background(), &frame, "debug_traceCall", args, "latest", map[string]interface{}{
+		"tracer":       "callTracer",
+		"tracerConfig": map[string]interface{}{"withLog": true},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if frame.Error != "" {
+		return nil, errors.New(frame.Error)
+	}
+
+	pb := newPreviewBuilder(args.From, "trace")
+	pb.addFrame(&frame)
+	return pb.preview(), nil
+}
+
+func previewBySimulation(rc *rpc.Client, args previewCallArgs) (*TxPreview, error) {
+	var blocks []*simulatedBlock
+	err := rc.CallContext(context.Background(), &blocks, "eth_simulateV1", map[string]interface{}{
+		"blockStateCalls": []interface{}{
+			map[string]interface{}{"calls": []previewCallArgs{args}},
+		},
+		"traceTransfers": true,
+	}, "latest")
+	if err != nil {
+		return nil, err
+	}
+
+	if len(blocks) == 0 || len(blocks[0].Calls) == 0 {
+		return nil, errors.New("empty simulation result")
+	}
+
+	call := blocks[0].Calls[0]
+	if call.Error != nil {
+		return nil, errors.New(call.Error.Message)
+	}
+
+	pb := newPreviewBuilder(args.From, "simulation")
+	for _, l := range call.Logs {
+		pb.addLog(l)
+	}
+	return pb.preview(), nil
+}
+
+type previewBuilder struct {
+	from    common.Address
+	p       *TxPreview
+	deltas  map[common.Address]*TokenDelta // native under the zero address
+	allowed map[[2]common.Address]*TokenApproval
+}
+
+func newPreviewBuilder(from common.Address, source string) *previewBuilder {
+	return &previewBuilder{
+		from:    from,
+		p:       &TxPreview{Source: source},
+		deltas:  map[common.Address]*TokenDelta{},
+		allowed: map[[2]common.Address]*TokenApproval{},
+	}
+}
+
+// addFrame collects native value moves and logs of the successful calls
+func (pb *previewBuilder) addFrame(f *callFrame) {
+	if f.Error != "" {
+		return // reverted, including the nested calls
+	}
+
+	// a delegate call reports the value of the parent frame
+	if f.Value != nil && f.To != nil && f.Type != "DELEGATECALL" && f.Type != "CALLCODE" {
+		if f.From == pb.from {
+			pb.addDelta(common.Address{}, true, new(big.Int).Neg(f.Value.ToInt()))
+		}
+		if *f.To == pb.from {
+			pb.addDelta(common.Address{}, true, f.Value.ToInt())
+		}
+	}
+
+	for _, l := range f.Logs {
+		pb.addLog(l)
+	}
+
+	for _, c := range f.Calls {
+		pb.addFrame(c)
+	}
+}
+
+func (pb *previewBuilder) addLog(l *previewLog) {
+	if len(l.Topics) == 0 {
+		return
+	}
+
+	switch l.Topics[0] {
+	case TOPIC_TRANSFER:
+		if len(l.Topics) != 3 || len(l.Data) != 32 {
+			return // not ERC-20
+		}
+
+		native := l.Address == SIMULATE_NATIVE_TOKEN
+		token := l.Address
+		if native {
+			token = common.Address{}
+		}
+
+		value := new(big.Int).SetBytes(l.Data)
+		if common.BytesToAddress(l.Topics[1].Bytes()) == pb.from {
+			pb.addDelta(token, native, new(big.Int).Neg(value))
+		}
+		if common.BytesToAddress(l.Topics[2].Bytes()) == pb.from {
+			pb.addDelta(token, native, value)
+		}
+	case TOPIC_APPROVAL:
+		if len(l.Topics) != 3 || len(l.Data) != 32 ||
+			common.BytesToAddress(l.Topics[1].Bytes()) != pb.from {
+			return
+		}
+
+		spender := common.BytesToAddress(l.Topics[2].Bytes())
+		pb.setApproval(&TokenApproval{Token: l.Address, Spender: spender, Amount: new(big.Int).SetBytes(l.Data)})
+	case TOPIC_APPROVAL_FOR_ALL:
+		if len(l.Topics) != 3 || len(l.Data) != 32 ||
+			common.BytesToAddress(l.Topics[1].Bytes()) != pb.from {
+			return
+		}
+
+		operator := common.BytesToAddress(l.Topics[2].Bytes())
+		pb.setApproval(&TokenApproval{Token: l.Address, Spender: operator, All: new(big.Int).SetBytes(l.Data).Sign() != 0})
+	}
+}
+
+func (pb *previewBuilder) addDelta(token common.Address, native bool, delta *big.Int) {
+	d, ok := pb.deltas[token]
+	if !ok {
+		d = &TokenDelta{Token: token, Native: native, Delta: big.NewInt(0)}
+		pb.deltas[token] = d
+		pb.p.Deltas = append(pb.p.Deltas, d)
+	}
+	d.Delta.Add(d.Delta, delta)
+}
+
+// setApproval keeps the last approval of the token to the spender
+func (pb *previewBuilder) setApproval(a *TokenApproval) {
+	k := [2]common.Address{a.Token, a.Spender}
+	if prev, ok := pb.allowed[k]; ok {
+		*prev = *a
+		return
+	}
+	pb.allowed[k] = a
+	pb.p.Approvals = append(pb.p.Approvals, a)
+}
+
+// preview drops the deltas that net to zero
+func (pb *previewBuilder) preview() *TxPreview {
+	deltas := []*TokenDelta{}
+	for _, d := range pb.p.Deltas {
+		if d.Delta.Sign() != 0 {
+			deltas = append(deltas, d)
+		}
+	}
+	pb.p.Deltas = deltas
+	return pb.p
+}
@@ -0,0 +1,264 @@
+package eth
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// the addresses of the canned traces
+const (
+	previewUser   = "0x1000000000000000000000000000000000000001"
+	previewRouter = "0x2000000000000000000000000000000000000002"
+	previewToken  = "0x3000000000000000000000000000000000000003"
+	previewPool   = "0x4000000000000000000000000000000000000004"
+)
+
+// previewJSON replaces USER, ROUTER, TOKEN and POOL with the addresses and
+// their 32 byte topics (USER32, ...)
+func previewJSON(s string) string {
+	r := strings.NewReplacer(
+		"USER32", common.HexToHash(previewUser).Hex(),
+		"ROUTER32", common.HexToHash(previewRouter).Hex(),
+		"POOL32", common.HexToHash(previewPool).Hex(),
+		"TRANSFER", TOPIC_TRANSFER.Hex(),
+		"APPROVAL_ALL", TOPIC_APPROVAL_FOR_ALL.Hex(),
+		"APPROVAL", TOPIC_APPROVAL.Hex(),
+		"USER", previewUser,
+		"ROUTER", previewRouter,
+		"TOKEN", previewToken,
+		"POOL", previewPool,
+		"NATIVE", SIMULATE_NATIVE_TOKEN.Hex(),
+	)
+	return r.Replace(s)
+}
+
+func word(n int64) string {
+	return common.BigToHash(big.NewInt(n)).Hex()
+}
+
+func previewFromFrame(t *testing.T, frame string) *TxPreview {
+	t.Helper()
+
+	var f callFrame
+	if err := json.Unmarshal([]byte(previewJSON(frame)), &f); err != nil {
+		t.Fatal(err)
+	}
+
+	pb := newPreviewBuilder(common.HexToAddress(previewUser), "trace")
+	pb.addFrame(&f)
+	return pb.preview()
+}
+
+func checkDeltas(t *testing.T, p *TxPreview, want map[common.Address]int64) {
+	t.Helper()
+
+	if len(p.Deltas) != len(want) {
+		t.Fatalf("%d deltas, want %d: %+v", len(p.Deltas), len(want), p.Deltas)
+	}
+
+	for _, d := range p.Deltas {
+		w, ok := want[d.Token]
+		if !ok {
+			t.Errorf("unexpected delta of %s: %v", d.Token.Hex(), d.Delta)
+			continue
+		}
+		if d.Delta.Cmp(big.NewInt(w)) != 0 {
+			t.Errorf("delta of %s is %v, want %d", d.Token.Hex(), d.Delta, w)
+		}
+		if d.Native != (d.Token == common.Address{}) {
+			t.Errorf("delta of %s native %v", d.Token.Hex(), d.Native)
+		}
+	}
+}
+
+func TestPreviewNestedRevertedFrames(t *testing.T) {
+	p := previewFromFrame(t, `{
+		"type": "CALL", "from": "USER", "to": "ROUTER", "value": "0x3e8",
+		"calls": [
+			{
+				"type": "CALL", "from": "ROUTER", "to": "TOKEN", "error": "execution reverted",
+				"logs": [{"address": "TOKEN", "topics": ["TRANSFER", "POOL32", "USER32"], "data": "`+word(100)+`"}],
+				"calls": [
+					{"type": "CALL", "from": "TOKEN", "to": "USER", "value": "0x64"}
+				]
+			},
+			{
+				"type": "CALL", "from": "ROUTER", "to": "TOKEN",
+				"logs": [{"address": "TOKEN", "topics": ["TRANSFER", "USER32", "POOL32"], "data": "`+word(50)+`"}],
+				"calls": [
+					{"type": "CALL", "from": "ROUTER", "to": "USER", "value": "0xa"}
+				]
+			}
+		]
+	}`)
+
+	checkDeltas(t, p, map[common.Address]int64{
+		{}:                                -1000 + 10,
+		common.HexToAddress(previewToken): -50,
+	})
+}
+
+func TestPreviewDelegateCallValue(t *testing.T) {
+	// the delegate call frames repeat the value of the parent call
+	p := previewFromFrame(t, `{
+		"type": "CALL", "from": "USER", "to": "ROUTER", "value": "0x64",
+		"calls": [
+			{
+				"type": "DELEGATECALL", "from": "USER", "to": "POOL", "value": "0x64",
+				"calls": [
+					{"type": "CALLCODE", "from": "USER", "to": "POOL", "value": "0x64"}
+				]
+			}
+		]
+	}`)
+
+	checkDeltas(t, p, map[common.Address]int64{{}: -100})
+}
+
+func TestPreviewSelfTransferNetting(t *testing.T) {
+	p := previewFromFrame(t, `{
+		"type": "CALL", "from": "USER", "to": "USER", "value": "0x64",
+		"logs": [
+			{"address": "TOKEN", "topics": ["TRANSFER", "USER32", "USER32"], "data": "`+word(7)+`"},
+			{"address": "TOKEN", "topics": ["TRANSFER", "USER32", "POOL32"], "data": "`+word(5)+`"},
+			{"address": "TOKEN", "topics": ["TRANSFER", "POOL32", "USER32"], "data": "`+word(5)+`"}
+		]
+	}`)
+
+	checkDeltas(t, p, map[common.Address]int64{})
+}
+
+func TestPreviewApprovals(t *testing.T) {
+	p := previewFromFrame(t, `{
+		"type": "CALL", "from": "USER", "to": "TOKEN",
+		"logs": [
+			{"address": "TOKEN", "topics": ["APPROVAL", "USER32", "ROUTER32"], "data": "`+word(1)+`"},
+			{"address": "TOKEN", "topics": ["APPROVAL", "USER32", "ROUTER32"], "data": "`+word(9)+`"},
+			{"address": "TOKEN", "topics": ["APPROVAL", "POOL32", "ROUTER32"], "data": "`+word(3)+`"},
+			{"address": "POOL", "topics": ["APPROVAL_ALL", "USER32", "ROUTER32"], "data": "`+word(1)+`"},
+			{"address": "POOL", "topics": ["TRANSFER", "USER32", "ROUTER32", "`+word(42)+`"], "data": "0x"}
+		]
+	}`)
+
+	if len(p.Deltas) != 0 {
+		t.Errorf("ERC-721 transfer counted as a delta: %+v", p.Deltas)
+	}
+
+	if len(p.Approvals) != 2 {
+		t.Fatalf("%d approvals, want 2: %+v", len(p.Approvals), p.Approvals)
+	}
+
+	a := p.Approvals[0]
+	if a.Token != common.HexToAddress(previewToken) || a.Spender != common.HexToAddress(previewRouter) ||
+		a.Amount.Cmp(big.NewInt(9)) != 0 {
+		t.Errorf("approval %+v, want the last one of 9", a)
+	}
+
+	if a := p.Approvals[1]; a.Token != common.HexToAddress(previewPool) || !a.All || a.Amount != nil {
+		t.Errorf("approval for all %+v", a)
+	}
+}
+
+func TestPreviewSimulationNativeToken(t *testing.T) {
+	var call simulatedCall
+	err := json.Unmarshal([]byte(previewJSON(`{
+		"status": "0x1",
+		"logs": [
+			{"address": "NATIVE", "topics": ["TRANSFER", "USER32", "ROUTER32"], "data": "`+word(300)+`"},
+			{"address": "NATIVE", "topics": ["TRANSFER", "ROUTER32", "USER32"], "data": "`+word(100)+`"},
+			{"address": "TOKEN", "topics": ["TRANSFER", "POOL32", "USER32"], "data": "`+word(20)+`"}
+		]
+	}`)), &call)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pb := newPreviewBuilder(common.HexToAddress(previewUser), "simulation")
+	for _, l := range call.Logs {
+		pb.addLog(l)
+	}
+
+	checkDeltas(t, pb.preview(), map[common.Address]int64{
+		{}:                                -200,
+		common.HexToAddress(previewToken): 20,
+	})
+}
+
+// previewDebug and previewEth serve canned debug_traceCall and
+// eth_simulateV1 results
+type previewDebug struct{ frame string }
+type previewEth struct{ blocks string }
+
+func (d *previewDebug) TraceCall(args previewCallArgs, block string, config map[string]interface{}) (json.RawMessage, error) {
+	if d.frame == "" {
+		return nil, errors.New("the method debug_traceCall does not exist/is not available")
+	}
+	return json.RawMessage(previewJSON(d.frame)), nil
+}
+
+func (e *previewEth) SimulateV1(opts map[string]interface{}, block string) (json.RawMessage, error) {
+	return json.RawMessage(previewJSON(e.blocks)), nil
+}
+
+func previewClient(t *testing.T, frame string, blocks string) *rpc.Client {
+	t.Helper()
+
+	srv := rpc.NewServer()
+	if err := srv.RegisterName("debug", &previewDebug{frame}); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.RegisterName("eth", &previewEth{blocks}); err != nil {
+		t.Fatal(err)
+	}
+
+	c := rpc.DialInProc(srv)
+	t.Cleanup(func() {
+		c.Close()
+		srv.Stop()
+	})
+	return c
+}
+
+func TestPreviewTx(t *testing.T) {
+	user := common.HexToAddress(previewUser)
+	router := common.HexToAddress(previewRouter)
+	blocks := `[{"calls": [{"status": "0x1", "logs": [
+		{"address": "NATIVE", "topics": ["TRANSFER", "USER32", "ROUTER32"], "data": "` + word(5) + `"}
+	]}]}]`
+
+	// the trace is preferred
+	c := previewClient(t, `{"type": "CALL", "from": "USER", "to": "ROUTER", "value": "0x7"}`, blocks)
+	p, err := previewTx(c, user, router, big.NewInt(7), hexutil.Bytes{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Source != "trace" {
+		t.Errorf("source %s, want trace", p.Source)
+	}
+	checkDeltas(t, p, map[common.Address]int64{{}: -7})
+
+	// nodes without the debug API are simulated
+	c = previewClient(t, "", blocks)
+	p, err = previewTx(c, user, router, big.NewInt(5), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Source != "simulation" {
+		t.Errorf("source %s, want simulation", p.Source)
+	}
+	checkDeltas(t, p, map[common.Address]int64{{}: -5})
+
+	// a reverting call is an error
+	c = previewClient(t, `{"type": "CALL", "from": "USER", "to": "ROUTER", "error": "execution reverted"}`,
+		`[{"calls": [{"status": "0x0", "error": {"code": 3, "message": "execution reverted"}}]}]`)
+	if _, err := previewTx(c, user, router, nil, nil); err == nil || err.Error() != "execution reverted" {
+		t.Errorf("error %v, want execution reverted", err)
+	}
+}
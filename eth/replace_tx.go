@@ -0,0 +1,315 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/AlexNa-Holdings/web3pro/bus"
+	"github.com/AlexNa-Holdings/web3pro/cmn"
+	"github.com/AlexNa-Holdings/web3pro/gocui"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/rs/zerolog/log"
+)
+
+// REPLACEMENT_FEE_BUMP is the fee increase in percents. Nodes reject a
+// same-nonce replacement unless both fee caps grow by at least 10%.
+const REPLACEMENT_FEE_BUMP = 12
+
+// replaceTx speeds up or cancels a pending transaction by sending a new one
+// with the same nonce and higher fees
+func replaceTx(msg *bus.Message) (string, error) {
+	req, ok := msg.Data.(*bus.B_EthReplaceTx)
+	if !ok {
+		return "", bus.ErrInvalidMessageData
+	}
+
+	hash, err := processReplaceTx(msg, req)
+	if err != nil {
+		bus.Send("ui", "notify-error", fmt.Sprintf("Error: %v", err))
+	}
+	return hash, err
+}
+
+func processReplaceTx(msg *bus.Message, req *bus.B_EthReplaceTx) (string, error) {
+	w := cmn.CurrentWallet
+	if w == nil {
+		return "", errors.New("no wallet")
+	}
+
+	r := w.GetTxRecord(req.Hash.Hex())
+	if r == nil {
+		return "", fmt.Errorf("transaction not found: %s", req.Hash.Hex())
+	}
+
+	if !r.IsPending() {
+		return "", fmt.Errorf("transaction is %s", r.Status)
+	}
+
+	b := w.GetBlockchain(r.ChainId)
+	if b == nil {
+		return "", fmt.Errorf("blockchain not found: %v", r.ChainId)
+	}
+
+	from := w.GetAddress(r.From)
+	if from == nil || from.Signer == "" {
+		return "", fmt.Errorf("no signer for address: %s", r.From.String())
+	}
+
+	signer := w.GetSigner(from.Signer)
+	if signer == nil {
+		return "", fmt.Errorf("signer not found: %v", from.Signer)
+	}
+
+	c, err := getEthClient(b)
+	if err != nil {
+		return "", err
+	}
+
+	old, pending, err := c.TransactionByHash(context.Background(), r.Hash)
+	if err != nil {
+		return "", fmt.Errorf("transaction not found on the node: %v", err)
+	}
+
+	if !pending {
+		return "", errors.New("transaction is already mined")
+	}
+
+	tx, err := BuildReplacementTx(b, old, req.Cancel)
+	if err != nil {
+		return "", err
+	}
+
+	// the speed up repeats the original, its spending is already recorded
+	to := common.Address{}
+	if tx.To() != nil {
+		to = *tx.To()
+	}
+	policy_req := newPolicyRequest(b.ChainId, from.Address, to, tx.Value(), tx.Data())
+	policy_req.Replacement = true
+	if err := enforcePolicies(msg, w.CheckPolicies(policy_req)); err != nil {
+		return "", err
+	}
+
+	title := "Speed Up Tx"
+	if req.Cancel {
+		title = "Cancel Tx"
+	}
+
+	template, err := buildReplaceTxTemplate(b, from, old, tx, req.Cancel, false)
+	if err != nil {
+		return "", err
+	}
+
+	confirmed := false
+	hash := ""
+
+	msg.Fetch("ui", "hail", &bus.B_Hail{
+		Title:    title,
+		Template: template,
+		OnOk: func(m *bus.Message, v *gocui.View) bool {
+			hail, ok := m.Data.(*bus.B_Hail)
+			if !ok {
+				err = errors.New("hail data not found")
+				return false
+			}
+
+			hail.Template, err = buildReplaceTxTemplate(b, from, old, tx, req.Cancel, true)
+			if err != nil {
+				bus.Send("ui", "notify-error", fmt.Sprintf("Error: %v", err))
+				return false
+			}
+
+			v.GetGui().UpdateAsync(func(*gocui.Gui) error {
+				v.RenderTemplate(hail.Template)
+				return nil
+			})
+
+			sign_res := msg.Fetch("signer", "sign-tx", &bus.B_SignerSignTx{
+				Type:      signer.Type,
+				Name:      signer.Name,
+				MasterKey: signer.MasterKey,
+				Chain:     b.Name,
+				Tx:        tx,
+				From:      from.Address,
+				Path:      from.Path,
+			})
+
+			if sign_res.Error != nil {
+				err = fmt.Errorf("error signing transaction: %v", sign_res.Error)
+				return false
+			}
+
+			signedTx, ok := sign_res.Data.(*types.Transaction)
+			if !ok {
+				err = errors.New("cannot convert to transaction")
+				return false
+			}
+
+			hash, err = sendSignedTx(signedTx, r.Origin)
+			if err != nil {
+				log.Error().Err(err).Msg("replaceTx: Cannot send tx")
+				return false
+			}
+
+			if err = w.LinkTxReplacement(r.Hash, signedTx.Hash()); err != nil {
+				log.Error().Err(err).Msg("replaceTx: Cannot link replacement")
+			}
+
+			confirmed = true
+			return true
+		},
+		OnCancel: func(m *bus.Message) {
+			bus.Send("timer", "trigger", m.TimerID) // to cancel all nested operations
+		},
+		OnOverHotspot: func(m *bus.Message, v *gocui.View, hs *gocui.Hotspot) {
+			cmn.StandardOnOverHotspot(v, hs)
+		},
+		OnClickHotspot: func(m *bus.Message, v *gocui.View, hs *gocui.Hotspot) {
+			cmn.StandardOnClickHotspot(v, hs)
+		},
+	})
+
+	if !confirmed {
+		return "", err
+	}
+
+	return hash, nil
+}
+
+// BuildReplacementTx builds a transaction with the nonce of the pending one
+// and fees that meet the replacement rules. Cancel sends 0 to the sender.
+func BuildReplacementTx(b *cmn.Blockchain, old *types.Transaction, cancel bool) (*types.Transaction, error) {
+	client, err := getEthClient(b)
+	if err != nil {
+		return nil, err
+	}
+
+	from, err := types.Sender(types.LatestSignerForChainID(old.ChainId()), old)
+	if err != nil {
+		return nil, err
+	}
+
+	if old.Type() == types.LegacyTxType {
+		suggested, err := client.SuggestGasPrice(context.Background())
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to suggest gas price")
+			return nil, err
+		}
+
+		ltx := &types.LegacyTx{
+			Nonce:    old.Nonce(),
+			To:       old.To(),
+			Value:    old.Value(),
+			Gas:      old.Gas(),
+			GasPrice: bumpFee(old.GasPrice(), suggested),
+			Data:     old.Data(),
+		}
+
+		if cancel {
+			ltx.To = &from
+			ltx.Value = big.NewInt(0)
+			ltx.Gas = 21000
+			ltx.Data = nil
+		}
+
+		return types.NewTx(ltx), nil
+	}
+
+	suggestedTip, err := client.SuggestGasTipCap(context.Background())
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to suggest gas tip cap")
+		return nil, err
+	}
+
+	block, err := client.BlockByNumber(context.Background(), nil)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to get the latest block")
+		return nil, err
+	}
+
+	tip := bumpFee(old.GasTipCap(), suggestedTip)
+
+	suggestedFeeCap := new(big.Int).Set(tip)
+	if block.BaseFee() != nil {
+		suggestedFeeCap.Add(suggestedFeeCap, new(big.Int).Mul(block.BaseFee(), big.NewInt(2)))
+	}
+	feeCap := bumpFee(old.GasFeeCap(), suggestedFeeCap)
+
+	dtx := &types.DynamicFeeTx{
+		ChainID:    old.ChainId(),
+		Nonce:      old.Nonce(),
+		To:         old.To(),
+		Value:      old.Value(),
+		Gas:        old.Gas(),
+		GasFeeCap:  feeCap,
+		GasTipCap:  tip,
+		Data:       old.Data(),
+		AccessList: old.AccessList(),
+	}
+
+	if cancel {
+		dtx.To = &from
+		dtx.Value = big.NewInt(0)
+		dtx.Gas = 21000
+		dtx.Data = nil
+		dtx.AccessList = nil // would not fit in 21000 gas
+	}
+
+	return types.NewTx(dtx), nil
+}
+
+// bumpFee returns the fee increased by REPLACEMENT_FEE_BUMP, or the suggested
+// fee if it is higher
+func bumpFee(old, suggested *big.Int) *big.Int {
+	bumped := new(big.Int).Mul(old, big.NewInt(100+REPLACEMENT_FEE_BUMP))
+	bumped.Div(bumped, big.NewInt(100))
+	bumped.Add(bumped, big.NewInt(1))
+
+	if suggested != nil && suggested.Cmp(bumped) > 0 {
+		return new(big.Int).Set(suggested)
+	}
+	return bumped
+}
+
+func buildReplaceTxTemplate(b *cmn.Blockchain, from *cmn.Address, old, tx *types.Transaction, cancel, confirmed bool) (string, error) {
+	w := cmn.CurrentWallet
+	if w == nil {
+		return "", errors.New("no wallet")
+	}
+
+	nt, err := w.GetNativeToken(b)
+	if err != nil {
+		return "", err
+	}
+
+	action := "Speed up"
+	if cancel {
+		action = "<color fg:red>Cancel</color>"
+	}
+
+	max_fee := new(big.Int).Mul(new(big.Int).SetUint64(tx.Gas()), tx.GasFeeCap())
+
+	bottom := `<button text:Replace id:ok bgcolor:g.HelpBgColor color:g.HelpFgColor tip:"send replacement">  ` +
+		`<button text:Reject id:cancel bgcolor:g.ErrorFgColor tip:"keep the pending transaction">`
+	if confirmed {
+		bottom = `<c><blink>Waiting</blink> to be signed
+
+<button text:Reject id:cancel bgcolor:g.ErrorFgColor tip:"reject transaction">`
+	}
+
+	return `  Blockchain: ` + b.Name + `
+        From: ` + cmn.TagAddressShortLink(from.Address) + " " + from.Name + `
+      Action: ` + action + `
+    Replaces: ` + cmn.TagLink(old.Hash().Hex()[:10], "copy "+old.Hash().Hex(), old.Hash().Hex()) + `
+       Nonce: ` + cmn.TagUint64Link(tx.Nonce()) + `
+<line text:Fee>
+   Gas Limit: ` + cmn.TagUint64Link(tx.Gas()) + `
+     Max Fee: ` + cmn.TagValueSymbolLink(old.GasFeeCap(), nt) + ` → ` + cmn.TagValueSymbolLink(tx.GasFeeCap(), nt) + `
+     Max Tip: ` + cmn.TagValueSymbolLink(old.GasTipCap(), nt) + ` → ` + cmn.TagValueSymbolLink(tx.GasTipCap(), nt) + `
+   Total Max: ` + cmn.TagValueSymbolLink(max_fee, nt) + `
+<c>
+` + bottom, nil
+}
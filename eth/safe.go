@@ -0,0 +1,824 @@
+package eth
+
+// Gnosis Safe multisig accounts. A SafeTx is signed off-chain by the owners
+// (EIP-712) and executed with execTransaction by any account once the
+// threshold is met. Partially signed SafeTxs are kept as JSON files in
+// SafeTxFolder, so they can be passed to the co-signers.
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/AlexNa-Holdings/web3pro/bus"
+	"github.com/AlexNa-Holdings/web3pro/cmn"
+	"github.com/AlexNa-Holdings/web3pro/gocui"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	SAFE_OPERATION_CALL         = 0
+	SAFE_OPERATION_DELEGATECALL = 1
+)
+
+type SafeTx struct {
+	ChainId        int                              `json:"chain_id"`
+	Safe           common.Address                   `json:"safe"`
+	Version        string                           `json:"version"`
+	To             common.Address                   `json:"to"`
+	Value          *big.Int                         `json:"value"`
+	Data           hexutil.Bytes                    `json:"data"`
+	Operation      uint8                            `json:"operation"`
+	SafeTxGas      *big.Int                         `json:"safe_tx_gas"`
+	BaseGas        *big.Int                         `json:"base_gas"`
+	GasPrice       *big.Int                         `json:"gas_price"`
+	GasToken       common.Address                   `json:"gas_token"`
+	RefundReceiver common.Address                   `json:"refund_receiver"`
+	Nonce          *big.Int                         `json:"nonce"`
+	Signatures     map[common.Address]hexutil.Bytes `json:"signatures"`
+}
+
+func SafeTxFolder() string {
+	return cmn.DataFolder + "/safe"
+}
+
+func safeCall(b *cmn.Blockchain, safe common.Address, method string) ([]interface{}, error) {
+	client, err := getEthClient(b)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := SAFE_ABI.Pack(method)
+	if err != nil {
+		return nil, err
+	}
+
+	acquireRateLimit(b.ChainId)
+	output, err := client.CallContract(context.Background(), ethereum.CallMsg{To: &safe, Data: data}, nil)
+	handleRPCResult(b.ChainId, err)
+	if err != nil {
+		return nil, err
+	}
+
+	return SAFE_ABI.Unpack(method, output)
+}
+
+// ReadSafe reads the Safe configuration from the chain
+func ReadSafe(b *cmn.Blockchain, address common.Address) (*cmn.Safe, error) {
+	res, err := safeCall(b, address, "VERSION")
+	if err != nil || len(res) != 1 {
+		log.Error().Err(err).Msgf("ReadSafe: Cannot read version of %s", address)
+		return nil, fmt.Errorf("%s is not a Safe on %s", address, b.Name)
+	}
+	version, _ := res[0].(string)
+
+	res, err = safeCall(b, address, "getOwners")
+	if err != nil || len(res) != 1 {
+		log.Error().Err(err).Msgf("ReadSafe: Cannot read owners of %s", address)
+		return nil, fmt.Errorf("cannot read owners: %v", err)
+	}
+	owners, _ := res[0].([]common.Address)
+
+	res, err = safeCall(b, address, "getThreshold")
+	if err != nil || len(res) != 1 {
+		log.Error().Err(err).Msgf("ReadSafe: Cannot read threshold of %s", address)
+		return nil, fmt.Errorf("cannot read threshold: %v", err)
+	}
+	threshold, _ := res[0].(*big.Int)
+
+	if len(owners) == 0 || threshold == nil || threshold.Sign() <= 0 {
+		return nil, fmt.Errorf("%s is not a set up Safe", address)
+	}
+
+	return &cmn.Safe{
+		ChainId:   b.ChainId,
+		Version:   version,
+		Owners:    owners,
+		Threshold: int(threshold.Int64()),
+	}, nil
+}
+
+func GetSafeNonce(b *cmn.Blockchain, address common.Address) (*big.Int, error) {
+	res, err := safeCall(b, address, "nonce")
+	if err != nil || len(res) != 1 {
+		log.Error().Err(err).Msgf("GetSafeNonce: Cannot read nonce of %s", address)
+		return nil, fmt.Errorf("cannot read Safe nonce: %v", err)
+	}
+
+	nonce, ok := res[0].(*big.Int)
+	if !ok {
+		return nil, errors.New("invalid Safe nonce")
+	}
+	return nonce, nil
+}
+
+// RefreshSafe re-reads owners and threshold and saves the wallet if they changed
+func RefreshSafe(b *cmn.Blockchain, a *cmn.Address) error {
+	s, err := ReadSafe(b, a.Address)
+	if err != nil {
+		return err
+	}
+
+	if a.Safe != nil && a.Safe.ChainId == s.ChainId && a.Safe.Version == s.Version &&
+		a.Safe.Threshold == s.Threshold && slices.Equal(a.Safe.Owners, s.Owners) {
+		return nil
+	}
+
+	a.Safe = s
+	return cmn.CurrentWallet.Save()
+}
+
+func NewSafeTx(b *cmn.Blockchain, safe *cmn.Address, to common.Address, value *big.Int, data []byte) (*SafeTx, error) {
+	if err := RefreshSafe(b, safe); err != nil {
+		return nil, err
+	}
+
+	nonce, err := GetSafeNonce(b, safe.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	if value == nil {
+		value = big.NewInt(0)
+	}
+
+	return &SafeTx{
+		ChainId:    b.ChainId,
+		Safe:       safe.Address,
+		Version:    safe.Safe.Version,
+		To:         to,
+		Value:      value,
+		Data:       data,
+		Operation:  SAFE_OPERATION_CALL,
+		SafeTxGas:  big.NewInt(0),
+		BaseGas:    big.NewInt(0),
+		GasPrice:   big.NewInt(0),
+		Nonce:      nonce,
+		Signatures: map[common.Address]hexutil.Bytes{},
+	}, nil
+}
+
+// safeDomainHasChainId is true for Safe v1.3.0+, older versions sign without it
+func safeDomainHasChainId(version string) bool {
+	p := strings.Split(version, ".")
+	if len(p) < 2 {
+		return true
+	}
+	major, _ := strconv.Atoi(p[0])
+	minor, _ := strconv.Atoi(p[1])
+	return major > 1 || (major == 1 && minor >= 3)
+}
+
+func (st *SafeTx) TypedData() apitypes.TypedData {
+	domain := []apitypes.Type{{Name: "verifyingContract", Type: "address"}}
+	td := apitypes.TypedData{
+		PrimaryType: "SafeTx",
+		Domain: apitypes.TypedDataDomain{
+			VerifyingContract: st.Safe.Hex(),
+		},
+		Message: apitypes.TypedDataMessage{
+			"to":             st.To.Hex(),
+			"value":          st.Value.String(),
+			"data":           hexutil.Encode(st.Data),
+			"operation":      strconv.Itoa(int(st.Operation)),
+			"safeTxGas":      st.SafeTxGas.String(),
+			"baseGas":        st.BaseGas.String(),
+			"gasPrice":       st.GasPrice.String(),
+			"gasToken":       st.GasToken.Hex(),
+			"refundReceiver": st.RefundReceiver.Hex(),
+			"nonce":          st.Nonce.String(),
+		},
+	}
+
+	if safeDomainHasChainId(st.Version) {
+		domain = append([]apitypes.Type{{Name: "chainId", Type: "uint256"}}, domain...)
+		td.Domain.ChainId = math.NewHexOrDecimal256(int64(st.ChainId))
+	}
+
+	td.Types = apitypes.Types{
+		"EIP712Domain": domain,
+		"SafeTx": {
+			{Name: "to", Type: "address"},
+			{Name: "value", Type: "uint256"},
+			{Name: "data", Type: "bytes"},
+			{Name: "operation", Type: "uint8"},
+			{Name: "safeTxGas", Type: "uint256"},
+			{Name: "baseGas", Type: "uint256"},
+			{Name: "gasPrice", Type: "uint256"},
+			{Name: "gasToken", Type: "address"},
+			{Name: "refundReceiver", Type: "address"},
+			{Name: "nonce", Type: "uint256"},
+		},
+	}
+
+	return td
+}
+
+func (st *SafeTx) Hash() (common.Hash, error) {
+	hash, _, err := apitypes.TypedDataAndHash(st.TypedData())
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return common.BytesToHash(hash), nil
+}
+
+// AddSignature adds the owner's EIP-712 signature after checking it recovers
+// to the owner, and the owner is an owner of the Safe (unless s is nil)
+func (st *SafeTx) AddSignature(s *cmn.Safe, owner common.Address, signature []byte) error {
+	if s != nil && !s.IsOwner(owner) {
+		return fmt.Errorf("%s is not an owner of the Safe", owner)
+	}
+
+	if len(signature) != 65 {
+		return fmt.Errorf("invalid signature length: %d", len(signature))
+	}
+
+	hash, err := st.Hash()
+	if err != nil {
+		return err
+	}
+
+	sig := common.CopyBytes(signature)
+	if sig[64] >= 27 {
+		sig[64] -= 27
+	}
+	if sig[64] > 1 {
+		return errors.New("only EIP-712 signatures are supported")
+	}
+
+	pub, err := crypto.SigToPub(hash.Bytes(), sig)
+	if err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+
+	if crypto.PubkeyToAddress(*pub) != owner {
+		return fmt.Errorf("signature is not from %s", owner)
+	}
+
+	sig[64] += 27 // the Safe expects 27/28
+	if st.Signatures == nil {
+		st.Signatures = map[common.Address]hexutil.Bytes{}
+	}
+	st.Signatures[owner] = sig
+	return nil
+}
+
+// Signers returns the owners who signed, in the order the Safe expects them.
+// The signatures of the addresses no longer owning the Safe are skipped, the
+// Safe would revert on them.
+func (st *SafeTx) Signers(s *cmn.Safe) []common.Address {
+	res := make([]common.Address, 0, len(st.Signatures))
+	for a := range st.Signatures {
+		if s.IsOwner(a) {
+			res = append(res, a)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return bytes.Compare(res[i].Bytes(), res[j].Bytes()) < 0
+	})
+	return res
+}
+
+// DropNonOwners removes the signatures of the removed owners and returns them
+func (st *SafeTx) DropNonOwners(s *cmn.Safe) []common.Address {
+	res := []common.Address{}
+	for a := range st.Signatures {
+		if !s.IsOwner(a) {
+			delete(st.Signatures, a)
+			res = append(res, a)
+		}
+	}
+	return res
+}
+
+func (st *SafeTx) ExecData(s *cmn.Safe) ([]byte, error) {
+	signatures := []byte{}
+	for _, a := range st.Signers(s) {
+		signatures = append(signatures, st.Signatures[a]...)
+	}
+
+	return SAFE_ABI.Pack("execTransaction", st.To, st.Value, []byte(st.Data), st.Operation,
+		st.SafeTxGas, st.BaseGas, st.GasPrice, st.GasToken, st.RefundReceiver, signatures)
+}
+
+// FileName is the name of the SafeTx file, the same for all co-signers
+func (st *SafeTx) FileName() string {
+	hash, _ := st.Hash()
+	return fmt.Sprintf("%s_%s_%s.json", st.Safe.Hex(), st.Nonce.String(), hash.Hex()[2:10])
+}
+
+func LoadSafeTx(file string) (*SafeTx, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	st := &SafeTx{}
+	if err := json.Unmarshal(data, st); err != nil {
+		return nil, fmt.Errorf("invalid SafeTx file: %w", err)
+	}
+
+	if st.Value == nil || st.SafeTxGas == nil || st.BaseGas == nil || st.GasPrice == nil || st.Nonce == nil {
+		return nil, errors.New("invalid SafeTx file: missing fields")
+	}
+
+	// never trust the signatures in a file, they must be from the owners of
+	// the Safe, as last read from the chain
+	var safe *cmn.Safe
+	if w := cmn.CurrentWallet; w != nil {
+		if a := w.GetAddress(st.Safe); a != nil {
+			safe = a.Safe
+		}
+	}
+
+	signatures := st.Signatures
+	st.Signatures = map[common.Address]hexutil.Bytes{}
+	for owner, sig := range signatures {
+		if err := st.AddSignature(safe, owner, sig); err != nil {
+			return nil, fmt.Errorf("signature of %s: %w", owner, err)
+		}
+	}
+
+	return st, nil
+}
+
+// SaveSafeTx writes the SafeTx to SafeTxFolder, keeping the signatures
+// already collected in the existing file
+func SaveSafeTx(st *SafeTx) (string, error) {
+	if err := os.MkdirAll(SafeTxFolder(), 0700); err != nil {
+		return "", err
+	}
+
+	file := SafeTxFolder() + "/" + st.FileName()
+
+	if old, err := LoadSafeTx(file); err == nil {
+		for owner, sig := range old.Signatures {
+			if _, ok := st.Signatures[owner]; !ok {
+				st.Signatures[owner] = sig
+			}
+		}
+	}
+
+	return file, ExportSafeTx(st, file)
+}
+
+func ExportSafeTx(st *SafeTx, file string) error {
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0600)
+}
+
+// ImportSafeTx merges a SafeTx file from a co-signer into SafeTxFolder
+func ImportSafeTx(file string) (string, error) {
+	st, err := LoadSafeTx(file)
+	if err != nil {
+		return "", err
+	}
+	return SaveSafeTx(st)
+}
+
+// SafeTxList returns the pending SafeTx files
+func SafeTxList() []string {
+	res := []string{}
+	files, err := filepath.Glob(SafeTxFolder() + "/*.json")
+	if err != nil {
+		return res
+	}
+	for _, f := range files {
+		res = append(res, filepath.Base(f))
+	}
+	sort.Strings(res)
+	return res
+}
+
+func safeTx(msg *bus.Message) (string, error) {
+	req, ok := msg.Data.(*bus.B_EthSafeTx)
+	if !ok {
+		return "", bus.ErrInvalidMessageData
+	}
+
+	w := cmn.CurrentWallet
+	if w == nil {
+		return "", errors.New("no wallet")
+	}
+
+	st, err := LoadSafeTx(req.File)
+	if err != nil {
+		return "", err
+	}
+
+	safe := w.GetAddress(st.Safe)
+	if safe == nil || safe.Safe == nil {
+		return "", fmt.Errorf("safe not found in wallet: %s", st.Safe)
+	}
+
+	b := w.GetBlockchain(st.ChainId)
+	if b == nil {
+		return "", fmt.Errorf("blockchain not found: %v", st.ChainId)
+	}
+
+	nonce, err := GetSafeNonce(b, safe.Address)
+	if err == nil && nonce.Cmp(st.Nonce) > 0 {
+		err = fmt.Errorf("SafeTx nonce %v is already used", st.Nonce)
+	}
+	if err == nil {
+		err = RefreshSafe(b, safe)
+	}
+	if err != nil {
+		bus.Send("ui", "notify-error", fmt.Sprintf("Error: %v", err))
+		return "", err
+	}
+
+	// the owners may have changed since the file was signed
+	if removed := st.DropNonOwners(safe.Safe); len(removed) > 0 {
+		log.Warn().Msgf("safeTx: signatures of removed owners dropped: %v", removed)
+		bus.Send("ui", "notify", fmt.Sprintf("%d signature(s) of removed owners dropped", len(removed)))
+	}
+
+	return processSafeTx(msg, b, safe, st)
+}
+
+// sendFromSafe proposes a transaction from the Safe
+func sendFromSafe(msg *bus.Message, b *cmn.Blockchain, safe *cmn.Address, to common.Address, value *big.Int, data []byte) (string, error) {
+	if safe.Safe.ChainId != b.ChainId {
+		return "", fmt.Errorf("safe %s is not deployed on %s", safe.Name, b.Name)
+	}
+
+	st, err := NewSafeTx(b, safe, to, value, data)
+	if err != nil {
+		log.Error().Err(err).Msg("Error building SafeTx")
+		bus.Send("ui", "notify-error", fmt.Sprintf("Error: %v", err))
+		return "", err
+	}
+
+	return processSafeTx(msg, b, safe, st)
+}
+
+// processSafeTx shows the SafeTx, collects the signatures of the local
+// owners and executes it once the threshold is met
+func processSafeTx(msg *bus.Message, b *cmn.Blockchain, safe *cmn.Address, st *SafeTx) (string, error) {
+	w := cmn.CurrentWallet
+
+	// the policies of the Safe for the transaction, and of the local owners
+	// for the SafeTx they sign
+	policy_req := newPolicyRequest(b.ChainId, safe.Address, st.To, st.Value, st.Data)
+	violations := w.CheckPolicies(policy_req)
+	for _, a := range w.GetSafeSigners(safe.Safe) {
+		if _, ok := st.Signatures[a.Address]; !ok {
+			violations = append(violations, w.CheckTypedDataPolicies(a.Address, st.TypedData())...)
+		}
+	}
+	if err := enforcePolicies(msg, violations); err != nil {
+		return "", err
+	}
+
+	confirmed := false
+	var err error
+	hash := ""
+
+	msg.Fetch("ui", "hail", &bus.B_Hail{
+		Title:    "Safe Transaction",
+		Template: buildSafeTxTemplate(b, safe, st, false),
+		OnOk: func(m *bus.Message, v *gocui.View) bool {
+			hail, ok := m.Data.(*bus.B_Hail)
+			if !ok {
+				log.Error().Msg("processSafeTx: hail data not found")
+				err = errors.New("hail data not found")
+				return false
+			}
+
+			hail.Template = buildSafeTxTemplate(b, safe, st, true)
+			v.GetGui().UpdateAsync(func(*gocui.Gui) error {
+				v.RenderTemplate(hail.Template)
+				return nil
+			})
+
+			if len(st.Signers(safe.Safe)) < safe.Safe.Threshold {
+				err = signSafeTx(msg, b, safe, st)
+				if err != nil {
+					bus.Send("ui", "notify-error", fmt.Sprintf("Error: %v", err))
+					return false
+				}
+
+				var file string
+				file, err = SaveSafeTx(st)
+				if err != nil {
+					bus.Send("ui", "notify-error", fmt.Sprintf("Error saving SafeTx: %v", err))
+					return false
+				}
+
+				if missing := safe.Safe.Threshold - len(st.Signers(safe.Safe)); missing > 0 {
+					err = fmt.Errorf("SafeTx needs %d more signature(s), saved to %s", missing, file)
+					bus.Send("ui", "notify", err.Error())
+					return true
+				}
+			}
+
+			hash, err = execSafeTx(msg, b, safe, st)
+			if err != nil {
+				log.Error().Err(err).Msg("processSafeTx: Cannot execute SafeTx")
+				bus.Send("ui", "notify-error", fmt.Sprintf("Error: %v", err))
+				return false
+			}
+
+			os.Remove(SafeTxFolder() + "/" + st.FileName())
+			confirmed = true
+			return true
+		},
+		OnCancel: func(m *bus.Message) {
+			bus.Send("timer", "trigger", m.TimerID) // to cancel all nested operations
+		},
+		OnOverHotspot: func(m *bus.Message, v *gocui.View, hs *gocui.Hotspot) {
+			cmn.StandardOnOverHotspot(v, hs)
+		},
+		OnClickHotspot: func(m *bus.Message, v *gocui.View, hs *gocui.Hotspot) {
+			if hs != nil {
+				switch hs.Value {
+				case "button export":
+					file, err := SaveSafeTx(st)
+					if err != nil {
+						bus.Send("ui", "notify-error", fmt.Sprintf("Error saving SafeTx: %v", err))
+						return
+					}
+					bus.Send("ui", "notify", "SafeTx saved to "+file)
+
+					template := buildSafeTxTemplate(b, safe, st, false)
+					v.GetGui().UpdateAsync(func(*gocui.Gui) error {
+						hail, ok := m.Data.(*bus.B_Hail)
+						if ok {
+							hail.Template = template
+							v.RenderTemplate(template)
+						}
+						return nil
+					})
+				default:
+					cmn.StandardOnClickHotspot(v, hs)
+				}
+			}
+		},
+	})
+
+	if !confirmed {
+		return "", err
+	}
+
+	recordPolicySpend(policy_req)
+	bus.Send("ui", "notify", "Safe transaction sent: "+hash)
+	return hash, nil
+}
+
+// signSafeTx collects the signatures of the local owners, up to the threshold
+func signSafeTx(msg *bus.Message, b *cmn.Blockchain, safe *cmn.Address, st *SafeTx) error {
+	w := cmn.CurrentWallet
+
+	for _, a := range w.GetSafeSigners(safe.Safe) {
+		if len(st.Signers(safe.Safe)) >= safe.Safe.Threshold {
+			break
+		}
+
+		if _, ok := st.Signatures[a.Address]; ok {
+			continue
+		}
+
+		signer := w.GetSigner(a.Signer)
+
+		res := msg.Fetch("signer", "sign-typed-data-v4", &bus.B_SignerSignTypedData_v4{
+			Type:      signer.Type,
+			Name:      signer.Name,
+			MasterKey: signer.MasterKey,
+			Address:   a.Address,
+			Path:      a.Path,
+			TypedData: st.TypedData(),
+		})
+		if res.Error != nil {
+			return fmt.Errorf("error signing with %s: %v", a.Name, res.Error)
+		}
+
+		sig, ok := res.Data.(string)
+		if !ok {
+			return fmt.Errorf("invalid signature from %s", a.Name)
+		}
+
+		sig_bytes, err := hexutil.Decode(sig)
+		if err != nil {
+			return fmt.Errorf("invalid signature from %s: %v", a.Name, err)
+		}
+
+		if err := st.AddSignature(safe.Safe, a.Address, sig_bytes); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// safeExecutor picks the account paying for execTransaction: a local
+// owner, or the current address
+func safeExecutor(safe *cmn.Address, st *SafeTx) *cmn.Address {
+	w := cmn.CurrentWallet
+
+	for _, o := range st.Signers(safe.Safe) {
+		a := w.GetAddress(o)
+		if a != nil && a.Signer != "" && w.GetSigner(a.Signer) != nil {
+			return a
+		}
+	}
+
+	a := w.GetAddress(w.CurrentAddress)
+	if a != nil && a.Safe == nil && a.Signer != "" && w.GetSigner(a.Signer) != nil {
+		return a
+	}
+
+	return nil
+}
+
+func execSafeTx(msg *bus.Message, b *cmn.Blockchain, safe *cmn.Address, st *SafeTx) (string, error) {
+	w := cmn.CurrentWallet
+
+	executor := safeExecutor(safe, st)
+	if executor == nil {
+		return "", errors.New("no local account to execute the SafeTx")
+	}
+	signer := w.GetSigner(executor.Signer)
+
+	data, err := st.ExecData(safe.Safe)
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := BuildTx(b, signer, executor, st.Safe, big.NewInt(0), data)
+	if err != nil {
+		return "", err
+	}
+
+	tx, err = reserveNonce(b, executor.Address, tx)
+	if err != nil {
+		return "", err
+	}
+
+	sign_res := msg.Fetch("signer", "sign-tx", &bus.B_SignerSignTx{
+		Type:      signer.Type,
+		Name:      signer.Name,
+		MasterKey: signer.MasterKey,
+		Chain:     b.Name,
+		Tx:        tx,
+		From:      executor.Address,
+		Path:      executor.Path,
+	})
+	if sign_res.Error != nil {
+		releaseNonce(b.ChainId, executor.Address, tx)
+		return "", fmt.Errorf("error signing transaction: %v", sign_res.Error)
+	}
+
+	signedTx, ok := sign_res.Data.(*types.Transaction)
+	if !ok {
+		releaseNonce(b.ChainId, executor.Address, tx)
+		return "", errors.New("cannot convert to transaction")
+	}
+
+	hash, err := SendSignedTx(signedTx)
+	if err != nil {
+		releaseNonce(b.ChainId, executor.Address, tx)
+	}
+	return hash, err
+}
+
+func buildSafeTxTemplate(b *cmn.Blockchain, safe *cmn.Address, st *SafeTx, confirmed bool) string {
+	w := cmn.CurrentWallet
+
+	value := st.Value.String() + " wei"
+	if nt, err := w.GetNativeToken(b); err == nil {
+		value = nt.Value2Str(st.Value) + " " + nt.Symbol
+	}
+
+	to_name := ""
+	if a := w.GetAddress(st.To); a != nil {
+		to_name = a.Name
+	} else if c := w.GetContract(st.To); c != nil {
+		to_name = c.Name
+	}
+
+	data := "\n        Data: (none)"
+	switch {
+	case len(st.Data) >= 4:
+		data = callDetails(b, st.To, st.Data, 0) + buildRiskDetails(b, st.To, st.Data)
+	case len(st.Data) > 0:
+		data = "\n        Data: " + cmn.TagBytesLink(st.Data)
+	}
+
+	operation := "CALL"
+	warnings := ""
+	if st.Operation != SAFE_OPERATION_CALL {
+		operation = fmt.Sprintf("<color fg:red>DELEGATECALL (%d)</color>", st.Operation)
+		warnings += `
+<c><blink><color fg:red>HIGH RISK</color></blink>
+<c>DELEGATECALL runs the code of ` + cmn.TagAddressShortLink(st.To) + `
+<c>with the storage and the funds of the Safe
+`
+	}
+
+	gas_token := "native"
+	if st.GasToken != (common.Address{}) {
+		gas_token = cmn.TagAddressShortLink(st.GasToken)
+		if t := w.GetTokenByAddress(b.ChainId, st.GasToken); t != nil {
+			gas_token += " " + t.Symbol
+		}
+	}
+
+	refund_receiver := "executor"
+	if st.RefundReceiver != (common.Address{}) {
+		refund_receiver = cmn.TagAddressShortLink(st.RefundReceiver) + " " + addressName(b, st.RefundReceiver)
+	}
+
+	if st.GasPrice.Sign() != 0 || st.RefundReceiver != (common.Address{}) {
+		warnings += `
+<c><color fg:red>The Safe pays a gas refund to ` + refund_receiver + `</color>
+`
+	}
+
+	hash, _ := st.Hash()
+
+	owners := ""
+	can_sign := 0
+	for _, o := range safe.Safe.Owners {
+		status := "remote"
+		if _, ok := st.Signatures[o]; ok {
+			status = "signed"
+		} else if a := w.GetAddress(o); a != nil && a.Signer != "" && w.GetSigner(a.Signer) != nil {
+			status = "local"
+			can_sign++
+		}
+
+		icon := cmn.ICON_UNCHECK
+		if status == "signed" {
+			icon = cmn.ICON_CHECK
+		}
+
+		name := ""
+		if a := w.GetAddress(o); a != nil {
+			name = a.Name
+		}
+
+		owners += " " + icon + cmn.TagAddressShortLink(o) + " " + name + " (" + status + ")\n"
+	}
+
+	signed := len(st.Signers(safe.Safe))
+	missing := safe.Safe.Threshold - signed
+
+	ok_button := ""
+	switch {
+	case missing <= 0:
+		ok_button = `<button text:Execute id:ok bgcolor:g.HelpBgColor color:g.HelpFgColor tip:"execute transaction">  `
+	case can_sign >= missing:
+		ok_button = `<button text:'Sign & Execute' id:ok bgcolor:g.HelpBgColor color:g.HelpFgColor tip:"sign and execute transaction">  `
+	case can_sign > 0:
+		ok_button = `<button text:Sign id:ok bgcolor:g.HelpBgColor color:g.HelpFgColor tip:"sign with local owners">  `
+	}
+
+	bottom := ok_button +
+		`<button text:Export id:export tip:"save for co-signers">  ` +
+		`<button text:Reject id:cancel bgcolor:g.ErrorFgColor tip:"reject transaction">`
+	if confirmed {
+		bottom = `<c><blink>Waiting</blink> to be signed
+
+<button text:Reject id:cancel bgcolor:g.ErrorFgColor tip:"reject transaction">`
+	}
+
+	return `  Blockchain: ` + b.Name + `
+        Safe: ` + cmn.TagAddressShortLink(safe.Address) + " " + safe.Name + `
+   Threshold: ` + fmt.Sprintf("%d of %d", safe.Safe.Threshold, len(safe.Safe.Owners)) + `
+       Nonce: ` + st.Nonce.String() + `
+<line text:Transaction>
+          To: ` + cmn.TagAddressShortLink(st.To) + " " + to_name + `
+       Value: ` + value + `
+   Operation: ` + operation + data + `
+<line text:Gas>
+   SafeTxGas: ` + st.SafeTxGas.String() + `
+     BaseGas: ` + st.BaseGas.String() + `
+    GasPrice: ` + st.GasPrice.String() + `
+    GasToken: ` + gas_token + `
+      Refund: ` + refund_receiver + `
+  SafeTxHash: ` + cmn.TagLink(hash.Hex()[:10]+"…", "copy "+hash.Hex(), "Copy SafeTxHash") + `
+` + warnings + `<line text:Owners>
+` + owners + fmt.Sprintf("<c>%d of %d signatures\n", signed, safe.Safe.Threshold) + `
+<c>
+` + bottom
+}
@@ -40,10 +40,30 @@ func send(msg *bus.Message) error {
 		return fmt.Errorf("address from not found: %v", req.From)
 	}
 
+	policy_req := &cmn.PolicyRequest{ChainId: b.ChainId, From: from.Address, To: req.To, Value: req.Amount}
+	if !t.Native {
+		data, err := ERC20_ABI.Pack("transfer", req.To, req.Amount)
+		if err != nil {
+			return err
+		}
+		policy_req.To, policy_req.Value, policy_req.Data = t.Address, big.NewInt(0), data
+	}
+
+	if from.Safe != nil { // the policies are checked for the SafeTx
+		_, err := sendFromSafe(msg, b, from, policy_req.To, policy_req.Value, policy_req.Data)
+		return err
+	}
+
+	if err := enforcePolicies(msg, w.CheckPolicies(policy_req)); err != nil {
+		return err
+	}
+
 	if from.Signer == "" {
 		return fmt.Errorf("cannot send from watch-only address")
 	}
 
+	primeENSName(req.To)
+
 	template, err := BuildHailToSendTemplate(b, t, from, req.To, req.Amount, nil, false)
 	if err != nil {
 		log.Error().Err(err).Msg("Error building hail template")
@@ -104,6 +124,7 @@ func send(msg *bus.Message) error {
 					return false
 				}
 			}
+			recordPolicySpend(policy_req)
 			return true
 		},
 		OnCancel: func(m *bus.Message) {
@@ -1,22 +1,17 @@
 package eth
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"math/big"
-	"os"
-	"reflect"
 	"strings"
 
 	"github.com/AlexNa-Holdings/web3pro/bus"
 	"github.com/AlexNa-Holdings/web3pro/cmn"
 	"github.com/AlexNa-Holdings/web3pro/gocui"
-	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/rs/zerolog/log"
 )
@@ -42,11 +37,25 @@ func sendTx(msg *bus.Message) (string, error) {
 		return "", fmt.Errorf("address from not found: %v", req.From)
 	}
 
+	if from.Safe != nil { // the policies are checked for the SafeTx
+		return sendFromSafe(msg, b, from, req.To, req.Amount, req.Data)
+	}
+
+	policy_req := newPolicyRequest(b.ChainId, from.Address, req.To, req.Amount, req.Data)
+	if err := enforcePolicies(msg, w.CheckPolicies(policy_req)); err != nil {
+		return "", err
+	}
+
 	if from.Signer == "" {
 		return "", fmt.Errorf("cannot send from watch-only address")
 	}
 
-	template, err := BuildHailToSendTxTemplate(b, from, req.To, req.Amount, req.Data, nil, false)
+	sim := SimulateTx(b, from.Address, req.To, req.Amount, req.Data)
+	preset := "" // the chain default
+	var gas_price *big.Int // set by the user, overrides the preset
+	primeENSName(req.To)
+
+	template, err := BuildHailToSendTxTemplate(b, from, req.To, req.Amount, req.Data, nil, sim, preset, false)
 	if err != nil {
 		log.Error().Err(err).Msg("Error building send-tx hail template")
 		bus.Send("ui", "notify-error", fmt.Sprintf("Error: %v", err))
@@ -55,7 +64,7 @@ func sendTx(msg *bus.Message) (string, error) {
 
 	nt, _ := w.GetNativeToken(b)
 
-	tx, err := BuildTx(b, w.GetSigner(from.Signer), from, req.To, req.Amount, req.Data)
+	tx, err := buildTx(b, w.GetSigner(from.Signer), from, req.To, req.Amount, req.Data, sim, preset)
 	if err != nil {
 		log.Error().Err(err).Msg("Error building transaction")
 		bus.Send("ui", "notify-error", fmt.Sprintf("Error: %v", err))
@@ -77,14 +86,18 @@ func sendTx(msg *bus.Message) (string, error) {
 				return false
 			}
 
-			hail.Template, err = BuildHailToSendTxTemplate(b, from, req.To, req.Amount, req.Data, nil, true)
+			if sim.Blocked() {
+				return false // the Send button is not shown until overridden
+			}
+
+			hail.Template, err = BuildHailToSendTxTemplate(b, from, req.To, req.Amount, req.Data, gas_price, sim, preset, true)
 			if err != nil {
 				bus.Send("ui", "notify-error", fmt.Sprintf("Error: %v", err))
 				return false
 			}
 
 			v.GetGui().UpdateAsync(func(*gocui.Gui) error {
-				v.RenderTemplate(template)
+				v.RenderTemplate(hail.Template)
 				return nil
 			})
 
@@ -94,6 +107,13 @@ func sendTx(msg *bus.Message) (string, error) {
 				return false
 			}
 
+			reserved, rerr := reserveNonce(b, from.Address, tx)
+			if rerr != nil {
+				err = rerr
+				return false
+			}
+			tx = reserved
+
 			sign_res := msg.Fetch("signer", "sign-tx", &bus.B_SignerSignTx{
 				Type:      signer.Type,
 				Name:      signer.Name,
@@ -105,6 +125,7 @@ func sendTx(msg *bus.Message) (string, error) {
 			})
 
 			if sign_res.Error != nil {
+				releaseNonce(b.ChainId, from.Address, tx)
 				err = fmt.Errorf("error signing transaction: %v", sign_res.Error)
 				return false
 			}
@@ -112,12 +133,14 @@ func sendTx(msg *bus.Message) (string, error) {
 			signedTx, ok := sign_res.Data.(*types.Transaction)
 			if !ok {
 				log.Error().Msgf("sendTx: Cannot convert to transaction. Data:(%v)", sign_res.Data)
+				releaseNonce(b.ChainId, from.Address, tx)
 				err = errors.New("cannot convert to transaction")
 				return false
 			}
 
-			hash, err = SendSignedTx(signedTx)
+			hash, err = sendSignedTx(signedTx, req.Origin)
 			if err != nil {
+				releaseNonce(b.ChainId, from.Address, tx)
 				log.Error().Err(err).Msg("sendTx: Cannot send tx")
 				bus.Send("ui", "notify-error", fmt.Sprintf("Error: %v", err))
 				return false
@@ -136,10 +159,25 @@ func sendTx(msg *bus.Message) (string, error) {
 			rebuild := false
 			if hs != nil {
 				switch hs.Value {
+				case "button override":
+					sim.Override = true
+					rebuild = true
+				case "button fee_slow", "button fee_normal", "button fee_fast", "button fee_urgent":
+					ntx, err := buildTx(b, w.GetSigner(from.Signer), from, req.To, req.Amount, req.Data, sim,
+						strings.TrimPrefix(hs.Value, "button fee_"))
+					if err != nil {
+						bus.Send("ui", "notify-error", fmt.Sprintf("Error: %v", err))
+						return
+					}
+					preset = strings.TrimPrefix(hs.Value, "button fee_")
+					gas_price = nil
+					tx = ntx
+					rebuild = true
 				case "button edit_gas_price":
 					go editFee(m, v, tx, nt, func(newGasPrice *big.Int) {
-						tx.GasPrice().Set(newGasPrice)
-						template, err := BuildHailToSendTxTemplate(b, from, req.To, req.Amount, req.Data, newGasPrice, false)
+						gas_price = newGasPrice
+						tx = withGasPrice(tx, gas_price)
+						template, err := BuildHailToSendTxTemplate(b, from, req.To, req.Amount, req.Data, gas_price, sim, preset, false)
 						if err != nil {
 							log.Error().Err(err).Msg("Error building hail template")
 							bus.Send("ui", "notify-error", fmt.Sprintf("Error: %v", err))
@@ -157,7 +195,7 @@ func sendTx(msg *bus.Message) (string, error) {
 					})
 				case "button edit_contract":
 					go editContract(m, v, req.To, func() {
-						template, err := BuildHailToSendTxTemplate(b, from, req.To, req.Amount, req.Data, nil, false)
+						template, err := BuildHailToSendTxTemplate(b, from, req.To, req.Amount, req.Data, gas_price, sim, preset, false)
 						if err != nil {
 							log.Error().Err(err).Msg("Error building hail template")
 							bus.Send("ui", "notify-error", fmt.Sprintf("Error: %v", err))
@@ -205,7 +243,7 @@ func sendTx(msg *bus.Message) (string, error) {
 				}
 
 				if rebuild {
-					template, err := BuildHailToSendTxTemplate(b, from, req.To, req.Amount, req.Data, nil, false)
+					template, err := BuildHailToSendTxTemplate(b, from, req.To, req.Amount, req.Data, gas_price, sim, preset, false)
 					if err != nil {
 						log.Error().Err(err).Msg("Error building hail template")
 						bus.Send("ui", "notify-error", fmt.Sprintf("Error: %v", err))
@@ -227,6 +265,7 @@ func sendTx(msg *bus.Message) (string, error) {
 	if !confirmed {
 		return "", err
 	}
+	recordPolicySpend(policy_req)
 	bus.Send("ui", "notify", "Transaction sent: "+hash)
 
 	return hash, nil
@@ -234,83 +273,71 @@ func sendTx(msg *bus.Message) (string, error) {
 
 func BuildTx(b *cmn.Blockchain, s *cmn.Signer, from *cmn.Address, to common.Address,
 	amount *big.Int, data []byte) (*types.Transaction, error) {
+	return buildTx(b, s, from, to, amount, data, nil, "")
+}
+
+// buildTx skips the gas estimation if the simulation shows the tx reverts,
+// so the hail can still be shown with the revert reason. The empty preset
+// means the chain default.
+func buildTx(b *cmn.Blockchain, s *cmn.Signer, from *cmn.Address, to common.Address,
+	amount *big.Int, data []byte, sim *TxSimulation, preset string) (*types.Transaction, error) {
 
 	if from.Signer != s.Name {
 		log.Error().Msgf("BuildTxTransfer: Signer mismatch. Token:(%s) Blockchain:(%s)", from.Signer, s.Name)
 		return nil, errors.New("signer mismatch")
 	}
 
+	return buildUnsignedTx(b, from.Address, to, amount, data, sim, preset)
+}
+
+// buildUnsignedTx fills the nonce, gas and fees, the signer is not needed
+// (tx export-unsigned of a watch-only address)
+func buildUnsignedTx(b *cmn.Blockchain, from common.Address, to common.Address,
+	amount *big.Int, data []byte, sim *TxSimulation, preset string) (*types.Transaction, error) {
+
 	client, err := getEthClient(b)
 	if err != nil {
 		log.Error().Msgf("BuildTxTransfer: Failed to open client: %v", err)
 		return nil, err
 	}
 
-	nonce, err := client.PendingNonceAt(context.Background(), from.Address)
+	nonce, err := nextNonce(b, from, false)
 	if err != nil {
 		log.Error().Msgf("BuildTxTransfer: Cannot get nonce. Error:(%v)", err)
 		return nil, err
 	}
 
 	msg := ethereum.CallMsg{
-		From:  from.Address,
+		From:  from,
 		To:    &to,
 		Gas:   0, // Set to 0 for gas estimation
 		Value: amount,
 		Data:  data,
 	}
 
-	gasLimit, err := client.EstimateGas(context.Background(), msg)
-	if err != nil {
-		log.Error().Msgf("BuildTxTransfer: Cannot estimate gas. Error:(%v)", err)
-		return nil, err
-	}
-
-	// // Simulate the transaction
-	// _, err = client.CallContract(context.Background(), msg, nil)
-	// if err != nil {
-	// 	log.Error().Msgf("BuildTxTransfer: Cannot simulate transaction. Error:(%v)", err)
-	// 	return nil, err
-	// }
-
-	priorityFee, err := client.SuggestGasTipCap(context.Background())
-	if err != nil {
-		log.Error().Err(err).Msg("Failed to suggest gas tip cap")
-		return nil, err
+	var gasLimit uint64 = REVERT_GAS_LIMIT
+	if sim == nil || !sim.Reverted {
+		gasLimit, err = client.EstimateGas(context.Background(), msg)
+		if err != nil {
+			log.Error().Msgf("BuildTxTransfer: Cannot estimate gas. Error:(%v)", err)
+			return nil, fmt.Errorf("cannot estimate gas: %s", RevertReason(err, to))
+		}
 	}
 
-	// Get the latest block to determine the base fee
-	block, err := client.BlockByNumber(context.Background(), nil) // Get the latest block
+	fee, err := GetFeePreset(b, preset)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to get the latest block")
+		log.Error().Err(err).Msg("Failed to get the fee preset")
 		return nil, err
 	}
 
-	// Base fee is included in the block header (introduced in EIP-1559)
-	baseFee := block.BaseFee()
-	// Calculate the MaxFeePerGas based on base fee and priority fee
-	// For example, you might want to set MaxFeePerGas to be slightly higher than baseFee + priorityFee
-	maxFeePerGas := new(big.Int).Add(baseFee, priorityFee)
-	buffer := big.NewInt(2) // Set a buffer (optional) to ensure transaction gets processed
-	maxFeePerGas = maxFeePerGas.Mul(maxFeePerGas, buffer)
-
-	tx := types.NewTx(&types.DynamicFeeTx{
-		ChainID:   big.NewInt(int64(b.ChainId)),
-		Nonce:     nonce,
-		To:        &to,
-		Value:     amount,
-		Gas:       gasLimit,
-		GasFeeCap: maxFeePerGas,
-		GasTipCap: priorityFee,
-		Data:      data,
-	})
+	tx := newTx(b, nonce, to, amount, gasLimit, data, fee)
 
 	return tx, nil
 
 }
 
 func BuildHailToSendTxTemplate(b *cmn.Blockchain, from *cmn.Address, to common.Address,
-	amount *big.Int, data []byte, suggested_gas_price *big.Int, confirmed bool) (string, error) {
+	amount *big.Int, data []byte, suggested_gas_price *big.Int, sim *TxSimulation, preset string, confirmed bool) (string, error) {
 	if cmn.CurrentWallet == nil {
 		return "", errors.New("no wallet")
 	}
@@ -342,7 +369,7 @@ func BuildHailToSendTxTemplate(b *cmn.Blockchain, from *cmn.Address, to common.A
 		dollars = "(unknown)"
 	}
 
-	tx, err := BuildTx(b, s, from, to, amount, data)
+	tx, err := buildTx(b, s, from, to, amount, data, sim, preset)
 
 	if err != nil {
 		log.Error().Err(err).Msg("Error building transaction")
@@ -374,7 +401,19 @@ func BuildHailToSendTxTemplate(b *cmn.Blockchain, from *cmn.Address, to common.A
 
 		gas_price = suggested_gas_price
 	}
-	total_gas := new(big.Int).Mul(new(big.Int).SetUint64(tx.Gas()), gas_price)
+	fee := EstimateTxFee(b, from.Address, tx, gas_price)
+	total_gas := fee.Total()
+
+	l1_fee := ""
+	if fee.HasL1() {
+		l1_fee_s := "(unknown)"
+		if fee.L1 != nil {
+			l1_fee_s = cmn.TagValueSymbolLink(fee.L1, nt)
+		}
+		l1_fee = `
+      L2 Fee: ` + cmn.TagValueSymbolLink(fee.L2, nt) + `
+ L1 Data Fee: ` + l1_fee_s + " (" + fee.Model + ")"
+	}
 
 	total_fee_s := "(unknown)"
 	if nt.Price > 0 {
@@ -408,10 +447,25 @@ func BuildHailToSendTxTemplate(b *cmn.Blockchain, from *cmn.Address, to common.A
 
 	toolbar += `<l text:'` + cmn.ICON_LINK + `' action:'open ` + burl + "/address/" + to.String() + `' tip:"Open in Explorer">`
 
-	call_details := buildCallDetails(tx, to)
+	call_details := buildCallDetails(b, tx, to)
+
+	simulation := "<color fg:green>OK</color>"
+	if sim == nil {
+		simulation = "(not simulated)"
+	} else if sim.Error != "" {
+		reason := strings.NewReplacer("<", "‹", ">", "›").Replace(sim.Error)
+		simulation = "(not simulated: " + reason + ")"
+	} else if sim.Reverted {
+		reason := strings.NewReplacer("<", "‹", ">", "›").Replace(sim.Reason) // keep template tags intact
+		simulation = "<color fg:red>REVERTS: " + reason + "</color>"
+	}
 
 	bottom := `<button text:Send id:ok bgcolor:g.HelpBgColor color:g.HelpFgColor tip:"send tokens">  ` +
 		`<button text:Reject id:cancel bgcolor:g.ErrorFgColor tip:"reject transaction">`
+	if sim.Blocked() {
+		bottom = `<button text:Override id:override bgcolor:g.ErrorFgColor tip:"send the transaction anyway">  ` +
+			`<button text:Reject id:cancel bgcolor:g.HelpBgColor color:g.HelpFgColor tip:"reject transaction">`
+	}
 	if confirmed {
 		bottom = `<c><blink>Waiting</blink> to be signed
 
@@ -426,17 +480,131 @@ func BuildHailToSendTxTemplate(b *cmn.Blockchain, from *cmn.Address, to common.A
 <line text:Contract>
      Address: ` + cmn.TagAddressShortLink(to) + `
         Name: ` + color_tag + contract_name + color_tag_end + `
-<c>` + toolbar + `</c>` + call_details + `
+<c>` + toolbar + `</c>` + call_details + buildRiskDetails(b, to, tx.Data()) + `
+<line text:Simulation>
+` + simulation + `
+<line text:Balance Changes>
+` + buildPreviewDetails(b, sim) + `
 <line text:Fee> 
    Gas Limit: ` + cmn.TagUint64Link(tx.Gas()) + ` 
+` + buildFeePresetsDetails(b, nt, tx.Gas(), preset, fee) + `
    Gas Price: ` + cmn.TagValueSymbolLink(gas_price, nt) + " " +
-		` <l text:` + cmn.ICON_EDIT + ` action:'button edit_gas_price' tip:"Edit Fee">` + gp_change + `
+		` <l text:` + cmn.ICON_EDIT + ` action:'button edit_gas_price' tip:"Edit Fee">` + gp_change + l1_fee + `
    Total Fee: ` + cmn.TagValueSymbolLink(total_gas, nt) + `
 Total Fee($): ` + total_fee_s + `
 <c>
 ` + bottom, nil
 }
 
+// buildFeePresetsDetails lists the fee presets with the expected inclusion
+// time and cost, a preset is selected by click. The OP-stack L1 data fee
+// does not depend on the gas price and is added to every preset
+func buildFeePresetsDetails(b *cmn.Blockchain, nt *cmn.Token, gas uint64, selected string, fee *TxFee) string {
+	presets, err := GetFeePresets(b)
+	if err != nil {
+		return "     Presets: (not available)"
+	}
+
+	if selected == "" {
+		selected = b.FeePreset
+	}
+	if selected == "" {
+		selected = cmn.FEE_PRESET_NORMAL
+	}
+
+	r := ""
+	for i, p := range presets {
+		title := "             "
+		if i == 0 {
+			title = "     Presets: "
+		}
+
+		mark := "  "
+		if p.Name == selected {
+			mark = "<color fg:green>●</color> "
+		}
+
+		cost := new(big.Int).Mul(new(big.Int).SetUint64(gas), p.GasPrice())
+		if fee != nil && fee.Model == cmn.FEE_MODEL_OP_STACK && fee.L1 != nil {
+			cost.Add(cost, fee.L1)
+		}
+
+		r += title + mark + `<l text:'` + cmn.FixedWidth(p.Name, 6) + `' action:'button fee_` + p.Name + `' tip:"use the ` + p.Name + ` fee">` +
+			fmt.Sprintf(" %-7s ", p.WaitString()) + cmn.TagValueSymbolLink(cost, nt)
+		if nt.Price > 0 {
+			r += " " + cmn.TagShortDollarLink(nt.Price*nt.Float64(cost))
+		}
+		r += "\n"
+	}
+
+	return strings.TrimSuffix(r, "\n")
+}
+
+func buildPreviewDetails(b *cmn.Blockchain, sim *TxSimulation) string {
+	if sim == nil || sim.Preview == nil {
+		return "(not available)"
+	}
+
+	w := cmn.CurrentWallet
+	p := sim.Preview
+
+	if len(p.Deltas) == 0 && len(p.Approvals) == 0 {
+		return "(no changes)"
+	}
+
+	r := ""
+	for _, d := range p.Deltas {
+		color := "green"
+		sign := "+"
+		if d.Delta.Sign() < 0 {
+			color = "red"
+			sign = "-"
+		}
+		abs := new(big.Int).Abs(d.Delta)
+
+		var t *cmn.Token
+		if d.Native {
+			t, _ = w.GetNativeToken(b)
+		} else {
+			t = w.GetTokenByAddress(b.ChainId, d.Token)
+		}
+
+		if t != nil {
+			r += "<color fg:" + color + ">" + sign + t.Value2Str(abs) + "</color> " + t.Symbol + "\n"
+		} else {
+			r += "<color fg:" + color + ">" + sign + abs.String() + "</color> " + cmn.TagAddressShortLink(d.Token) + " (unknown token)\n"
+		}
+	}
+
+	for _, a := range p.Approvals {
+		token := cmn.TagAddressShortLink(a.Token)
+		var t *cmn.Token
+		if t = w.GetTokenByAddress(b.ChainId, a.Token); t != nil {
+			token = t.Symbol
+		}
+
+		spender := cmn.TagAddressShortLink(a.Spender)
+		if c := w.GetContract(a.Spender); c != nil && c.Name != "" {
+			spender += " " + c.Name
+		}
+
+		switch {
+		case a.Amount == nil && a.All:
+			r += "<color fg:red>Approve all</color> " + token + " to " + spender + "\n"
+		case a.Amount == nil:
+			r += "Revoke all " + token + " from " + spender + "\n"
+		case a.Amount.Cmp(cmn.UNLIMITED_APPROVAL) >= 0:
+			r += "<color fg:red>Approve unlimited</color> " + token + " to " + spender + "\n"
+		case t != nil:
+			r += "Approve " + t.Value2Str(a.Amount) + " " + token + " to " + spender + "\n"
+		default:
+			r += "Approve " + a.Amount.String() + " " + token + " to " + spender + "\n"
+		}
+	}
+
+	return strings.TrimSuffix(r, "\n")
+}
+
 func editContract(m *bus.Message, v *gocui.View, address common.Address, on_close func()) {
 	w := cmn.CurrentWallet
 	if w == nil {
@@ -484,200 +652,3 @@ func editContract(m *bus.Message, v *gocui.View, address common.Address, on_clos
 	})
 }
 
-func buildCallDetails(tx *types.Transaction, to common.Address) string {
-	r := ""
-
-	if !cmn.IsContractDownloaded(to) {
-		return r + "(no ABI)"
-	}
-
-	ContractFolder := cmn.DataFolder + "/contracts/" + to.String()
-
-	// Load and parse ABI
-	path := ContractFolder + "/abi.json"
-	abiJSON, err := os.ReadFile(path)
-	if err != nil {
-		return r + "(error reading ABI)"
-	}
-
-	parsedABI, err := abi.JSON(bytes.NewReader(abiJSON))
-	if err != nil {
-		return r + "(error parsing ABI)"
-	}
-
-	data := tx.Data()
-	if len(data) < 4 {
-		return r + "(insufficient data for function selector)"
-	}
-
-	// Extract the first 4 bytes for the function selector
-	functionSelector := data[:4]
-
-	// Match the function selector to a function in the ABI
-	var function abi.Method
-	found := false
-	for _, method := range parsedABI.Methods {
-		if bytes.Equal(functionSelector, method.ID) {
-			function = method
-			found = true
-			break
-		}
-	}
-
-	if !found {
-		return r + "(function not found)"
-	}
-
-	r += functionDetails(parsedABI, function, data)
-
-	return r
-}
-
-func functionDetails(parsedABI abi.ABI, function abi.Method, data []byte) string {
-
-	r := `
-      Method: `
-
-	r += fmt.Sprintf("<l text:'%v' tip:'Copy function name' action:'copy %v'>", function.RawName, function.RawName) + `
-  Parameters: ` + cmn.TagLink(cmn.ICON_COPY, "copy "+hexutil.Encode(data[4:]), "Copy data") + ` 
-`
-	// Decode the parameters of the function
-	args := data[4:] // The rest of the data contains the function parameters
-	params, err := function.Inputs.Unpack(args)
-	if err != nil {
-		return r + "(error unpacking)"
-	}
-
-	// Display the parameters
-	for i, param := range params {
-		r += printValue(function.Inputs[i].Name, parsedABI, function, function.Inputs[i].Type, param, 0)
-	}
-
-	return r
-}
-
-func printValue(name string, parsedABI abi.ABI, function abi.Method, v abi.Type, param interface{}, indent int) string {
-	r := fmt.Sprintf("%s%12s: ", strings.Repeat(" ", indent*2), name)
-
-	switch v.T {
-	case abi.IntTy, abi.UintTy:
-		s := fmt.Sprintf("%v", param)
-		r += cmn.TagLink(s, "copy "+s, "Copy value") + "\n"
-	case abi.BoolTy:
-		r += fmt.Sprintf("%t\n", param)
-	case abi.StringTy:
-		r += fmt.Sprintf("%q\n", param)
-	case abi.AddressTy:
-
-		name := ""
-		if cmn.CurrentWallet != nil {
-			a := cmn.CurrentWallet.GetAddress(param.(common.Address))
-			if a != nil {
-				name = a.Name
-			} else {
-				c := cmn.CurrentWallet.GetContract(param.(common.Address))
-				if c != nil {
-					name = c.Name
-				}
-			}
-		}
-
-		address := param.(common.Address)
-		r += cmn.TagAddressShortLink(address) + " " + name + "\n"
-	case abi.FixedBytesTy, abi.BytesTy:
-		r += cmn.TagBytesLink(param.([]byte)) + "\n"
-	case abi.SliceTy, abi.ArrayTy:
-		// Handling array/slice of values
-		v := reflect.ValueOf(param)
-		if v.Len() > 0 &&
-			(v.Index(0).Kind() == reflect.Uint8 ||
-				v.Index(0).Kind() == reflect.Int8) {
-			// If it's a byte array, display as hex string
-			bytesValue := param.([]byte)
-			r += formatBytesAsHex(bytesValue)
-		} else {
-			r += "["
-			for j := 0; j < v.Len(); j++ {
-				if j > 0 {
-					r += ", "
-				}
-				elem := v.Index(j)
-				if elem.Kind() == reflect.Slice && elem.Type().Elem().Kind() == reflect.Uint8 {
-					var f abi.Method
-					found := false
-					if function.RawName == "multicall" && len(elem.Interface().([]byte)) > 4 {
-						// Match the function selector to a function in the ABI
-						functionSelector := elem.Interface().([]byte)[:4]
-
-						log.Debug().Msgf("functionSelector: %s", hexutil.Encode(functionSelector))
-
-						for _, method := range parsedABI.Methods {
-
-							log.Debug().Msgf("method.ID: %s name: %s", hexutil.Encode(method.ID), method.Name)
-
-							if bytes.Equal(functionSelector, method.ID) {
-								f = method
-								found = true
-								break
-							}
-						}
-					}
-
-					if found {
-						r += functionDetails(parsedABI, f, elem.Interface().([]byte))
-
-					} else {
-						r += formatBytesAsHex(elem.Interface().([]byte))
-					}
-				} else {
-					r += fmt.Sprintf("%v", elem.Interface())
-				}
-			}
-			r += strings.Repeat(" ", 14+indent*2) + "]\n"
-		}
-	case abi.TupleTy:
-		// Handling tuples, including structs
-		r += v.TupleRawName + "{\n"
-
-		param_v := reflect.ValueOf(param)
-		for j := 0; j < param_v.NumField(); j++ {
-			field := param_v.Field(j)
-			r += printValue(
-				v.TupleRawNames[j],
-				parsedABI, function, *v.TupleElems[j], field.Interface(), indent+1)
-		}
-		r, _ = strings.CutSuffix(r, "\n")
-		r += " }\n"
-	case abi.HashTy:
-		r += cmn.TagBytesLink(param.([]byte)) + "\n"
-	case abi.FixedPointTy:
-		r += fmt.Sprintf("%v (FixedPoint)\n", param)
-	case abi.FunctionTy:
-		functionData := param.([24]byte)
-		r += fmt.Sprintf("0x%x\n", functionData)
-	default:
-		r += "(unknown type)\n"
-	}
-	return r
-}
-func formatBytesAsHex(bytesValue []byte) string {
-	MAX_IN_LINE := 16
-
-	result := "\n"
-
-	for i := 0; i < len(bytesValue); i += MAX_IN_LINE {
-		end := i + MAX_IN_LINE
-		if end > len(bytesValue) {
-			end = len(bytesValue)
-		}
-
-		line := "0x"
-		if i > 0 {
-			line = ""
-			result += "\n  "
-		}
-		result += line + fmt.Sprintf("%x", bytesValue[i:end])
-	}
-
-	return result
-}
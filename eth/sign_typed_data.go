@@ -35,6 +35,10 @@ func signTypedDataV4(msg *bus.Message) (string, error) {
 		return "", fmt.Errorf("signer not found")
 	}
 
+	if err := enforcePolicies(msg, w.CheckTypedDataPolicies(a.Address, req.TypedData)); err != nil {
+		return "", err
+	}
+
 	OK := false
 	var sign string
 	var err error
@@ -0,0 +1,130 @@
+package eth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/AlexNa-Holdings/web3pro/cmn"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/rs/zerolog/log"
+)
+
+// gas limit used when the estimation fails because the transaction reverts
+const REVERT_GAS_LIMIT = 1_000_000
+
+type TxSimulation struct {
+	Reverted bool
+	Reason   string // decoded revert reason
+	Error    string // the node could not run the call, the tx is not known to revert
+	Override bool   // the user accepted to send a reverting tx
+	Preview  *TxPreview
+}
+
+// Blocked returns true if the tx is known to revert and not overridden
+func (s *TxSimulation) Blocked() bool {
+	return s != nil && s.Reverted && !s.Override
+}
+
+// SimulateTx runs the transaction with eth_call at the pending state and,
+// if it succeeds, previews the balance changes of from
+func SimulateTx(b *cmn.Blockchain, from common.Address, to common.Address, amount *big.Int, data []byte) *TxSimulation {
+	client, err := getEthClient(b)
+	if err != nil {
+		return &TxSimulation{Error: err.Error()}
+	}
+
+	_, err = client.PendingCallContract(context.Background(), ethereum.CallMsg{
+		From:  from,
+		To:    &to,
+		Value: amount,
+		Data:  data,
+	})
+	if err != nil {
+		if !IsRevert(err) {
+			log.Debug().Err(err).Msg("SimulateTx: call failed")
+			return &TxSimulation{Error: err.Error()}
+		}
+		return &TxSimulation{Reverted: true, Reason: RevertReason(err, to)}
+	}
+
+	preview, err := PreviewTx(b, from, to, amount, data)
+	if err != nil {
+		log.Debug().Err(err).Msg("SimulateTx: no balance preview")
+	}
+
+	return &TxSimulation{Preview: preview}
+}
+
+// IsRevert tells an execution revert from the transport and node errors
+// (timeouts, rate limits, unsupported block tags)
+func IsRevert(err error) bool {
+	var de rpc.DataError
+	if errors.As(err, &de) {
+		if s, ok := de.ErrorData().(string); ok {
+			if _, derr := hexutil.Decode(s); derr == nil {
+				return true
+			}
+		}
+	}
+	return strings.Contains(strings.ToLower(err.Error()), "execution reverted")
+}
+
+// RevertReason decodes the revert data of the node error, if any
+func RevertReason(err error, to common.Address) string {
+	var de rpc.DataError
+	if errors.As(err, &de) {
+		if s, ok := de.ErrorData().(string); ok {
+			if data, derr := hexutil.Decode(s); derr == nil && len(data) >= 4 {
+				return DecodeRevert(data, to)
+			}
+		}
+	}
+	return err.Error()
+}
+
+// DecodeRevert decodes Error(string), Panic(uint256) and the custom errors
+// found in the contract ABI, the embedded ABIs or the downloaded contracts
+func DecodeRevert(data []byte, to common.Address) string {
+	if len(data) < 4 {
+		return "reverted without a reason"
+	}
+
+	if reason, err := abi.UnpackRevert(data); err == nil {
+		return reason
+	}
+
+	for _, a := range knownABIs(to, true) {
+		for _, e := range a.Errors {
+			if !bytes.Equal(e.ID[:4], data[:4]) {
+				continue
+			}
+
+			values, err := e.Unpack(data)
+			if err != nil {
+				continue
+			}
+
+			args := []string{}
+			if list, ok := values.([]interface{}); ok {
+				for i, v := range list {
+					arg := fmt.Sprintf("%v", v)
+					if i < len(e.Inputs) && e.Inputs[i].Name != "" {
+						arg = e.Inputs[i].Name + ": " + arg
+					}
+					args = append(args, arg)
+				}
+			}
+			return e.Name + "(" + strings.Join(args, ", ") + ")"
+		}
+	}
+
+	return "custom error " + hexutil.Encode(data[:4])
+}
@@ -0,0 +1,41 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+type testDataError struct {
+	msg  string
+	data interface{}
+}
+
+func (e *testDataError) Error() string          { return e.msg }
+func (e *testDataError) ErrorData() interface{} { return e.data }
+
+func TestIsRevert(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"revert data", &testDataError{"execution reverted", "0x08c379a0"}, true},
+		{"empty revert data", &testDataError{"reverted", "0x"}, true},
+		{"wrapped revert", fmt.Errorf("call: %w", &testDataError{"x", "0x1234abcd"}), true},
+		{"message only", errors.New("execution reverted"), true},
+		{"timeout", context.DeadlineExceeded, false},
+		{"rate limit", errors.New("429 Too Many Requests"), false},
+		{"pending tag", errors.New("unsupported block tag pending"), false},
+		{"data without revert", &testDataError{"rate limited", map[string]interface{}{"retry": 1}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRevert(tt.err); got != tt.want {
+				t.Errorf("IsRevert(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
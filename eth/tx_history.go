@@ -0,0 +1,135 @@
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/AlexNa-Holdings/web3pro/bus"
+	"github.com/AlexNa-Holdings/web3pro/cmn"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/rs/zerolog/log"
+)
+
+// recordTx adds the broadcast transaction to the wallet journal
+func recordTx(signedTx *types.Transaction, from common.Address, origin string) {
+	w := cmn.CurrentWallet
+	if w == nil {
+		return
+	}
+
+	r := &cmn.TxRecord{
+		Hash:    signedTx.Hash(),
+		ChainId: int(signedTx.ChainId().Int64()),
+		From:    from,
+		Nonce:   signedTx.Nonce(),
+		Value:   signedTx.Value(),
+		Origin:  origin,
+		Time:    time.Now(),
+		Status:  cmn.TX_STATUS_PENDING,
+	}
+
+	if signedTx.To() != nil {
+		r.To = *signedTx.To()
+		r.Method = MethodName(r.To, signedTx.Data())
+	} else {
+		r.Method = "deploy"
+	}
+
+	if err := w.AddTxRecord(r); err != nil {
+		log.Error().Err(err).Msg("recordTx: cannot save transaction")
+	}
+}
+
+// MethodName returns the name of the called method. The downloaded contract
+// ABI is tried first, then the embedded ABIs, the well known methods, the
+// 4-byte database and finally the raw selector.
+func MethodName(to common.Address, data []byte) string {
+	if len(data) == 0 {
+		return ""
+	}
+
+	if len(data) < 4 {
+		return hexutil.Encode(data)
+	}
+
+	selector := data[:4]
+
+	for _, a := range knownABIs(to, false) {
+		if m, err := a.MethodById(selector); err == nil {
+			return m.Name
+		}
+	}
+
+	for name, s := range cmn.KNOWN_SELECTORS {
+		if s == hexutil.Encode(selector) {
+			return name
+		}
+	}
+
+	if m, ok := SELECTOR_DB[hexutil.Encode(selector)]; ok {
+		return m.Name
+	}
+
+	return hexutil.Encode(selector)
+}
+
+func getTxReceipt(msg *bus.Message) (*bus.B_EthTxReceipt_Response, error) {
+	req, ok := msg.Data.(*bus.B_EthTxReceipt)
+	if !ok {
+		return nil, fmt.Errorf("invalid request: %v", msg.Data)
+	}
+
+	w := cmn.CurrentWallet
+	if w == nil {
+		return nil, errors.New("no wallet")
+	}
+
+	b := w.GetBlockchain(req.ChainId)
+	if b == nil {
+		return nil, fmt.Errorf("blockchain not found: %v", req.ChainId)
+	}
+
+	c, err := getEthClient(b)
+	if err != nil {
+		return nil, err
+	}
+
+	receipt, err := c.TransactionReceipt(context.Background(), req.Hash)
+	if errors.Is(err, ethereum.NotFound) && req.From != (common.Address{}) {
+		// once the nonce is used, the transaction is mined or will never be
+		n, nerr := c.NonceAt(context.Background(), req.From, nil)
+		if nerr == nil && n > req.Nonce {
+			receipt, err = c.TransactionReceipt(context.Background(), req.Hash) // mined meanwhile?
+			if errors.Is(err, ethereum.NotFound) {
+				return &bus.B_EthTxReceipt_Response{Dropped: true}, nil
+			}
+		}
+	}
+	if errors.Is(err, ethereum.NotFound) {
+		return &bus.B_EthTxReceipt_Response{}, nil // still pending
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	fee := new(big.Int).SetUint64(receipt.GasUsed)
+	if receipt.EffectiveGasPrice != nil {
+		fee.Mul(fee, receipt.EffectiveGasPrice)
+	} else {
+		fee = nil
+	}
+
+	return &bus.B_EthTxReceipt_Response{
+		Found:       true,
+		Success:     receipt.Status == types.ReceiptStatusSuccessful,
+		GasUsed:     receipt.GasUsed,
+		BlockNumber: receipt.BlockNumber.Uint64(),
+		Fee:         fee,
+	}, nil
+}
@@ -2,6 +2,7 @@ package eth
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/big"
 
@@ -24,7 +25,59 @@ func BalanceOf(b *cmn.Blockchain, t *cmn.Token, address common.Address) (*big.In
 	}
 }
 
+// signAndSendTx reserves the nonce, signs the transaction with the signer
+// of from and broadcasts it
+func signAndSendTx(msg *bus.Message, b *cmn.Blockchain, s *cmn.Signer, from *cmn.Address, tx *types.Transaction) (string, error) {
+	tx, err := reserveNonce(b, from.Address, tx)
+	if err != nil {
+		log.Error().Err(err).Msg("signAndSendTx: Cannot reserve nonce")
+		return "", err
+	}
+
+	res := msg.Fetch("signer", "sign-tx", &bus.B_SignerSignTx{
+		Type:      s.Type,
+		Name:      s.Name,
+		MasterKey: s.MasterKey,
+		Chain:     b.Name,
+		Tx:        tx,
+		From:      from.Address,
+		Path:      from.Path,
+	})
+
+	if res.Error != nil {
+		log.Error().Err(res.Error).Msg("signAndSendTx: Cannot sign tx")
+		releaseNonce(b.ChainId, from.Address, tx)
+		return "", res.Error
+	}
+
+	signedTx, ok := res.Data.(*types.Transaction)
+	if !ok {
+		log.Error().Msgf("signAndSendTx: Cannot convert to transaction. Data:(%v)", res.Data)
+		releaseNonce(b.ChainId, from.Address, tx)
+		return "", errors.New("cannot convert to transaction")
+	}
+
+	hash, err := SendSignedTx(signedTx)
+	if err != nil {
+		releaseNonce(b.ChainId, from.Address, tx)
+	}
+	return hash, err
+}
+
 func SendSignedTx(signedTx *types.Transaction) (string, error) {
+	return sendSignedTx(signedTx, "")
+}
+
+// sendSignedTx broadcasts the transaction and records it in the history
+// together with the dApp origin. A reserved nonce is released by the caller
+// if the broadcast fails.
+func sendSignedTx(signedTx *types.Transaction, origin string) (string, error) {
+
+	from, err := types.Sender(types.LatestSignerForChainID(signedTx.ChainId()), signedTx)
+	if err != nil {
+		log.Error().Err(err).Msgf("SendSignedTx: Cannot recover sender")
+		return "", err
+	}
 
 	chainId := int(signedTx.ChainId().Int64())
 	c, ok := cons[chainId]
@@ -37,7 +90,7 @@ func SendSignedTx(signedTx *types.Transaction) (string, error) {
 	acquireRateLimit(chainId)
 
 	// Send the transaction
-	err := c.SendTransaction(context.Background(), signedTx)
+	err = c.SendTransaction(context.Background(), signedTx)
 	handleRPCResult(chainId, err)
 	if err != nil {
 		log.Error().Err(err).Msgf("SendSignedTx: Cannot send transaction")
@@ -46,5 +99,8 @@ func SendSignedTx(signedTx *types.Transaction) (string, error) {
 
 	bus.Send("ui", "notify", fmt.Sprintf("Transaction sent: %s", signedTx.Hash().Hex()))
 
+	nonceSent(chainId, from, signedTx)
+	recordTx(signedTx, from, origin)
+
 	return signedTx.Hash().Hex(), nil
 }
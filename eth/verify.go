@@ -0,0 +1,97 @@
+package eth
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/AlexNa-Holdings/web3pro/bus"
+	"github.com/AlexNa-Holdings/web3pro/cmn"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// Signature verification. The EOA signatures are recovered with ecrecover,
+// the contract wallets (Safe and others) are asked with EIP-1271
+// isValidSignature.
+
+var EIP1271_MAGIC = []byte{0x16, 0x26, 0xba, 0x7e}
+
+// MessageHash returns the EIP-191 hash signed by personal_sign
+func MessageHash(data []byte) []byte {
+	return accounts.TextHash(data)
+}
+
+// ParseTypedData accepts the eth_signTypedData_v4 JSON
+func ParseTypedData(data []byte) (*apitypes.TypedData, error) {
+	td := &apitypes.TypedData{}
+	if err := json.Unmarshal(data, td); err != nil {
+		return nil, err
+	}
+
+	if td.PrimaryType == "" || len(td.Types) == 0 {
+		return nil, errors.New("not EIP-712 typed data")
+	}
+
+	return td, nil
+}
+
+// TypedDataHash returns the EIP-712 hash signed by eth_signTypedData_v4
+func TypedDataHash(td *apitypes.TypedData) ([]byte, error) {
+	hash, _, err := apitypes.TypedDataAndHash(*td)
+	return hash, err
+}
+
+// RecoverSigner returns the address of the 65 bytes signature, v is 0/1 or
+// 27/28
+func RecoverSigner(hash []byte, sig []byte) (common.Address, error) {
+	if len(sig) != 65 {
+		return common.Address{}, fmt.Errorf("invalid signature length: %d", len(sig))
+	}
+
+	s := bytes.Clone(sig)
+	if s[64] >= 27 {
+		s[64] -= 27
+	}
+
+	pub, err := crypto.SigToPub(hash, s)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	return crypto.PubkeyToAddress(*pub), nil
+}
+
+// IsValidSignature asks the contract with EIP-1271 isValidSignature
+func IsValidSignature(b *cmn.Blockchain, contract common.Address, hash []byte, sig []byte) (bool, error) {
+	data, err := ERC1271_ABI.Pack("isValidSignature", common.BytesToHash(hash), sig)
+	if err != nil {
+		return false, err
+	}
+
+	resp := bus.Fetch("eth", "call", &bus.B_EthCall{
+		ChainId: b.ChainId,
+		To:      contract,
+		Amount:  big.NewInt(0),
+		Data:    data,
+	})
+	if resp.Error != nil {
+		return false, errors.New(RevertReason(resp.Error, contract))
+	}
+
+	out, err := hexutil.Decode(resp.Data.(string))
+	if err != nil {
+		return false, err
+	}
+
+	if len(out) == 0 {
+		return false, errors.New("not a contract")
+	}
+
+	return len(out) >= 4 && bytes.Equal(out[:4], EIP1271_MAGIC), nil
+}
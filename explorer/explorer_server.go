@@ -6,6 +6,7 @@ import (
 	"github.com/AlexNa-Holdings/web3pro/bus"
 	"github.com/AlexNa-Holdings/web3pro/cmn"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/rs/zerolog/log"
 )
 
@@ -15,6 +16,7 @@ func Init() {
 
 type Explorer interface {
 	DownloadContract(w *cmn.Wallet, b *cmn.Blockchain, contract common.Address) (string, error)
+	GetLogs(b *cmn.Blockchain, address *common.Address, topics []common.Hash) ([]types.Log, error)
 }
 
 func Loop() {
@@ -44,9 +46,28 @@ func process(msg *bus.Message) {
 
 		err := download(m)
 		msg.Respond(nil, err)
+	case "get-logs":
+		m, ok := msg.Data.(*bus.B_ExplorerGetLogs)
+		if !ok {
+			log.Error().Msg("Loop: Invalid explorer get-logs data")
+			return
+		}
+
+		logs, err := getLogs(m)
+		msg.Respond(logs, err)
 	}
 }
 
+func getExplorer(b *cmn.Blockchain) Explorer {
+	switch b.ExplorerApiType {
+	case "etherscan":
+		return &EtherScanAPI{}
+	case "blockscout":
+		return &BlockscoutAPI{}
+	}
+	return nil
+}
+
 func download(m *bus.B_ExplorerDownloadContract) error {
 	w := cmn.CurrentWallet
 	if w == nil {
@@ -58,15 +79,7 @@ func download(m *bus.B_ExplorerDownloadContract) error {
 		return errors.New("no blockchain")
 	}
 
-	var ex Explorer
-
-	switch b.ExplorerApiType {
-	case "etherscan":
-		ex = &EtherScanAPI{}
-	case "blockscout":
-		ex = &BlockscoutAPI{}
-	}
-
+	ex := getExplorer(b)
 	if ex == nil {
 		return errors.New("no explorer")
 	}
@@ -95,3 +108,22 @@ func download(m *bus.B_ExplorerDownloadContract) error {
 
 	return nil
 }
+
+func getLogs(m *bus.B_ExplorerGetLogs) ([]types.Log, error) {
+	w := cmn.CurrentWallet
+	if w == nil {
+		return nil, errors.New("no wallet")
+	}
+
+	b := w.GetBlockchain(m.ChainId)
+	if b == nil {
+		return nil, errors.New("no blockchain")
+	}
+
+	ex := getExplorer(b)
+	if ex == nil {
+		return nil, errors.New("no explorer")
+	}
+
+	return ex.GetLogs(b, m.Address, m.Topics)
+}
@@ -0,0 +1,168 @@
+package explorer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/AlexNa-Holdings/web3pro/cmn"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/rs/zerolog/log"
+)
+
+// the logs API returns at most this many records per page
+const LOGS_PAGE_SIZE = 1000
+const LOGS_MAX_PAGES = 10
+
+type apiLog struct {
+	Address         string   `json:"address"`
+	Topics          []string `json:"topics"`
+	Data            string   `json:"data"`
+	BlockNumber     string   `json:"blockNumber"`
+	TransactionHash string   `json:"transactionHash"`
+	LogIndex        string   `json:"logIndex"`
+}
+
+func (e *EtherScanAPI) GetLogs(b *cmn.Blockchain, address *common.Address, topics []common.Hash) ([]types.Log, error) {
+	if b.ExplorerAPIUrl == "" {
+		return nil, errors.New("blockchain has no explorer API")
+	}
+
+	exu, _ := strings.CutSuffix(b.ExplorerAPIUrl, "/")
+	return getLogsAPI(exu, b.ExplorerAPIToken, address, topics)
+}
+
+// GetLogs uses the Etherscan compatible RPC API of Blockscout, the v2 API
+// can't filter the logs by topics
+func (e *BlockscoutAPI) GetLogs(b *cmn.Blockchain, address *common.Address, topics []common.Hash) ([]types.Log, error) {
+	if b.ExplorerAPIUrl == "" {
+		return nil, errors.New("blockchain has no explorer API")
+	}
+
+	exu, _ := strings.CutSuffix(b.ExplorerAPIUrl, "/")
+	exu, _ = strings.CutSuffix(exu, "/v2")
+	return getLogsAPI(exu, b.ExplorerAPIToken, address, topics)
+}
+
+func getLogsAPI(exu string, token string, address *common.Address, topics []common.Hash) ([]types.Log, error) {
+	query := "module=logs&action=getLogs&fromBlock=0&toBlock=latest"
+	if address != nil {
+		query += "&address=" + address.Hex()
+	}
+
+	prev := -1
+	for i, t := range topics {
+		if t == (common.Hash{}) {
+			continue
+		}
+		query += fmt.Sprintf("&topic%d=%s", i, t.Hex())
+		if prev >= 0 {
+			query += fmt.Sprintf("&topic%d_%d_opr=and", prev, i)
+		}
+		prev = i
+	}
+
+	if token != "" {
+		query += "&apikey=" + token
+	}
+
+	logs := []types.Log{}
+	for page := 1; page <= LOGS_MAX_PAGES; page++ {
+		URL := fmt.Sprintf("%s?%s&page=%d&offset=%d", exu, query, page, LOGS_PAGE_SIZE)
+
+		log.Trace().Msgf("Getting logs from %s", URL)
+
+		list, err := getLogsPage(URL)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, l := range list {
+			tl, err := l.toLog()
+			if err != nil {
+				log.Debug().Err(err).Msg("getLogsAPI: invalid log")
+				continue
+			}
+			logs = append(logs, tl)
+		}
+
+		if len(list) < LOGS_PAGE_SIZE {
+			return logs, nil
+		}
+	}
+
+	log.Warn().Msgf("getLogsAPI: more than %d logs, the rest is ignored", LOGS_PAGE_SIZE*LOGS_MAX_PAGES)
+	return logs, nil
+}
+
+func getLogsPage(URL string) ([]apiLog, error) {
+	resp, err := http.Get(URL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Status  string          `json:"status"`
+		Message string          `json:"message"`
+		Result  json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		log.Error().Err(err).Msg("Error parsing JSON response")
+		return nil, err
+	}
+
+	list := []apiLog{}
+	if err := json.Unmarshal(result.Result, &list); err != nil {
+		// the error text comes in the result
+		if result.Status != "1" && !strings.HasPrefix(result.Message, "No records") {
+			return nil, fmt.Errorf("API error: %s %s", result.Message, string(result.Result))
+		}
+		return list, nil
+	}
+
+	return list, nil
+}
+
+func (l *apiLog) toLog() (types.Log, error) {
+	if !common.IsHexAddress(l.Address) {
+		return types.Log{}, fmt.Errorf("invalid address: %s", l.Address)
+	}
+
+	data, err := hexutil.Decode(l.Data)
+	if err != nil {
+		return types.Log{}, err
+	}
+
+	r := types.Log{
+		Address: common.HexToAddress(l.Address),
+		Data:    data,
+		TxHash:  common.HexToHash(l.TransactionHash),
+	}
+
+	for _, t := range l.Topics {
+		if t != "" {
+			r.Topics = append(r.Topics, common.HexToHash(t))
+		}
+	}
+
+	if n, err := hexutil.DecodeUint64(l.BlockNumber); err == nil {
+		r.BlockNumber = n
+	}
+
+	if n, err := hexutil.DecodeUint64(l.LogIndex); err == nil {
+		r.Index = uint(n)
+	}
+
+	return r, nil
+}
@@ -0,0 +1,112 @@
+package history
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/AlexNa-Holdings/web3pro/bus"
+	"github.com/AlexNa-Holdings/web3pro/cmn"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/rs/zerolog/log"
+)
+
+var trackMutex sync.Mutex
+
+func Init() {
+	go Loop()
+}
+
+func Loop() {
+	ch := bus.Subscribe("timer")
+	for msg := range ch {
+		if msg.RespondTo != 0 {
+			continue // ignore responses
+		}
+		go process(msg)
+	}
+}
+
+func process(msg *bus.Message) {
+	switch msg.Topic {
+	case "timer":
+		switch msg.Type {
+		case "tick-10sec":
+			track()
+		}
+	}
+}
+
+// track polls the receipts of the pending transactions
+func track() {
+	if !trackMutex.TryLock() {
+		return // previous round is still running
+	}
+	defer trackMutex.Unlock()
+
+	w := cmn.CurrentWallet
+	if w == nil {
+		return
+	}
+
+	changed := false
+	for _, r := range w.PendingTxRecords() {
+		res := bus.Fetch("eth", "get-tx-receipt", &bus.B_EthTxReceipt{
+			ChainId: r.ChainId,
+			Hash:    r.Hash,
+			From:    r.From,
+			Nonce:   r.Nonce,
+		})
+		if res.Error != nil {
+			log.Debug().Err(res.Error).Msgf("track: cannot get receipt for %s", r.Hash.Hex())
+			continue
+		}
+
+		receipt, ok := res.Data.(*bus.B_EthTxReceipt_Response)
+		if ok && receipt.Dropped {
+			status := ""
+			err := w.UpdateTxRecord(r.Hash, func(r *cmn.TxRecord) {
+				r.Status = cmn.TX_STATUS_DROPPED
+				if r.ReplacedBy != (common.Hash{}) {
+					r.Status = cmn.TX_STATUS_REPLACED
+				}
+				status = r.Status
+			})
+			if err == nil {
+				bus.Send("ui", "notify", fmt.Sprintf("Transaction %s: %s", status, r.Hash.Hex()))
+				changed = true
+			}
+			continue
+		}
+
+		if !ok || !receipt.Found {
+			continue
+		}
+
+		err := w.UpdateTxRecord(r.Hash, func(r *cmn.TxRecord) {
+			r.GasUsed = receipt.GasUsed
+			r.Block = receipt.BlockNumber
+			r.Fee = receipt.Fee
+			r.Status = cmn.TX_STATUS_REVERTED
+			if receipt.Success {
+				r.Status = cmn.TX_STATUS_CONFIRMED
+			}
+		})
+		if err != nil {
+			continue // dropped from the journal meanwhile
+		}
+
+		if receipt.Success {
+			bus.Send("ui", "notify", fmt.Sprintf("Transaction confirmed: %s", r.Hash.Hex()))
+		} else {
+			bus.Send("ui", "notify-error", fmt.Sprintf("Transaction reverted: %s", r.Hash.Hex()))
+		}
+		bus.Send("sound", "play", nil)
+		changed = true
+	}
+
+	if changed && w == cmn.CurrentWallet {
+		if err := w.Save(); err != nil {
+			log.Error().Err(err).Msg("track: cannot save wallet")
+		}
+	}
+}
@@ -71,7 +71,10 @@ func signTx(msg *bus.Message) (*types.Transaction, error) {
 	sig = append(sig, reply[1:]...) // R + S
 	sig = append(sig, reply[0])     // V
 
-	// sig[64] -= byte(b.ChainID*2 + 35)
+	// the legacy V is chain_id*2+35+parity, cut to one byte
+	if m.Tx.Type() == types.LegacyTxType {
+		sig[64] -= byte(b.ChainId*2 + 35)
+	}
 
 	signedTx, err := m.Tx.WithSignature(types.NewCancunSigner(big.NewInt(int64(b.ChainId))), sig)
 	if err != nil {
@@ -89,7 +92,7 @@ func serializeTxForLedger(tx *types.Transaction, chainID *big.Int) ([]byte, erro
 
 	switch tx.Type() {
 	case types.LegacyTxType:
-		// Legacy transaction
+		// Legacy transaction, EIP-155 signing form
 		txType = 0x00 // No type prefix
 		txData, err = rlp.EncodeToBytes([]interface{}{
 			tx.Nonce(),
@@ -98,6 +101,9 @@ func serializeTxForLedger(tx *types.Transaction, chainID *big.Int) ([]byte, erro
 			tx.To(),
 			tx.Value(),
 			tx.Data(),
+			chainID,
+			uint(0),
+			uint(0),
 		})
 	case types.AccessListTxType:
 		// EIP-2930 transaction
@@ -11,6 +11,7 @@ import (
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/rs/zerolog/log"
+	"google.golang.org/protobuf/proto"
 )
 
 func signTx(msg *bus.Message) (*types.Transaction, error) {
@@ -40,34 +41,43 @@ func signTx(msg *bus.Message) (*types.Transaction, error) {
 	ch_id := uint32(b.ChainId)
 	to := m.Tx.To().Hex()
 
-	request := &trezorproto.EthereumSignTxEIP1559{
-		AddressN:       dp,
-		Nonce:          new(big.Int).SetUint64(m.Tx.Nonce()).Bytes(),
-		MaxGasFee:      m.Tx.GasFeeCap().Bytes(),
-		MaxPriorityFee: m.Tx.GasTipCap().Bytes(),
-		ChainId:        &ch_id,
-		GasLimit:       new(big.Int).SetUint64(m.Tx.Gas()).Bytes(),
-		Value:          m.Tx.Value().Bytes(),
-		To:             &to,
-	}
-
 	data := m.Tx.Data()
 	length := uint32(len(data))
 
-	if to := m.Tx.To(); to != nil {
-		// Non contract deploy, set recipient explicitly
-		hex := to.Hex()
-		request.To = &hex
-	}
-
+	var initial_chunk []byte
 	if length > 1024 { // Send the data chunked if that was requested
-		request.DataInitialChunk, data = data[:1024], data[1024:]
+		initial_chunk, data = data[:1024], data[1024:]
 	} else {
-		request.DataInitialChunk, data = data, nil
+		initial_chunk, data = data, nil
 	}
 
-	request.DataLength = &length
-	request.ChainId = &ch_id // EIP-155 transaction, set chain ID explicitly (only 32 bit is supported!?)
+	var request proto.Message
+	if m.Tx.Type() == types.LegacyTxType {
+		request = &trezorproto.EthereumSignTx{
+			AddressN:         dp,
+			Nonce:            new(big.Int).SetUint64(m.Tx.Nonce()).Bytes(),
+			GasPrice:         m.Tx.GasPrice().Bytes(),
+			GasLimit:         new(big.Int).SetUint64(m.Tx.Gas()).Bytes(),
+			Value:            m.Tx.Value().Bytes(),
+			To:               &to,
+			DataInitialChunk: initial_chunk,
+			DataLength:       &length,
+			ChainId:          &ch_id, // EIP-155 transaction, set chain ID explicitly (only 32 bit is supported!?)
+		}
+	} else {
+		request = &trezorproto.EthereumSignTxEIP1559{
+			AddressN:         dp,
+			Nonce:            new(big.Int).SetUint64(m.Tx.Nonce()).Bytes(),
+			MaxGasFee:        m.Tx.GasFeeCap().Bytes(),
+			MaxPriorityFee:   m.Tx.GasTipCap().Bytes(),
+			ChainId:          &ch_id,
+			GasLimit:         new(big.Int).SetUint64(m.Tx.Gas()).Bytes(),
+			Value:            m.Tx.Value().Bytes(),
+			To:               &to,
+			DataInitialChunk: initial_chunk,
+			DataLength:       &length,
+		}
+	}
 
 	response := new(trezorproto.EthereumTxRequest)
 
@@ -108,6 +118,11 @@ func signTx(msg *bus.Message) (*types.Transaction, error) {
 	// signature := append(append(response.GetSignatureR(), response.GetSignatureS()...), v_bytes...)
 	signature := append(append(response.GetSignatureR(), response.GetSignatureS()...), byte(response.GetSignatureV()))
 
+	// the legacy V is chain_id*2+35+parity, cut to 32 bits
+	if m.Tx.Type() == types.LegacyTxType {
+		signature[64] -= byte(ch_id*2 + 35)
+	}
+
 	//	signedTx, err := m.Tx.WithSignature(types.NewCancunSigner(big.NewInt(m.Tx.ChainId().Int64())), signature)
 	signedTx, err := m.Tx.WithSignature(types.NewCancunSigner(big.NewInt(int64(ch_id))), signature)
 	if err != nil {
@@ -13,6 +13,7 @@ import (
 	"github.com/AlexNa-Holdings/web3pro/eth"
 	"github.com/AlexNa-Holdings/web3pro/explorer"
 	"github.com/AlexNa-Holdings/web3pro/gocui"
+	"github.com/AlexNa-Holdings/web3pro/history"
 	"github.com/AlexNa-Holdings/web3pro/hw"
 	"github.com/AlexNa-Holdings/web3pro/lp_v2"
 	"github.com/AlexNa-Holdings/web3pro/lp_v3"
@@ -56,6 +57,7 @@ func main() {
 	lp_v3.Init()
 	lp_v4.Init()
 	staking.Init()
+	history.Init()
 
 	defer ui.Gui.Close()
 
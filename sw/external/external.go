@@ -0,0 +1,342 @@
+package external
+
+// External signer speaking the Clef account_* JSON-RPC API. The signer's
+// MasterKey holds the endpoint: an IPC socket path or an http(s)/ws(s) URL.
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/AlexNa-Holdings/web3pro/bus"
+	"github.com/AlexNa-Holdings/web3pro/cmn"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/rs/zerolog/log"
+)
+
+const SIGNER_TYPE = "external"
+
+var CONNECT_TIMEOUT = 5 * time.Second
+
+// signing waits for the user to approve the request in the external signer
+var SIGN_TIMEOUT = bus.BusHardTimeout
+
+type signTransactionResult struct {
+	Raw hexutil.Bytes      `json:"raw"`
+	Tx  *types.Transaction `json:"tx"`
+}
+
+func Loop() {
+	ch := bus.Subscribe("signer")
+	for msg := range ch {
+		if msg.RespondTo != 0 {
+			continue // ignore responses
+		}
+		go process(msg)
+	}
+}
+
+func process(msg *bus.Message) {
+	w := cmn.CurrentWallet
+	if w == nil {
+		msg.Respond(nil, errors.New("no wallet"))
+		return
+	}
+
+	switch msg.Topic {
+	case "signer":
+		switch msg.Type {
+		case "is-connected":
+			m, ok := msg.Data.(*bus.B_SignerIsConnected)
+			if !ok {
+				log.Error().Msg("Loop: Invalid external is-connected data")
+				return
+			}
+
+			if m.Type == SIGNER_TYPE {
+				s := w.GetSigner(m.Name)
+				if s == nil {
+					msg.Respond(&bus.B_SignerIsConnected_Response{Connected: false}, nil)
+					return
+				}
+
+				_, err := Version(s.MasterKey)
+				msg.Respond(&bus.B_SignerIsConnected_Response{Connected: err == nil}, nil)
+			}
+
+		case "get-addresses":
+			m, ok := msg.Data.(*bus.B_SignerGetAddresses)
+			if !ok {
+				log.Error().Msg("Loop: Invalid external get-addresses data")
+				return
+			}
+
+			if m.Type == SIGNER_TYPE {
+				list, err := List(m.MasterKey)
+				if err != nil {
+					log.Error().Msgf("Error listing accounts: %v", err)
+					msg.Respond(&bus.B_SignerGetAddresses_Response{}, err)
+					return
+				}
+
+				a := []common.Address{}
+				p := []string{}
+				for i := m.StartFrom; i < len(list) && i < m.StartFrom+m.Count; i++ {
+					a = append(a, list[i])
+					p = append(p, "")
+				}
+
+				msg.Respond(&bus.B_SignerGetAddresses_Response{
+					Addresses: a,
+					Paths:     p,
+				}, nil)
+			}
+		case "sign-tx":
+			m, ok := msg.Data.(*bus.B_SignerSignTx)
+			if !ok {
+				log.Error().Msg("Loop: Invalid external sign-tx data")
+				msg.Respond(nil, errors.New("invalid data"))
+				return
+			}
+
+			if m.Type == SIGNER_TYPE {
+				b := w.GetBlockchainByName(m.Chain)
+				if b == nil {
+					log.Error().Msgf("Error getting blockchain: %v", m.Chain)
+					msg.Respond(nil, fmt.Errorf("external: blockchain not found: %v", m.Chain))
+					return
+				}
+
+				tx, err := SignTx(m.MasterKey, m.From, m.Tx, big.NewInt(int64(b.ChainId)))
+				if err != nil {
+					log.Error().Msgf("Error signing transaction: %v", err)
+					msg.Respond(nil, err)
+					return
+				}
+
+				msg.Respond(tx, nil)
+			}
+		case "sign-typed-data-v4":
+			m, ok := msg.Data.(*bus.B_SignerSignTypedData_v4)
+			if !ok {
+				log.Error().Msg("Loop: Invalid external sign-typed-data-v4 data")
+				msg.Respond(nil, errors.New("invalid data"))
+				return
+			}
+
+			if m.Type == SIGNER_TYPE {
+				signature, err := SignTypedData(m.MasterKey, m.Address, m.TypedData)
+				if err != nil {
+					log.Error().Msgf("Error signing typed data: %v", err)
+					msg.Respond(nil, err)
+					return
+				}
+
+				msg.Respond(signature, nil)
+			}
+		case "sign":
+			m, ok := msg.Data.(*bus.B_SignerSign)
+			if !ok {
+				log.Error().Msg("Loop: Invalid external sign data")
+				msg.Respond(nil, errors.New("invalid data"))
+				return
+			}
+
+			if m.Type == SIGNER_TYPE {
+				signature, err := SignData(m.MasterKey, m.Address, m.Data)
+				if err != nil {
+					log.Error().Msgf("Error signing data: %v", err)
+					msg.Respond(nil, err)
+					return
+				}
+
+				msg.Respond(signature, nil)
+			}
+		}
+	}
+}
+
+func call(endpoint string, timeout time.Duration, result any, method string, args ...any) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	client, err := rpc.DialContext(ctx, endpoint)
+	if err != nil {
+		return fmt.Errorf("external: error connecting to %s: %w", endpoint, err)
+	}
+	defer client.Close()
+
+	if err := client.CallContext(ctx, result, method, args...); err != nil {
+		return fmt.Errorf("external: %s: %w", method, err)
+	}
+
+	return nil
+}
+
+// List returns the accounts managed by the external signer
+func List(endpoint string) ([]common.Address, error) {
+	var res []common.Address
+	err := call(endpoint, SIGN_TIMEOUT, &res, "account_list")
+	return res, err
+}
+
+// Version returns the API version of the external signer. Unlike the
+// other calls it does not need an approval, so it is used to check the
+// connection.
+func Version(endpoint string) (string, error) {
+	var res string
+	err := call(endpoint, CONNECT_TIMEOUT, &res, "account_version")
+	return res, err
+}
+
+func SignTx(endpoint string, from common.Address, tx *types.Transaction, chainId *big.Int) (*types.Transaction, error) {
+	data := hexutil.Bytes(tx.Data())
+
+	args := &apitypes.SendTxArgs{
+		From:  common.NewMixedcaseAddress(from),
+		Gas:   hexutil.Uint64(tx.Gas()),
+		Value: hexutil.Big(*tx.Value()),
+		Nonce: hexutil.Uint64(tx.Nonce()),
+		Data:  &data,
+	}
+
+	if tx.To() != nil {
+		to := common.NewMixedcaseAddress(*tx.To())
+		args.To = &to
+	}
+
+	switch tx.Type() {
+	case types.LegacyTxType, types.AccessListTxType:
+		args.GasPrice = (*hexutil.Big)(tx.GasPrice())
+	case types.DynamicFeeTxType:
+		args.MaxFeePerGas = (*hexutil.Big)(tx.GasFeeCap())
+		args.MaxPriorityFeePerGas = (*hexutil.Big)(tx.GasTipCap())
+	default:
+		return nil, fmt.Errorf("external: unsupported tx type %d", tx.Type())
+	}
+
+	args.ChainID = (*hexutil.Big)(chainId)
+	if tx.Type() != types.LegacyTxType {
+		access_list := tx.AccessList()
+		args.AccessList = &access_list
+	}
+
+	var res signTransactionResult
+	if err := call(endpoint, SIGN_TIMEOUT, &res, "account_signTransaction", args); err != nil {
+		return nil, err
+	}
+
+	if res.Tx == nil {
+		return nil, errors.New("external: empty signed transaction")
+	}
+
+	// the external signer must not change what the user confirmed
+	st := res.Tx
+	if st.Type() != tx.Type() || st.Nonce() != tx.Nonce() || st.Gas() != tx.Gas() ||
+		st.Value().Cmp(tx.Value()) != 0 || !sameTo(st, tx) || !bytes.Equal(st.Data(), tx.Data()) ||
+		st.GasFeeCap().Cmp(tx.GasFeeCap()) != 0 || st.GasTipCap().Cmp(tx.GasTipCap()) != 0 {
+		return nil, errors.New("external: signed transaction does not match the request")
+	}
+
+	// nor sign for another chain or with another account
+	if st.ChainId().Cmp(chainId) != 0 {
+		return nil, fmt.Errorf("external: transaction signed for chain %v, expected %v", st.ChainId(), chainId)
+	}
+
+	sender, err := types.Sender(types.LatestSignerForChainID(chainId), st)
+	if err != nil {
+		return nil, fmt.Errorf("external: invalid signature: %w", err)
+	}
+
+	if sender != from {
+		return nil, fmt.Errorf("external: transaction signed by %s, expected %s", sender.Hex(), from.Hex())
+	}
+
+	log.Info().Msgf("Transaction signed: %s", res.Tx.Hash().Hex())
+	return res.Tx, nil
+}
+
+func sameTo(a, b *types.Transaction) bool {
+	if a.To() == nil || b.To() == nil {
+		return a.To() == nil && b.To() == nil
+	}
+	return *a.To() == *b.To()
+}
+
+// SignData signs a personal message (EIP-191, text/plain)
+func SignData(endpoint string, address common.Address, data []byte) (string, error) {
+	var res hexutil.Bytes
+	err := call(endpoint, SIGN_TIMEOUT, &res, "account_signData",
+		"text/plain", common.NewMixedcaseAddress(address), hexutil.Encode(data))
+	if err != nil {
+		return "", err
+	}
+
+	if err := checkSignature(accounts.TextHash(data), res, address); err != nil {
+		return "", err
+	}
+
+	return fixSignature(res), nil
+}
+
+func SignTypedData(endpoint string, address common.Address, typedData apitypes.TypedData) (string, error) {
+	var res hexutil.Bytes
+	err := call(endpoint, SIGN_TIMEOUT, &res, "account_signTypedData",
+		common.NewMixedcaseAddress(address), typedData)
+	if err != nil {
+		return "", err
+	}
+
+	hash, _, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		return "", err
+	}
+
+	if err := checkSignature(hash, res, address); err != nil {
+		return "", err
+	}
+
+	return fixSignature(res), nil
+}
+
+// checkSignature verifies the signature is made by the address
+func checkSignature(hash []byte, signature []byte, address common.Address) error {
+	if len(signature) != 65 {
+		return fmt.Errorf("external: invalid signature length %d", len(signature))
+	}
+
+	sig := bytes.Clone(signature)
+	if sig[64] >= 27 {
+		sig[64] -= 27
+	}
+
+	pub, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return fmt.Errorf("external: invalid signature: %w", err)
+	}
+
+	if signer := crypto.PubkeyToAddress(*pub); signer != address {
+		return fmt.Errorf("external: signed by %s, expected %s", signer.Hex(), address.Hex())
+	}
+
+	return nil
+}
+
+func fixSignature(signature []byte) string {
+	if len(signature) == 65 && (signature[64] == 0 || signature[64] == 1) {
+		signature[64] += 27
+	}
+
+	ss := fmt.Sprintf("0x%x", signature)
+	log.Info().Msgf("Signature: %s", ss)
+	return ss
+}
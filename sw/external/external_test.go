@@ -0,0 +1,247 @@
+package external
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"math/big"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// standIn serves the Clef account_* API the way clef does, or misbehaves as
+// set by the fields
+type standIn struct {
+	key        *ecdsa.PrivateKey
+	chainDelta int64    // signs for another chain
+	value      *big.Int // replaces the value
+}
+
+func (s *standIn) Version() string {
+	return "6.1.0"
+}
+
+func (s *standIn) List() []common.Address {
+	return []common.Address{crypto.PubkeyToAddress(s.key.PublicKey)}
+}
+
+func (s *standIn) SignTransaction(args apitypes.SendTxArgs) (*signTransactionResult, error) {
+	if s.value != nil {
+		args.Value = hexutil.Big(*s.value)
+	}
+
+	chainId := new(big.Int).Add(args.ChainID.ToInt(), big.NewInt(s.chainDelta))
+	args.ChainID = (*hexutil.Big)(chainId)
+
+	tx, err := args.ToTransaction()
+	if err != nil {
+		return nil, err
+	}
+
+	signed, err := types.SignTx(tx, types.LatestSignerForChainID(chainId), s.key)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := signed.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	return &signTransactionResult{Raw: raw, Tx: signed}, nil
+}
+
+func (s *standIn) SignData(contentType string, addr common.MixedcaseAddress, data hexutil.Bytes) (hexutil.Bytes, error) {
+	return s.sign(accounts.TextHash(data))
+}
+
+func (s *standIn) SignTypedData(addr common.MixedcaseAddress, typedData apitypes.TypedData) (hexutil.Bytes, error) {
+	hash, _, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		return nil, err
+	}
+	return s.sign(hash)
+}
+
+func (s *standIn) sign(hash []byte) (hexutil.Bytes, error) {
+	sig, err := crypto.Sign(hash, s.key)
+	if err != nil {
+		return nil, err
+	}
+	sig[64] += 27
+	return sig, nil
+}
+
+func startStandIn(t *testing.T, s *standIn) string {
+	t.Helper()
+
+	srv := rpc.NewServer()
+	if err := srv.RegisterName("account", s); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewServer(srv)
+	t.Cleanup(func() {
+		ts.Close()
+		srv.Stop()
+	})
+
+	return ts.URL
+}
+
+func newKey(t *testing.T) *ecdsa.PrivateKey {
+	t.Helper()
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestVersionAndList(t *testing.T) {
+	key := newKey(t)
+	endpoint := startStandIn(t, &standIn{key: key})
+
+	v, err := Version(endpoint)
+	if err != nil || v != "6.1.0" {
+		t.Fatalf("version %q, %v", v, err)
+	}
+
+	list, err := List(endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0] != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Fatalf("list %v", list)
+	}
+}
+
+func TestSignTx(t *testing.T) {
+	key := newKey(t)
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	to := common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	chainId := big.NewInt(10)
+
+	txs := map[string]*types.Transaction{
+		"legacy": types.NewTx(&types.LegacyTx{
+			Nonce:    1,
+			GasPrice: big.NewInt(1e9),
+			Gas:      21000,
+			To:       &to,
+			Value:    big.NewInt(1e15),
+		}),
+		"dynamic fee": types.NewTx(&types.DynamicFeeTx{
+			ChainID:   chainId,
+			Nonce:     2,
+			GasTipCap: big.NewInt(1e8),
+			GasFeeCap: big.NewInt(2e9),
+			Gas:       50000,
+			To:        &to,
+			Value:     big.NewInt(1),
+			Data:      []byte{0xa9, 0x05, 0x9c, 0xbb},
+		}),
+	}
+
+	tests := []struct {
+		name   string
+		signer *standIn
+		err    string
+	}{
+		{"valid", &standIn{key: key}, ""},
+		{"other account", &standIn{key: newKey(t)}, "signed by"},
+		{"other chain", &standIn{key: key, chainDelta: 1}, "signed for chain"},
+		{"changed value", &standIn{key: key, value: big.NewInt(5)}, "does not match"},
+	}
+
+	for tx_name, tx := range txs {
+		for _, tt := range tests {
+			t.Run(tx_name+" "+tt.name, func(t *testing.T) {
+				endpoint := startStandIn(t, tt.signer)
+
+				st, err := SignTx(endpoint, from, tx, chainId)
+				if tt.err != "" {
+					if err == nil || !strings.Contains(err.Error(), tt.err) {
+						t.Fatalf("error %v, want %q", err, tt.err)
+					}
+					return
+				}
+
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				sender, err := types.Sender(types.LatestSignerForChainID(chainId), st)
+				if err != nil || sender != from {
+					t.Fatalf("sender %s, %v", sender.Hex(), err)
+				}
+			})
+		}
+	}
+}
+
+func TestSignData(t *testing.T) {
+	key := newKey(t)
+	address := crypto.PubkeyToAddress(key.PublicKey)
+	msg := []byte("hello")
+
+	sig, err := SignData(startStandIn(t, &standIn{key: key}), address, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := checkSignature(accounts.TextHash(msg), hexutil.MustDecode(sig), address); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = SignData(startStandIn(t, &standIn{key: newKey(t)}), address, msg)
+	if err == nil || !strings.Contains(err.Error(), "signed by") {
+		t.Fatalf("error %v, want signed by", err)
+	}
+}
+
+func TestSignTypedData(t *testing.T) {
+	key := newKey(t)
+	address := crypto.PubkeyToAddress(key.PublicKey)
+
+	var td apitypes.TypedData
+	err := json.Unmarshal([]byte(`{
+		"types": {
+			"EIP712Domain": [{"name": "name", "type": "string"}, {"name": "chainId", "type": "uint256"}],
+			"Mail": [{"name": "to", "type": "address"}, {"name": "contents", "type": "string"}]
+		},
+		"primaryType": "Mail",
+		"domain": {"name": "test", "chainId": "10"},
+		"message": {"to": "0x00000000000000000000000000000000000000aa", "contents": "hi"}
+	}`), &td)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sig, err := SignTypedData(startStandIn(t, &standIn{key: key}), address, td)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash, _, err := apitypes.TypedDataAndHash(td)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := checkSignature(hash, hexutil.MustDecode(sig), address); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = SignTypedData(startStandIn(t, &standIn{key: newKey(t)}), address, td)
+	if err == nil || !strings.Contains(err.Error(), "signed by") {
+		t.Fatalf("error %v, want signed by", err)
+	}
+}
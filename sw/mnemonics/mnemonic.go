@@ -63,7 +63,7 @@ func process(msg *bus.Message) {
 			}
 
 			if m.Type == "mnemonics" {
-				mnemonics, err := NewFromSN(m.MasterKey)
+				mnemonics, err := newFromMessage(w, m.Name, m.MasterKey)
 				if err != nil {
 					log.Error().Msgf("Error creating mnemonics: %v", err)
 					msg.Respond(&bus.B_SignerGetAddresses_Response{}, err)
@@ -98,7 +98,7 @@ func process(msg *bus.Message) {
 					return
 				}
 
-				mnemonics, err := NewFromSN(m.MasterKey)
+				mnemonics, err := newFromMessage(w, m.Name, m.MasterKey)
 				if err != nil {
 					log.Error().Msgf("Error creating mnemonics: %v", err)
 					msg.Respond(nil, err)
@@ -123,7 +123,7 @@ func process(msg *bus.Message) {
 			}
 
 			if m.Type == "mnemonics" {
-				mnemonics, err := NewFromSN(m.MasterKey)
+				mnemonics, err := newFromMessage(w, m.Name, m.MasterKey)
 				if err != nil {
 					log.Error().Msgf("Error creating mnemonics: %v", err)
 					msg.Respond(nil, err)
@@ -149,7 +149,7 @@ func process(msg *bus.Message) {
 			}
 
 			if m.Type == "mnemonics" {
-				mnemonics, err := NewFromSN(m.MasterKey)
+				mnemonics, err := newFromMessage(w, m.Name, m.MasterKey)
 				if err != nil {
 					log.Error().Msgf("Error creating mnemonics: %v", err)
 					msg.Respond(nil, err)
@@ -170,7 +170,35 @@ func process(msg *bus.Message) {
 	}
 }
 
+// newFromMessage builds the key with the passphrase of the named signer. A
+// signer that cannot be found is an error: deriving without its passphrase
+// would sign from an unrelated account.
+func newFromMessage(w *cmn.Wallet, name string, SN string) (*Mnemonic, error) {
+	s := w.GetSigner(name)
+	if s == nil {
+		return nil, fmt.Errorf("signer not found: %s", name)
+	}
+
+	if s.MasterKey != SN {
+		return nil, fmt.Errorf("signer %s has a different master key", name)
+	}
+
+	passphrase, err := s.GetPassphrase()
+	if err != nil {
+		bus.Send("ui", "start_command", "signer unlock '"+s.Name+"'")
+		return nil, err
+	}
+
+	return NewFromSNWithPassphrase(SN, passphrase)
+}
+
 func NewFromSN(SN string) (*Mnemonic, error) {
+	return NewFromSNWithPassphrase(SN, "")
+}
+
+// NewFromSNWithPassphrase builds the master key from the stored entropy and
+// an optional BIP39 passphrase
+func NewFromSNWithPassphrase(SN string, passphrase string) (*Mnemonic, error) {
 	entropy, err := hex.DecodeString(SN)
 	if err != nil {
 		log.Error().Msgf("GetMasterKey: Error decoding entropy: %v", err)
@@ -183,7 +211,7 @@ func NewFromSN(SN string) (*Mnemonic, error) {
 		return nil, err
 	}
 
-	seed := bip39.NewSeed(mnemonics, "")
+	seed := bip39.NewSeed(mnemonics, passphrase)
 	masterKey, err := bip32.NewMasterKey(seed)
 	if err != nil {
 		log.Error().Msgf("GetMasterKey: Error creating master key: %v", err)
@@ -195,6 +223,50 @@ func NewFromSN(SN string) (*Mnemonic, error) {
 	}, nil
 }
 
+// Fingerprint returns the first address of the default derivation, so the
+// user can tell if the passphrase was mistyped
+func Fingerprint(SN string, passphrase string) (common.Address, error) {
+	m, err := NewFromSNWithPassphrase(SN, passphrase)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	a, _, err := m.GetAddresses(cmn.STANDARD_DERIVATIONS["default"].Format, 0, 1)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	return a[0], nil
+}
+
+// VerifyAddresses checks that the signer's addresses derive from the entropy
+// and passphrase. It returns the number of addresses checked.
+func VerifyAddresses(SN string, passphrase string, signer string, addresses []*cmn.Address) (int, error) {
+	m, err := NewFromSNWithPassphrase(SN, passphrase)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, a := range addresses {
+		if a.Signer != signer {
+			continue
+		}
+
+		key, err := DeriveKey(m.MasterKey, a.Path)
+		if err != nil {
+			return n, fmt.Errorf("address %s: %w", a.Name, err)
+		}
+
+		if GetAddressFromKey(key) != a.Address {
+			return n, fmt.Errorf("address %s (%s) does not match path %s", a.Name, a.Address.Hex(), a.Path)
+		}
+		n++
+	}
+
+	return n, nil
+}
+
 // deriveKey derives a key from the master key using the specified path
 func DeriveKey(masterKey *bip32.Key, path string) (*ecdsa.PrivateKey, error) {
 	// Parse the derivation path
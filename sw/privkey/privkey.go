@@ -0,0 +1,298 @@
+package privkey
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/AlexNa-Holdings/web3pro/bus"
+	"github.com/AlexNa-Holdings/web3pro/cmn"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+const SIGNER_TYPE = "privkey"
+
+// A privkey signer holds a single key, so it has no derivation path
+const KEY_PATH = "m"
+
+type PrivKey struct {
+	Key *ecdsa.PrivateKey
+}
+
+func Loop() {
+	ch := bus.Subscribe("signer")
+	for msg := range ch {
+		if msg.RespondTo != 0 {
+			continue // ignore responses
+		}
+		go process(msg)
+	}
+}
+
+func process(msg *bus.Message) {
+	w := cmn.CurrentWallet
+	if w == nil {
+		msg.Respond(nil, errors.New("no wallet"))
+		return
+	}
+
+	switch msg.Topic {
+	case "signer":
+		switch msg.Type {
+		case "is-connected":
+			m, ok := msg.Data.(*bus.B_SignerIsConnected)
+			if !ok {
+				log.Error().Msg("Loop: Invalid privkey is-connected data")
+				return
+			}
+
+			if m.Type == SIGNER_TYPE {
+				msg.Respond(&bus.B_SignerIsConnected_Response{Connected: true}, nil)
+			}
+
+		case "get-addresses":
+			m, ok := msg.Data.(*bus.B_SignerGetAddresses)
+			if !ok {
+				log.Error().Msg("Loop: Invalid privkey get-addresses data")
+				return
+			}
+
+			if m.Type == SIGNER_TYPE {
+				pk, err := NewFromSN(m.MasterKey)
+				if err != nil {
+					log.Error().Msgf("Error loading private key: %v", err)
+					msg.Respond(&bus.B_SignerGetAddresses_Response{}, err)
+					return
+				}
+
+				a, p := pk.GetAddresses(m.StartFrom, m.Count)
+				msg.Respond(&bus.B_SignerGetAddresses_Response{
+					Addresses: a,
+					Paths:     p,
+				}, nil)
+			}
+		case "sign-tx":
+			m, ok := msg.Data.(*bus.B_SignerSignTx)
+			if !ok {
+				log.Error().Msg("Loop: Invalid privkey sign-tx data")
+				msg.Respond(nil, errors.New("invalid data"))
+				return
+			}
+
+			if m.Type == SIGNER_TYPE {
+				b := w.GetBlockchainByName(m.Chain)
+				if b == nil {
+					log.Error().Msgf("Error getting blockchain: %v", m.Chain)
+					msg.Respond(nil, fmt.Errorf("privkey: blockchain not found: %v", m.Chain))
+					return
+				}
+
+				pk, err := NewFromSN(m.MasterKey)
+				if err != nil {
+					log.Error().Msgf("Error loading private key: %v", err)
+					msg.Respond(nil, err)
+					return
+				}
+
+				tx, err := pk.SignTx(int64(b.ChainId), m.Tx)
+				if err != nil {
+					log.Error().Msgf("Error signing transaction: %v", err)
+					msg.Respond(nil, err)
+					return
+				}
+
+				msg.Respond(tx, nil)
+			}
+		case "sign-typed-data-v4":
+			m, ok := msg.Data.(*bus.B_SignerSignTypedData_v4)
+			if !ok {
+				log.Error().Msg("Loop: Invalid privkey sign-typed-data-v4 data")
+				msg.Respond(nil, errors.New("invalid data"))
+				return
+			}
+
+			if m.Type == SIGNER_TYPE {
+				pk, err := NewFromSN(m.MasterKey)
+				if err != nil {
+					log.Error().Msgf("Error loading private key: %v", err)
+					msg.Respond(nil, err)
+					return
+				}
+
+				signature, err := pk.SignTypedData(m.TypedData)
+				if err != nil {
+					log.Error().Msgf("Error signing typed data: %v", err)
+					msg.Respond(nil, err)
+					return
+				}
+
+				msg.Respond(signature, nil)
+			}
+		case "sign":
+			m, ok := msg.Data.(*bus.B_SignerSign)
+			if !ok {
+				log.Error().Msg("Loop: Invalid privkey sign data")
+				msg.Respond(nil, errors.New("invalid data"))
+				return
+			}
+
+			if m.Type == SIGNER_TYPE {
+				pk, err := NewFromSN(m.MasterKey)
+				if err != nil {
+					log.Error().Msgf("Error loading private key: %v", err)
+					msg.Respond(nil, err)
+					return
+				}
+
+				signature, err := pk.Sign(m.Data)
+				if err != nil {
+					log.Error().Msgf("Error signing data: %v", err)
+					msg.Respond(nil, err)
+					return
+				}
+
+				msg.Respond(signature, nil)
+			}
+		}
+	}
+}
+
+// NewFromSN loads the key stored as the signer's MasterKey (hex, no 0x)
+func NewFromSN(SN string) (*PrivKey, error) {
+	key, err := crypto.HexToECDSA(SN)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PrivKey{Key: key}, nil
+}
+
+// ParseHex parses a raw hex private key, with or without 0x, and returns the
+// value to store as the signer's MasterKey
+func ParseHex(s string) (string, common.Address, error) {
+	s = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(s), "0x"), "0X")
+
+	key, err := crypto.HexToECDSA(s)
+	if err != nil {
+		return "", common.Address{}, fmt.Errorf("invalid private key: %w", err)
+	}
+
+	return hex.EncodeToString(crypto.FromECDSA(key)), crypto.PubkeyToAddress(key.PublicKey), nil
+}
+
+// ImportKeystore decrypts a keystore V3 JSON file and returns the value to
+// store as the signer's MasterKey
+func ImportKeystore(file string, pass string) (string, common.Address, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", common.Address{}, err
+	}
+
+	key, err := keystore.DecryptKey(data, pass)
+	if err != nil {
+		return "", common.Address{}, fmt.Errorf("error decrypting keystore: %w", err)
+	}
+
+	return hex.EncodeToString(crypto.FromECDSA(key.PrivateKey)), key.Address, nil
+}
+
+// ExportKeystore writes the key as a keystore V3 JSON file. An existing file
+// is never overwritten.
+func ExportKeystore(SN string, file string, pass string) (common.Address, error) {
+	pk, err := NewFromSN(SN)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	id, err := uuid.NewRandom()
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	key := &keystore.Key{
+		Id:         id,
+		Address:    pk.Address(),
+		PrivateKey: pk.Key,
+	}
+
+	data, err := keystore.EncryptKey(key, pass, keystore.StandardScryptN, keystore.StandardScryptP)
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return common.Address{}, err
+	}
+	defer f.Close()
+
+	if _, err := f.Write(data); err != nil {
+		return common.Address{}, err
+	}
+
+	return key.Address, f.Sync()
+}
+
+func (k *PrivKey) Address() common.Address {
+	return crypto.PubkeyToAddress(k.Key.PublicKey)
+}
+
+func (k *PrivKey) GetAddresses(start_from int, count int) ([]common.Address, []string) {
+	if start_from > 0 || count < 1 {
+		return []common.Address{}, []string{}
+	}
+	return []common.Address{k.Address()}, []string{KEY_PATH}
+}
+
+func (k *PrivKey) SignTx(chain_id int64, tx *types.Transaction) (*types.Transaction, error) {
+	signedTx, err := types.SignTx(tx, types.NewCancunSigner(big.NewInt(chain_id)), k.Key)
+	if err != nil {
+		log.Error().Msgf("SignTx: Failed to sign transaction: %v", err)
+		return nil, err
+	}
+
+	log.Info().Msgf("Transaction signed: %s", signedTx.Hash().Hex())
+	return signedTx, nil
+}
+
+func (k *PrivKey) SignTypedData(typedData apitypes.TypedData) (string, error) {
+	data, _, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		log.Error().Msgf("SignTypedData: Failed to hash typed data: %v", err)
+		return "", err
+	}
+
+	return k.signHash(data)
+}
+
+func (k *PrivKey) Sign(data []byte) (string, error) {
+	return k.signHash(accounts.TextHash(data))
+}
+
+func (k *PrivKey) signHash(hash []byte) (string, error) {
+	signature, err := crypto.Sign(hash, k.Key)
+	if err != nil {
+		log.Error().Msgf("Sign: Failed to sign hash: %v", err)
+		return "", err
+	}
+
+	// fix the signature
+	if len(signature) == 65 && (signature[64] == 0 || signature[64] == 1) {
+		signature[64] += 27
+	}
+
+	ss := fmt.Sprintf("0x%x", signature)
+	log.Info().Msgf("Signature: %s", ss)
+	return ss, nil
+}
@@ -0,0 +1,487 @@
+package slip39
+
+// SLIP-0039 Shamir's Secret-Sharing for mnemonic codes
+// https://github.com/satoshilabs/slips/blob/master/slip-0039.md
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	RADIX_BITS           = 10
+	ID_LENGTH_BITS       = 15
+	ITERATION_EXP_BITS   = 4
+	CHECKSUM_WORDS       = 3
+	METADATA_WORDS       = 2 + 2 + CHECKSUM_WORDS // id+exp, share params, checksum
+	MIN_STRENGTH_BYTES   = 16
+	MAX_SHARE_COUNT      = 16
+	BASE_ITERATION_COUNT = 10000
+	ROUND_COUNT          = 4
+	DIGEST_LENGTH        = 4
+	DIGEST_INDEX         = 254
+	SECRET_INDEX         = 255
+
+	// default iteration exponent, 10000 << 1 PBKDF2 iterations in total
+	ITERATION_EXPONENT = 1
+)
+
+var CUSTOMIZATION = []byte("shamir")
+var CUSTOMIZATION_EXTENDABLE = []byte("shamir_extendable")
+
+type Share struct {
+	Identifier        int
+	Extendable        bool
+	IterationExponent int
+	GroupIndex        int
+	GroupThreshold    int
+	GroupCount        int
+	MemberIndex       int
+	MemberThreshold   int
+	Value             []byte
+}
+
+type rawShare struct {
+	x    byte
+	data []byte
+}
+
+var expTable [255]byte
+var logTable [256]byte
+var wordIndex = map[string]int{}
+
+func init() {
+	// GF(256) with the Rijndael polynomial x^8 + x^4 + x^3 + x + 1
+	poly := 1
+	for i := 0; i < 255; i++ {
+		expTable[i] = byte(poly)
+		logTable[poly] = byte(i)
+
+		poly = (poly << 1) ^ poly
+		if poly&0x100 != 0 {
+			poly ^= 0x11B
+		}
+	}
+
+	for i, w := range WORDLIST {
+		wordIndex[w[:4]] = i
+	}
+}
+
+// Split encrypts the secret with the passphrase and splits it into count
+// single group shares, threshold of which are needed to recover it
+func Split(secret []byte, threshold int, count int, passphrase []byte) ([]string, error) {
+	if len(secret) < MIN_STRENGTH_BYTES || len(secret)%2 != 0 {
+		return nil, fmt.Errorf("secret must be at least %d bytes and have an even length", MIN_STRENGTH_BYTES)
+	}
+
+	if threshold < 1 || threshold > count || count > MAX_SHARE_COUNT {
+		return nil, fmt.Errorf("invalid threshold %d of %d (max %d shares)", threshold, count, MAX_SHARE_COUNT)
+	}
+
+	if threshold == 1 && count > 1 {
+		return nil, errors.New("multiple shares with a threshold of 1 are not allowed, use threshold 1 of 1")
+	}
+
+	var id_bytes [2]byte
+	if _, err := rand.Read(id_bytes[:]); err != nil {
+		return nil, err
+	}
+	id := int(binary.BigEndian.Uint16(id_bytes[:])) & (1<<ID_LENGTH_BITS - 1)
+
+	ems := encrypt(secret, passphrase, ITERATION_EXPONENT, id, true)
+
+	members, err := splitSecret(threshold, count, ems)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []string{}
+	for _, m := range members {
+		s := &Share{
+			Identifier:        id,
+			Extendable:        true,
+			IterationExponent: ITERATION_EXPONENT,
+			GroupIndex:        0,
+			GroupThreshold:    1,
+			GroupCount:        1,
+			MemberIndex:       int(m.x),
+			MemberThreshold:   threshold,
+			Value:             m.data,
+		}
+		res = append(res, s.Mnemonic())
+	}
+
+	return res, nil
+}
+
+// Combine recovers the secret from enough shares of enough groups
+func Combine(mnemonics []string, passphrase []byte) ([]byte, error) {
+	if len(mnemonics) == 0 {
+		return nil, errors.New("no shares provided")
+	}
+
+	shares := []*Share{}
+	for i, m := range mnemonics {
+		s, err := ParseShare(m)
+		if err != nil {
+			return nil, fmt.Errorf("share %d: %w", i+1, err)
+		}
+		shares = append(shares, s)
+	}
+
+	first := shares[0]
+	groups := map[int][]*Share{}
+
+	for _, s := range shares {
+		if s.Identifier != first.Identifier || s.Extendable != first.Extendable ||
+			s.IterationExponent != first.IterationExponent {
+			return nil, errors.New("shares belong to different secrets")
+		}
+
+		if s.GroupThreshold != first.GroupThreshold || s.GroupCount != first.GroupCount ||
+			len(s.Value) != len(first.Value) {
+			return nil, errors.New("shares have inconsistent parameters")
+		}
+
+		for _, o := range groups[s.GroupIndex] {
+			if o.MemberThreshold != s.MemberThreshold {
+				return nil, errors.New("shares of a group have different thresholds")
+			}
+			if o.MemberIndex == s.MemberIndex {
+				if !bytesEqual(o.Value, s.Value) {
+					return nil, errors.New("conflicting shares with the same index")
+				}
+				s = nil
+				break
+			}
+		}
+
+		if s != nil {
+			groups[s.GroupIndex] = append(groups[s.GroupIndex], s)
+		}
+	}
+
+	group_secrets := []rawShare{}
+	for gi, members := range groups {
+		threshold := members[0].MemberThreshold
+		if len(members) < threshold {
+			continue
+		}
+
+		raw := []rawShare{}
+		for _, m := range members[:threshold] {
+			raw = append(raw, rawShare{x: byte(m.MemberIndex), data: m.Value})
+		}
+
+		secret, err := recoverSecret(threshold, raw)
+		if err != nil {
+			return nil, fmt.Errorf("group %d: %w", gi+1, err)
+		}
+
+		group_secrets = append(group_secrets, rawShare{x: byte(gi), data: secret})
+	}
+
+	if len(group_secrets) < first.GroupThreshold {
+		return nil, fmt.Errorf("not enough shares, %d of %d groups complete (a group needs %d shares)",
+			len(group_secrets), first.GroupThreshold, first.MemberThreshold)
+	}
+
+	ems, err := recoverSecret(first.GroupThreshold, group_secrets[:first.GroupThreshold])
+	if err != nil {
+		return nil, err
+	}
+
+	return decrypt(ems, passphrase, first.IterationExponent, first.Identifier, first.Extendable), nil
+}
+
+func (s *Share) Indices() []int {
+	id_exp := s.Identifier << (ITERATION_EXP_BITS + 1)
+	if s.Extendable {
+		id_exp |= 1 << ITERATION_EXP_BITS
+	}
+	id_exp |= s.IterationExponent
+
+	params := s.GroupIndex<<16 | (s.GroupThreshold-1)<<12 | (s.GroupCount-1)<<8 |
+		s.MemberIndex<<4 | (s.MemberThreshold - 1)
+
+	data := []int{id_exp >> RADIX_BITS, id_exp & 1023, params >> RADIX_BITS, params & 1023}
+	data = append(data, bytesToIndices(s.Value, (len(s.Value)*8+RADIX_BITS-1)/RADIX_BITS)...)
+
+	return append(data, createChecksum(data, s.Extendable)...)
+}
+
+func (s *Share) Mnemonic() string {
+	words := []string{}
+	for _, i := range s.Indices() {
+		words = append(words, WORDLIST[i])
+	}
+	return strings.Join(words, " ")
+}
+
+// ParseShare decodes a share mnemonic. Words may be abbreviated to their
+// first four letters.
+func ParseShare(mnemonic string) (*Share, error) {
+	indices := []int{}
+	for _, w := range strings.Fields(strings.ToLower(mnemonic)) {
+		i, ok := -1, false
+		if len(w) >= 4 {
+			i, ok = wordIndex[w[:4]]
+		}
+		if !ok || !strings.HasPrefix(WORDLIST[i], w) {
+			return nil, fmt.Errorf("invalid word: %s", w)
+		}
+		indices = append(indices, i)
+	}
+
+	if len(indices) < METADATA_WORDS+(MIN_STRENGTH_BYTES*8+RADIX_BITS-1)/RADIX_BITS {
+		return nil, errors.New("mnemonic is too short")
+	}
+
+	padding := (RADIX_BITS * (len(indices) - METADATA_WORDS)) % 16
+	if padding > 8 {
+		return nil, errors.New("invalid mnemonic length")
+	}
+
+	id_exp := indices[0]<<RADIX_BITS | indices[1]
+	s := &Share{
+		Identifier:        id_exp >> (ITERATION_EXP_BITS + 1),
+		Extendable:        (id_exp>>ITERATION_EXP_BITS)&1 == 1,
+		IterationExponent: id_exp & (1<<ITERATION_EXP_BITS - 1),
+	}
+
+	if !verifyChecksum(indices, s.Extendable) {
+		return nil, errors.New("invalid checksum")
+	}
+
+	params := indices[2]<<RADIX_BITS | indices[3]
+	s.GroupIndex = params >> 16
+	s.GroupThreshold = (params>>12)&15 + 1
+	s.GroupCount = (params>>8)&15 + 1
+	s.MemberIndex = (params >> 4) & 15
+	s.MemberThreshold = params&15 + 1
+
+	if s.GroupCount < s.GroupThreshold {
+		return nil, errors.New("group threshold exceeds the number of groups")
+	}
+
+	value := indices[4 : len(indices)-CHECKSUM_WORDS]
+	v, err := indicesToBytes(value, (RADIX_BITS*len(value)-padding)/8)
+	if err != nil {
+		return nil, err
+	}
+	s.Value = v
+
+	return s, nil
+}
+
+func bytesToIndices(b []byte, count int) []int {
+	v := new(big.Int).SetBytes(b)
+	mask := big.NewInt(1023)
+
+	res := make([]int, count)
+	for i := count - 1; i >= 0; i-- {
+		res[i] = int(new(big.Int).And(v, mask).Int64())
+		v.Rsh(v, RADIX_BITS)
+	}
+	return res
+}
+
+func indicesToBytes(indices []int, length int) ([]byte, error) {
+	v := new(big.Int)
+	for _, i := range indices {
+		v.Lsh(v, RADIX_BITS)
+		v.Or(v, big.NewInt(int64(i)))
+	}
+
+	if v.BitLen() > length*8 {
+		return nil, errors.New("invalid mnemonic padding")
+	}
+
+	return v.FillBytes(make([]byte, length)), nil
+}
+
+func rs1024Polymod(values []int) int {
+	gen := [10]int{0xE0E040, 0x1C1C080, 0x3838100, 0x7070200, 0xE0E0009,
+		0x1C0C2412, 0x38086C24, 0x3090FC48, 0x21B1F890, 0x3F3F120}
+
+	chk := 1
+	for _, v := range values {
+		b := chk >> 20
+		chk = (chk&0xFFFFF)<<10 ^ v
+		for i := 0; i < 10; i++ {
+			if (b>>i)&1 != 0 {
+				chk ^= gen[i]
+			}
+		}
+	}
+	return chk
+}
+
+func customization(extendable bool) []int {
+	c := CUSTOMIZATION
+	if extendable {
+		c = CUSTOMIZATION_EXTENDABLE
+	}
+
+	res := []int{}
+	for _, b := range c {
+		res = append(res, int(b))
+	}
+	return res
+}
+
+func createChecksum(data []int, extendable bool) []int {
+	values := append(customization(extendable), data...)
+	values = append(values, 0, 0, 0)
+
+	p := rs1024Polymod(values) ^ 1
+	return []int{(p >> 20) & 1023, (p >> 10) & 1023, p & 1023}
+}
+
+func verifyChecksum(data []int, extendable bool) bool {
+	return rs1024Polymod(append(customization(extendable), data...)) == 1
+}
+
+func roundFunction(i int, passphrase []byte, e int, salt []byte, r []byte) []byte {
+	password := append([]byte{byte(i)}, passphrase...)
+	s := append(append([]byte{}, salt...), r...)
+	return pbkdf2.Key(password, s, (BASE_ITERATION_COUNT<<e)/ROUND_COUNT, len(r), sha256.New)
+}
+
+func getSalt(id int, extendable bool) []byte {
+	if extendable {
+		return []byte{}
+	}
+	return append(append([]byte{}, CUSTOMIZATION...), byte(id>>8), byte(id))
+}
+
+func feistel(data []byte, passphrase []byte, e int, id int, extendable bool, rounds []int) []byte {
+	half := len(data) / 2
+	l := append([]byte{}, data[:half]...)
+	r := append([]byte{}, data[half:]...)
+	salt := getSalt(id, extendable)
+
+	for _, i := range rounds {
+		f := roundFunction(i, passphrase, e, salt, r)
+		l, r = r, xorBytes(l, f)
+	}
+
+	return append(r, l...)
+}
+
+func encrypt(secret []byte, passphrase []byte, e int, id int, extendable bool) []byte {
+	return feistel(secret, passphrase, e, id, extendable, []int{0, 1, 2, 3})
+}
+
+func decrypt(ems []byte, passphrase []byte, e int, id int, extendable bool) []byte {
+	return feistel(ems, passphrase, e, id, extendable, []int{3, 2, 1, 0})
+}
+
+func xorBytes(a, b []byte) []byte {
+	res := make([]byte, len(a))
+	for i := range a {
+		res[i] = a[i] ^ b[i]
+	}
+	return res
+}
+
+func bytesEqual(a, b []byte) bool {
+	return hmac.Equal(a, b)
+}
+
+func createDigest(random_part []byte, secret []byte) []byte {
+	h := hmac.New(sha256.New, random_part)
+	h.Write(secret)
+	return h.Sum(nil)[:DIGEST_LENGTH]
+}
+
+func interpolate(shares []rawShare, x byte) []byte {
+	for _, s := range shares {
+		if s.x == x {
+			return append([]byte{}, s.data...)
+		}
+	}
+
+	log_prod := 0
+	for _, s := range shares {
+		log_prod += int(logTable[s.x^x])
+	}
+
+	res := make([]byte, len(shares[0].data))
+	for _, s := range shares {
+		log_basis := log_prod - int(logTable[s.x^x])
+		for _, o := range shares {
+			log_basis -= int(logTable[s.x^o.x])
+		}
+		log_basis = ((log_basis % 255) + 255) % 255
+
+		for i, y := range s.data {
+			if y != 0 {
+				res[i] ^= expTable[(int(logTable[y])+log_basis)%255]
+			}
+		}
+	}
+
+	return res
+}
+
+func splitSecret(threshold int, count int, secret []byte) ([]rawShare, error) {
+	if threshold == 1 {
+		res := []rawShare{}
+		for i := 0; i < count; i++ {
+			res = append(res, rawShare{x: byte(i), data: append([]byte{}, secret...)})
+		}
+		return res, nil
+	}
+
+	random_count := threshold - 2
+
+	shares := []rawShare{}
+	for i := 0; i < random_count; i++ {
+		d := make([]byte, len(secret))
+		if _, err := rand.Read(d); err != nil {
+			return nil, err
+		}
+		shares = append(shares, rawShare{x: byte(i), data: d})
+	}
+
+	random_part := make([]byte, len(secret)-DIGEST_LENGTH)
+	if _, err := rand.Read(random_part); err != nil {
+		return nil, err
+	}
+
+	base := append([]rawShare{}, shares...)
+	base = append(base,
+		rawShare{x: DIGEST_INDEX, data: append(createDigest(random_part, secret), random_part...)},
+		rawShare{x: SECRET_INDEX, data: secret})
+
+	for i := random_count; i < count; i++ {
+		shares = append(shares, rawShare{x: byte(i), data: interpolate(base, byte(i))})
+	}
+
+	return shares, nil
+}
+
+func recoverSecret(threshold int, shares []rawShare) ([]byte, error) {
+	if threshold == 1 {
+		return shares[0].data, nil
+	}
+
+	secret := interpolate(shares, SECRET_INDEX)
+	digest_share := interpolate(shares, DIGEST_INDEX)
+
+	if !hmac.Equal(digest_share[:DIGEST_LENGTH], createDigest(digest_share[DIGEST_LENGTH:], secret)) {
+		return nil, errors.New("invalid digest of the shared secret")
+	}
+
+	return secret, nil
+}
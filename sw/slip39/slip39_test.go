@@ -0,0 +1,215 @@
+package slip39
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/tyler-smith/go-bip32"
+)
+
+// testdata/vectors.json has the format of the SLIP-0039 test vectors
+// (https://github.com/trezor/python-shamir-mnemonic/blob/master/vectors.json):
+// description, mnemonics, master secret (empty if invalid), BIP32 master key.
+// The mnemonics are encrypted with the passphrase TREZOR.
+
+const VECTORS_PASSPHRASE = "TREZOR"
+
+type vector struct {
+	Description  string
+	Mnemonics    []string
+	MasterSecret string
+	Xprv         string
+}
+
+func loadVectors(t *testing.T) []vector {
+	t.Helper()
+
+	data, err := os.ReadFile("testdata/vectors.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var raw [][]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatal(err)
+	}
+
+	res := []vector{}
+	for i, r := range raw {
+		if len(r) != 4 {
+			t.Fatalf("vector %d has %d fields", i, len(r))
+		}
+
+		v := vector{}
+		for j, dst := range []interface{}{&v.Description, &v.Mnemonics, &v.MasterSecret, &v.Xprv} {
+			if err := json.Unmarshal(r[j], dst); err != nil {
+				t.Fatalf("vector %d: %v", i, err)
+			}
+		}
+		res = append(res, v)
+	}
+
+	return res
+}
+
+func TestVectors(t *testing.T) {
+	for _, v := range loadVectors(t) {
+		t.Run(v.Description, func(t *testing.T) {
+			secret, err := Combine(v.Mnemonics, []byte(VECTORS_PASSPHRASE))
+
+			if v.MasterSecret == "" {
+				if err == nil {
+					t.Fatalf("invalid shares accepted, secret %x", secret)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if hex.EncodeToString(secret) != v.MasterSecret {
+				t.Fatalf("secret %x, want %s", secret, v.MasterSecret)
+			}
+
+			if v.Xprv != "" {
+				key, err := bip32.NewMasterKey(secret)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if key.String() != v.Xprv {
+					t.Errorf("master key %s, want %s", key.String(), v.Xprv)
+				}
+			}
+
+			// the shares round trip through the parser
+			for _, m := range v.Mnemonics {
+				s, err := ParseShare(m)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if s.Mnemonic() != m {
+					t.Errorf("share %q encodes as %q", m, s.Mnemonic())
+				}
+			}
+		})
+	}
+}
+
+func TestAbbreviatedWords(t *testing.T) {
+	v := loadVectors(t)[0]
+
+	short := []string{}
+	for _, w := range strings.Fields(v.Mnemonics[0]) {
+		if len(w) > 4 {
+			w = w[:4]
+		}
+		short = append(short, strings.ToUpper(w))
+	}
+
+	secret, err := Combine([]string{strings.Join(short, " ")}, []byte(VECTORS_PASSPHRASE))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(secret) != v.MasterSecret {
+		t.Fatalf("secret %x, want %s", secret, v.MasterSecret)
+	}
+
+	if _, err := ParseShare("dumb " + v.Mnemonics[0]); err == nil {
+		t.Error("unknown word accepted")
+	}
+}
+
+func TestSplitCombine(t *testing.T) {
+	secrets := [][]byte{
+		bytes.Repeat([]byte{0x5a}, 16),
+		[]byte("0123456789abcdef0123456789abcdef"),
+	}
+	passphrase := []byte("correct horse")
+
+	tests := []struct{ threshold, count int }{
+		{1, 1},
+		{2, 2},
+		{2, 3},
+		{3, 5},
+		{5, 5},
+		{4, 16},
+	}
+
+	for _, secret := range secrets {
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("%d bytes %d of %d", len(secret), tt.threshold, tt.count), func(t *testing.T) {
+				shares, err := Split(secret, tt.threshold, tt.count, passphrase)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(shares) != tt.count {
+					t.Fatalf("%d shares, want %d", len(shares), tt.count)
+				}
+
+				// any threshold shares recover the secret, in any order
+				subsets := [][]string{
+					shares[:tt.threshold],
+					shares[tt.count-tt.threshold:],
+				}
+				reversed := []string{}
+				for i := tt.count - 1; i >= tt.count-tt.threshold; i-- {
+					reversed = append(reversed, shares[i])
+				}
+				subsets = append(subsets, reversed)
+
+				for _, subset := range subsets {
+					got, err := Combine(subset, passphrase)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if !bytes.Equal(got, secret) {
+						t.Fatalf("secret %x, want %x", got, secret)
+					}
+				}
+
+				if tt.threshold > 1 {
+					if _, err := Combine(shares[:tt.threshold-1], passphrase); err == nil {
+						t.Error("recovered with fewer shares than the threshold")
+					}
+				}
+
+				// a wrong passphrase gives another valid secret
+				got, err := Combine(shares[:tt.threshold], []byte("wrong"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if bytes.Equal(got, secret) {
+					t.Error("wrong passphrase recovered the secret")
+				}
+			})
+		}
+	}
+}
+
+func TestSplitInvalid(t *testing.T) {
+	tests := []struct {
+		name             string
+		secret           []byte
+		threshold, count int
+	}{
+		{"short secret", make([]byte, 14), 2, 3},
+		{"odd length", make([]byte, 17), 2, 3},
+		{"threshold over count", make([]byte, 16), 4, 3},
+		{"too many shares", make([]byte, 16), 2, MAX_SHARE_COUNT + 1},
+		{"threshold 1 of many", make([]byte, 16), 1, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Split(tt.secret, tt.threshold, tt.count, nil); err == nil {
+				t.Error("accepted")
+			}
+		})
+	}
+}
@@ -1,7 +1,13 @@
 package sw
 
-import "github.com/AlexNa-Holdings/web3pro/sw/mnemonics"
+import (
+	"github.com/AlexNa-Holdings/web3pro/sw/external"
+	"github.com/AlexNa-Holdings/web3pro/sw/mnemonics"
+	"github.com/AlexNa-Holdings/web3pro/sw/privkey"
+)
 
 func Init() {
 	go mnemonics.Loop()
+	go privkey.Loop()
+	go external.Loop()
 }
@@ -8,14 +8,14 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 )
 
-func DlgAddressAddWatch(addr string) *gocui.Popup {
+func DlgAddressAddWatch(addr string, name string) *gocui.Popup {
 	template := fmt.Sprintf(`
 Address: %s
-   Name: <input id:name size:32 value:"">
+   Name: <input id:name size:32 value:"%s">
     Tag: <input id:tag size:32 value:"">
    Type: (watch-only)
 
-<c><button text:Ok tip:"add watch-only address">  <button text:Cancel>`, addr)
+<c><button text:Ok tip:"add watch-only address">  <button text:Cancel>`, addr, name)
 
 	return &gocui.Popup{
 		Title: "Add Watch Address",
@@ -43,6 +43,12 @@ func DlgBlockchain(chain_id int) *gocui.Popup {
 		OnOpen: func(v *gocui.View) {
 			v.SetSelectList("explorer_api_type", cmn.EXPLORER_API_TYPES)
 			v.SetSelectList("rpc_rate_mode", []string{"auto", "fixed"})
+			v.SetSelectList("fee_preset", cmn.FEE_PRESETS)
+			v.SetInput("fee_preset", cmn.FEE_PRESET_NORMAL)
+			v.SetSelectList("tx_type", cmn.TX_TYPES)
+			v.SetInput("tx_type", cmn.TX_TYPE_AUTO)
+			v.SetSelectList("fee_model", cmn.FEE_MODELS)
+			v.SetInput("fee_model", cmn.FEE_MODEL_L1)
 			if b != nil {
 				v.SetInput("name", b.Name)
 				v.SetInput("short_name", b.ShortName)
@@ -72,6 +78,15 @@ func DlgBlockchain(chain_id int) *gocui.Popup {
 					currentRate = b.RPCRateLimit
 				}
 				v.SetInput("rpc_rate_limit", strconv.Itoa(currentRate))
+				if b.FeePreset != "" {
+					v.SetInput("fee_preset", b.FeePreset)
+				}
+				if b.TxType != "" {
+					v.SetInput("tx_type", b.TxType)
+				}
+				if b.FeeModel != "" {
+					v.SetInput("fee_model", b.FeeModel)
+				}
 			} else {
 				// New blockchain: default to auto mode with rate 5
 				v.SetInput("rpc_rate_mode", "auto")
@@ -143,6 +158,13 @@ func DlgBlockchain(chain_id int) *gocui.Popup {
 						}
 					}
 
+					fee_preset := v.GetInput("fee_preset")
+					tx_type := v.GetInput("tx_type")
+					if tx_type == cmn.TX_TYPE_AUTO {
+						tx_type = ""
+					}
+					fee_model := v.GetInput("fee_model")
+
 					wta := common.HexToAddress(wtoken_address)
 
 					var err error
@@ -161,6 +183,9 @@ func DlgBlockchain(chain_id int) *gocui.Popup {
 							Multicall:        common.HexToAddress(multicall),
 							RPCRateLimit:     rpcRateLimit,
 							RPCRateAuto:      rpcRateAuto,
+							FeePreset:        fee_preset,
+							TxType:           tx_type,
+							FeeModel:         fee_model,
 						})
 					} else {
 						var chainid int
@@ -189,6 +214,9 @@ func DlgBlockchain(chain_id int) *gocui.Popup {
 							Multicall:        common.HexToAddress(multicall),
 							RPCRateLimit:     rpcRateLimit,
 							RPCRateAuto:      rpcRateAuto,
+							FeePreset:        fee_preset,
+							TxType:           tx_type,
+							FeeModel:         fee_model,
 						})
 					}
 
@@ -212,6 +240,8 @@ func DlgBlockchain(chain_id int) *gocui.Popup {
 ` + chain_line + `
           Currency: <input id:currency size:16 value:"">
     RPC Rate Limit: <select id:rpc_rate_mode size:6> <input id:rpc_rate_limit size:4 value:""> calls/sec
+        Fee Preset: <select id:fee_preset size:8> Tx Type: <select id:tx_type size:8>
+         Fee Model: <select id:fee_model size:10>
 Wrapped Token Addr: <input id:wtoken_address size:43 value:""> 
 Multicall Contract: <input id:multicall size:43 value:"">
 <line text:Explorer> 
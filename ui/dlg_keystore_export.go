@@ -0,0 +1,67 @@
+package ui
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/AlexNa-Holdings/web3pro/cmn"
+	"github.com/AlexNa-Holdings/web3pro/gocui"
+	"github.com/AlexNa-Holdings/web3pro/sw/privkey"
+)
+
+func DlgKeystoreExport(s *cmn.Signer, file string) *gocui.Popup {
+	return &gocui.Popup{
+		Title: "Export Keystore " + s.Name,
+		OnClickHotspot: func(v *gocui.View, hs *gocui.Hotspot) {
+
+			if hs != nil {
+				switch hs.Value {
+				case "button Ok":
+					pass := v.GetInput("pass")
+					confirm := v.GetInput("confirm")
+
+					if pass == "" {
+						Notification.ShowError("Password cannot be empty")
+						break
+					}
+
+					if pass != confirm {
+						Notification.ShowError("Passwords do not match")
+						v.SetInput("confirm", "")
+						break
+					}
+
+					if err := os.MkdirAll(filepath.Dir(file), 0700); err != nil {
+						Notification.ShowErrorf("Error creating folder: %s", err)
+						break
+					}
+
+					Gui.HidePopup()
+					Printf("\nEncrypting keystore...\n")
+
+					// scrypt takes a while
+					go func() {
+						a, err := privkey.ExportKeystore(s.MasterKey, file, pass)
+						if err != nil {
+							Notification.ShowErrorf("Error exporting keystore: %s", err)
+							Printf("Error exporting keystore: %s\n", err)
+							return
+						}
+
+						Notification.Showf("Keystore for %s exported", a.Hex())
+						Printf("Keystore for %s written to: %s\n", a.Hex(), file)
+					}()
+
+				case "button Cancel":
+					Gui.HidePopup()
+				}
+			}
+		},
+		Template: `
+     File: ` + file + `
+ Password: <input id:pass masked:true size:24>
+  Confirm: <input id:confirm masked:true size:24>
+ <c>
+ <button text:Ok tip:"encrypt and save keystore">  <button text:Cancel>`,
+	}
+}
@@ -0,0 +1,84 @@
+package ui
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/AlexNa-Holdings/web3pro/bus"
+	"github.com/AlexNa-Holdings/web3pro/cmn"
+	"github.com/AlexNa-Holdings/web3pro/eth"
+	"github.com/AlexNa-Holdings/web3pro/gocui"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func DlgNFTSend(b *cmn.Blockchain, n *cmn.NFT, from *cmn.Address) *gocui.Popup {
+
+	collection := n.Collection
+	if collection == "" {
+		collection = "(unknown)"
+	}
+
+	amount := ""
+	standard := "ERC-721"
+	if n.IsERC1155() {
+		standard = "ERC-1155"
+		amount = `
+         Amount: <input id:amount size:24> of ` + n.Balance.String()
+	}
+
+	template := `
+     Blockchain: ` + b.Name + `
+     Collection: <b>` + collection + `</b> (` + standard + `)
+       Contract: ` + cmn.TagAddressShortLink(n.Contract) + ` 
+       Token ID: ` + n.TokenId.String() + `
+           From: ` + from.Name + `
+             To: <input id:to size:43> ` + amount + `
+ <c>
+ <button text:Ok tip:"transfer the token">  <button text:Cancel>`
+
+	return &gocui.Popup{
+		Title: "Send NFT",
+		OnOpen: func(v *gocui.View) {
+			if n.IsERC1155() {
+				v.SetInput("amount", "1")
+			}
+			v.SetFocus(1)
+		},
+		OnOverHotspot: cmn.StandardOnOverHotspot,
+		OnClickHotspot: func(v *gocui.View, hs *gocui.Hotspot) {
+
+			if hs != nil {
+				switch hs.Value {
+				case "button Ok":
+					to := v.GetInput("to")
+
+					if !common.IsHexAddress(to) && !eth.IsENSName(to) {
+						bus.Send("ui", "notify-error", fmt.Sprintf("Invalid address: %s", to))
+						return
+					}
+
+					command := "nft send " + from.Address.String() + " " + n.Contract.String() + " " + n.TokenId.String() + " " + to
+					if n.IsERC1155() {
+						amount := v.GetInput("amount")
+						val, ok := new(big.Int).SetString(amount, 10)
+						if !ok || val.Sign() <= 0 || val.Cmp(n.Balance) > 0 {
+							bus.Send("ui", "notify-error", fmt.Sprintf("Invalid amount: %s", amount))
+							return
+						}
+						command += " " + amount
+					}
+
+					bus.Send("ui", "command", command)
+
+					Gui.HidePopup()
+
+				case "button Cancel":
+					Gui.HidePopup()
+				default:
+					cmn.StandardOnClickHotspot(v, hs)
+				}
+			}
+		},
+		Template: template,
+	}
+}
@@ -0,0 +1,100 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/AlexNa-Holdings/web3pro/gocui"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+const (
+	QR_VERSION        = 6   // 41x41, the same size for all the frames
+	QR_FRAME_SIZE     = 110 // bytes of data in one frame
+	QR_FRAME_INTERVAL = 500 * time.Millisecond
+)
+
+// DlgQR shows the data as a QR code. The data too long for one code is split
+// into the frames "web3pro:I/N:PART" shown in a loop (animated QR).
+func DlgQR(title string, data []byte) *gocui.Popup {
+	frames, err := qrFrames(data)
+	page := 0
+	stop := make(chan bool)
+
+	template := func() string {
+		if err != nil {
+			return "\n <color fg:red>" + err.Error() + "</color>\n\n<c><button text:Close>"
+		}
+
+		t := "\n" + frames[page]
+		if len(frames) > 1 {
+			t += fmt.Sprintf("<c>Frame %d of %d\n", page+1, len(frames))
+		}
+		return t + "\n<c><button text:Close>"
+	}
+
+	return &gocui.Popup{
+		Title: title,
+		OnOpen: func(v *gocui.View) {
+			if len(frames) < 2 {
+				return
+			}
+
+			go func() {
+				ticker := time.NewTicker(QR_FRAME_INTERVAL)
+				defer ticker.Stop()
+
+				for {
+					select {
+					case <-stop:
+						return
+					case <-ticker.C:
+						page = (page + 1) % len(frames)
+						t := template()
+						Gui.UpdateAsync(func(*gocui.Gui) error {
+							v.RenderTemplate(t)
+							return nil
+						})
+					}
+				}
+			}()
+		},
+		OnClose: func(v *gocui.View) {
+			close(stop)
+		},
+		OnClickHotspot: func(v *gocui.View, hs *gocui.Hotspot) {
+			if hs != nil && hs.Value == "button Close" {
+				Gui.HidePopup()
+			}
+		},
+		Template: template(),
+	}
+}
+
+// qrFrames renders the codes as text, two modules per character
+func qrFrames(data []byte) ([]string, error) {
+	parts := []string{string(data)}
+
+	if len(data) > QR_FRAME_SIZE {
+		parts = []string{}
+		n := (len(data) + QR_FRAME_SIZE - 1) / QR_FRAME_SIZE
+		for i := 0; i < n; i++ {
+			end := min((i+1)*QR_FRAME_SIZE, len(data))
+			parts = append(parts, fmt.Sprintf("web3pro:%d/%d:%s", i+1, n, data[i*QR_FRAME_SIZE:end]))
+		}
+	}
+
+	frames := []string{}
+	for _, p := range parts {
+		q, err := qrcode.NewWithForcedVersion(p, QR_VERSION, qrcode.Low)
+		if err != nil {
+			return nil, err
+		}
+
+		lines := strings.Split(strings.TrimSuffix(q.ToSmallString(false), "\n"), "\n")
+		frames = append(frames, " "+strings.Join(lines, " \n ")+" \n")
+	}
+
+	return frames, nil
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/AlexNa-Holdings/web3pro/bus"
 	"github.com/AlexNa-Holdings/web3pro/cmn"
+	"github.com/AlexNa-Holdings/web3pro/eth"
 	"github.com/AlexNa-Holdings/web3pro/gocui"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/rs/zerolog/log"
@@ -49,7 +50,7 @@ func DlgSend(b *cmn.Blockchain, t *cmn.Token, from *cmn.Address, to string, amou
 					to := v.GetInput("to")
 					amount := v.GetInput("amount")
 
-					if !common.IsHexAddress(to) {
+					if !common.IsHexAddress(to) && !eth.IsENSName(to) {
 						bus.Send("ui", "notify-error", fmt.Sprintf("Invalid address: %s", to))
 						return
 					}
@@ -61,13 +62,25 @@ func DlgSend(b *cmn.Blockchain, t *cmn.Token, from *cmn.Address, to string, amou
 						return
 					}
 
-					bus.Send("eth", "send", &bus.B_EthSend{
-						ChainId: b.ChainId,
-						Token:   t.Symbol,
-						From:    from.Address,
-						To:      common.HexToAddress(to),
-						Amount:  val,
-					})
+					go func() {
+						to_addr := common.HexToAddress(to)
+						if !common.IsHexAddress(to) {
+							a, err := eth.ResolveENS(to)
+							if err != nil {
+								bus.Send("ui", "notify-error", fmt.Sprintf("Error: %v", err))
+								return
+							}
+							to_addr = a
+						}
+
+						bus.Send("eth", "send", &bus.B_EthSend{
+							ChainId: b.ChainId,
+							Token:   t.Symbol,
+							From:    from.Address,
+							To:      to_addr,
+							Amount:  val,
+						})
+					}()
 
 					Gui.HidePopup()
 
@@ -2,33 +2,54 @@ package ui
 
 import (
 	"encoding/hex"
+	"os"
 	"strings"
 
 	"github.com/AlexNa-Holdings/web3pro/cmn"
 	"github.com/AlexNa-Holdings/web3pro/gocui"
+	"github.com/AlexNa-Holdings/web3pro/sw/external"
+	"github.com/AlexNa-Holdings/web3pro/sw/mnemonics"
+	"github.com/AlexNa-Holdings/web3pro/sw/privkey"
 	"github.com/tyler-smith/go-bip39"
 )
 
+const (
+	PASSPHRASE_STORE = "store in wallet"
+	PASSPHRASE_ASK   = "ask every session"
+)
+
 func DlgSignerAdd(t string, name string) *gocui.Popup {
 	template := ""
 
-	if t != "mnemonics" {
+	switch t {
+	case "privkey":
 		template = `
-   Name: ` + name + `
-   Type: ` + t + `
-	 
-Copy of: <select id:copyof size:32 value:""> <c>
-NOTE: You are responsible to assure that the 
-device marked as copy is the same as the one
-you are adding. If you are not sure, please
-cancel and verify the device.
+     Name: <input id:name size:32> 
+     Type: ` + t + `
+      Key: <input id:key masked:true size:32> 
+ Password: <input id:pass masked:true size:32> 
 
-<button text:Ok tip:"create wallet">  <button text:Cancel>`
-	} else {
+ Enter a hex private key, or the path to a
+ keystore V3 JSON file and its password.
+
+<c>
+<button text:Ok tip:"import key">  <button text:Cancel>`
+	case "external":
 		template = `
      Name: <input id:name size:32> 
      Type: ` + t + `
- Mnemonic: <text id:mnemonics width:32 height:8> 
+ Endpoint: <input id:endpoint size:32> 
+
+ Clef compatible signer: IPC socket path or
+ http://host:port URL.
+
+<c>
+<button text:Ok tip:"connect external signer">  <button text:Cancel>`
+	case "mnemonics":
+		template = `
+       Name: <input id:name size:32> 
+       Type: ` + t + `
+   Mnemonic: <text id:mnemonics width:32 height:8> 
 
  
 
@@ -36,7 +57,23 @@ cancel and verify the device.
 
 
 
+ Passphrase: <input id:passphrase masked:true size:32> 
+       Keep: <select id:keep size:20> 
+ (optional BIP39 passphrase, the "25th word")
+
 <c>
+<button text:Ok tip:"create wallet">  <button text:Cancel>`
+	default:
+		template = `
+   Name: ` + name + `
+   Type: ` + t + `
+	 
+Copy of: <select id:copyof size:32 value:""> <c>
+NOTE: You are responsible to assure that the 
+device marked as copy is the same as the one
+you are adding. If you are not sure, please
+cancel and verify the device.
+
 <button text:Ok tip:"create wallet">  <button text:Cancel>`
 	}
 
@@ -53,6 +90,11 @@ cancel and verify the device.
 		OnOpen: func(v *gocui.View) {
 			v.SetInput("name", name)
 
+			if t == "external" {
+				v.SetInput("name", "")
+				v.SetInput("endpoint", name)
+			}
+
 			names := []string{""}
 			for _, s := range cmn.CurrentWallet.Signers {
 				if s.Type == t {
@@ -61,6 +103,11 @@ cancel and verify the device.
 			}
 
 			v.SetSelectList("copyof", names)
+
+			if t == "mnemonics" {
+				v.SetSelectList("keep", []string{PASSPHRASE_STORE, PASSPHRASE_ASK})
+				v.SetInput("keep", PASSPHRASE_STORE)
+			}
 		},
 		OnClickHotspot: func(v *gocui.View, hs *gocui.Hotspot) {
 
@@ -98,13 +145,98 @@ cancel and verify the device.
 							break
 						}
 
-						cmn.CurrentWallet.Signers = append(cmn.CurrentWallet.Signers, &cmn.Signer{
+						s := &cmn.Signer{
 							Name:      name,
 							Type:      t,
 							MasterKey: hex.EncodeToString(m[:]),
+						}
+
+						passphrase := v.GetInput("passphrase")
+						if passphrase != "" {
+							if v.GetInput("keep") == PASSPHRASE_ASK {
+								s.AskPassphrase = true
+							} else {
+								s.Passphrase = passphrase
+							}
+						}
+
+						fp, err := mnemonics.Fingerprint(s.MasterKey, passphrase)
+						if err != nil {
+							Notification.ShowErrorf("Error deriving address: %s", err)
+							break
+						}
+						s.Fingerprint = fp
+
+						if s.AskPassphrase {
+							s.SetSessionPassphrase(passphrase) // keyed by the fingerprint
+						}
+
+						cmn.CurrentWallet.Signers = append(cmn.CurrentWallet.Signers, s)
+						Printf("Signer %s first address: %s\n", name, fp.Hex())
+
+					} else if t == "privkey" {
+						name := strings.TrimSpace(v.GetInput("name"))
+						if len(name) == 0 {
+							Notification.ShowError("Name cannot be empty")
+							break
+						}
+
+						if cmn.CurrentWallet.GetSigner(name) != nil {
+							Notification.ShowErrorf("Signer %s already exists", name)
+							break
+						}
+
+						key := strings.TrimSpace(v.GetInput("key"))
+						if len(key) == 0 {
+							Notification.ShowError("Key cannot be empty")
+							break
+						}
+
+						var sn string
+						var err error
+						if _, serr := os.Stat(key); serr == nil {
+							sn, _, err = privkey.ImportKeystore(key, v.GetInput("pass"))
+						} else {
+							sn, _, err = privkey.ParseHex(key)
+						}
+						if err != nil {
+							Notification.ShowErrorf("%s", err)
+							break
+						}
+
+						cmn.CurrentWallet.Signers = append(cmn.CurrentWallet.Signers, &cmn.Signer{
+							Name:      name,
+							Type:      t,
+							MasterKey: sn,
+						})
+
+					} else if t == "external" {
+						name := strings.TrimSpace(v.GetInput("name"))
+						if len(name) == 0 {
+							Notification.ShowError("Name cannot be empty")
+							break
+						}
+
+						if cmn.CurrentWallet.GetSigner(name) != nil {
+							Notification.ShowErrorf("Signer %s already exists", name)
+							break
+						}
+
+						endpoint := strings.TrimSpace(v.GetInput("endpoint"))
+						version, err := external.Version(endpoint)
+						if err != nil {
+							Notification.ShowErrorf("%s", err)
+							break
+						}
+						Printf("External signer %s, API version %s\n", endpoint, version)
+
+						cmn.CurrentWallet.Signers = append(cmn.CurrentWallet.Signers, &cmn.Signer{
+							Name:      name,
+							Type:      t,
+							MasterKey: endpoint,
 						})
 
-					} else { // not mnemonics
+					} else { // hardware
 
 						if cmn.CurrentWallet.GetSigner(name) != nil {
 							Notification.ShowErrorf("Signer %s already exists", name)
@@ -0,0 +1,128 @@
+package ui
+
+import (
+	"encoding/hex"
+	"strings"
+
+	"github.com/AlexNa-Holdings/web3pro/cmn"
+	"github.com/AlexNa-Holdings/web3pro/gocui"
+	"github.com/AlexNa-Holdings/web3pro/sw/mnemonics"
+	"github.com/AlexNa-Holdings/web3pro/sw/slip39"
+	"github.com/tyler-smith/go-bip39"
+)
+
+func DlgSignerRestoreShares(name string) *gocui.Popup {
+	return &gocui.Popup{
+		Title: "Restore Signer from Shares",
+		OnOpen: func(v *gocui.View) {
+			v.SetInput("name", name)
+		},
+		OnClickHotspot: func(v *gocui.View, hs *gocui.Hotspot) {
+			if hs != nil {
+				switch hs.Value {
+				case "button Ok":
+					w := cmn.CurrentWallet
+					if w == nil {
+						Notification.ShowError("No wallet open")
+						break
+					}
+
+					name := strings.TrimSpace(v.GetInput("name"))
+					if name == "" {
+						Notification.ShowError("Name cannot be empty")
+						break
+					}
+
+					shares := []string{}
+					for _, l := range strings.Split(v.GetInput("shares"), "\n") {
+						if strings.TrimSpace(l) != "" {
+							shares = append(shares, l)
+						}
+					}
+
+					entropy, err := slip39.Combine(shares, nil)
+					if err != nil {
+						Notification.ShowErrorf("%s", err)
+						break
+					}
+
+					if _, err := bip39.NewMnemonic(entropy); err != nil {
+						Notification.ShowErrorf("Shares do not hold a mnemonic: %s", err)
+						break
+					}
+
+					sn := hex.EncodeToString(entropy)
+					passphrase := v.GetInput("passphrase")
+
+					s := w.GetSigner(name)
+					if s != nil {
+						if s.Type != "mnemonics" || s.MasterKey != sn {
+							Notification.ShowErrorf("Shares do not match signer %s", name)
+							break
+						}
+
+						if p, err := s.GetPassphrase(); err == nil {
+							passphrase = p
+						}
+					}
+
+					n, err := mnemonics.VerifyAddresses(sn, passphrase, name, w.Addresses)
+					if err != nil {
+						Notification.ShowErrorf("Verification failed: %s", err)
+						break
+					}
+
+					if s != nil {
+						Gui.HidePopup()
+						Notification.Showf("Shares verified: signer %s and %d addresses match", name, n)
+						break
+					}
+
+					fp, err := mnemonics.Fingerprint(sn, passphrase)
+					if err != nil {
+						Notification.ShowErrorf("Error deriving address: %s", err)
+						break
+					}
+
+					w.Signers = append(w.Signers, &cmn.Signer{
+						Name:        name,
+						Type:        "mnemonics",
+						MasterKey:   sn,
+						Passphrase:  passphrase,
+						Fingerprint: fp,
+					})
+
+					if err := w.Save(); err != nil {
+						Notification.ShowErrorf("Error saving wallet: %s", err)
+						break
+					}
+
+					Gui.HidePopup()
+					Printf("Signer %s restored, first address: %s, %d addresses verified\n", name, fp.Hex(), n)
+					Notification.Showf("Signer %s restored", name)
+
+				case "button Cancel":
+					Gui.HidePopup()
+				}
+			}
+		},
+		Template: `
+       Name: <input id:name size:32> 
+     Shares: <text id:shares width:48 height:10> 
+ (one share per line)
+
+
+
+
+
+
+
+
+
+ Passphrase: <input id:passphrase masked:true size:32> 
+ (BIP39 passphrase of the signer, if any)
+
+<c>
+<button text:Ok tip:"restore or verify signer">  <button text:Cancel>`,
+	}
+}
@@ -0,0 +1,62 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/AlexNa-Holdings/web3pro/cmn"
+	"github.com/AlexNa-Holdings/web3pro/gocui"
+)
+
+const SHARE_WORDS_PER_LINE = 6
+
+// DlgSignerShares shows the shares one at a time, so they can be written
+// down separately
+func DlgSignerShares(s *cmn.Signer, threshold int, shares []string) *gocui.Popup {
+	page := 0
+
+	template := func() string {
+		t := fmt.Sprintf(`
+ Signer: %s
+ Any %d of %d shares restore the signer ('signer restore-shares').
+ Write each share down and keep them in separate places.
+
+ <b>Share %d of %d</b>
+`, s.Name, threshold, len(shares), page+1, len(shares))
+
+		words := strings.Fields(shares[page])
+		for j := 0; j < len(words) || j < 33; j += SHARE_WORDS_PER_LINE { // 33 words max
+			line := ""
+			for k := j; k < j+SHARE_WORDS_PER_LINE && k < len(words); k++ {
+				line += fmt.Sprintf("%2d.%-9s", k+1, words[k])
+			}
+			t += " " + line + "\n"
+		}
+
+		return t + `
+<c><button text:Prev> <button text:Next> <button text:Close>`
+	}
+
+	return &gocui.Popup{
+		Title: "Shares of " + s.Name,
+		OnClickHotspot: func(v *gocui.View, hs *gocui.Hotspot) {
+			if hs != nil {
+				switch hs.Value {
+				case "button Prev":
+					if page > 0 {
+						page--
+						v.RenderTemplate(template())
+					}
+				case "button Next":
+					if page < len(shares)-1 {
+						page++
+						v.RenderTemplate(template())
+					}
+				case "button Close":
+					Gui.HidePopup()
+				}
+			}
+		},
+		Template: template(),
+	}
+}
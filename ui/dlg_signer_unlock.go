@@ -0,0 +1,49 @@
+package ui
+
+import (
+	"github.com/AlexNa-Holdings/web3pro/cmn"
+	"github.com/AlexNa-Holdings/web3pro/gocui"
+	"github.com/AlexNa-Holdings/web3pro/sw/mnemonics"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func DlgSignerUnlock(s *cmn.Signer) *gocui.Popup {
+	return &gocui.Popup{
+		Title: "Unlock Signer " + s.Name,
+		OnClickHotspot: func(v *gocui.View, hs *gocui.Hotspot) {
+
+			if hs != nil {
+				switch hs.Value {
+				case "button Ok":
+					passphrase := v.GetInput("passphrase")
+
+					fp, err := mnemonics.Fingerprint(s.MasterKey, passphrase)
+					if err != nil {
+						Notification.ShowErrorf("Error deriving address: %s", err)
+						break
+					}
+
+					if s.Fingerprint != (common.Address{}) && fp != s.Fingerprint {
+						Notification.ShowErrorf("Wrong passphrase: first address %s, expected %s",
+							cmn.ShortAddress(fp), cmn.ShortAddress(s.Fingerprint))
+						v.SetInput("passphrase", "")
+						v.SetFocus(0)
+						break
+					}
+
+					s.SetSessionPassphrase(passphrase)
+					Gui.HidePopup()
+					Notification.Showf("Signer %s unlocked", s.Name)
+
+				case "button Cancel":
+					Gui.HidePopup()
+				}
+			}
+		},
+		Template: `
+ First address: ` + cmn.TagAddressShortLink(s.Fingerprint) + `
+    Passphrase: <input id:passphrase masked:true size:32>
+ <c>
+ <button text:Ok tip:"unlock for this session">  <button text:Cancel>`,
+	}
+}
@@ -1,26 +1,16 @@
 package ui
 
 import (
-	"io"
 	"os"
-	"regexp"
 
 	"github.com/AlexNa-Holdings/web3pro/bus"
 	"github.com/AlexNa-Holdings/web3pro/cmn"
 	"github.com/AlexNa-Holdings/web3pro/gocui"
 )
 
-// extractWalletName extracts wallet name from backup filename
-// Format: walletName_YYYY-MM-DD_HH-MM-SS
-func extractWalletName(backupFile string) string {
-	// Match the timestamp suffix pattern: _YYYY-MM-DD_HH-MM-SS
-	re := regexp.MustCompile(`_\d{4}-\d{2}-\d{2}_\d{2}-\d{2}-\d{2}$`)
-	return re.ReplaceAllString(backupFile, "")
-}
-
 func DlgWalletRestore(backupFile string) *gocui.Popup {
-	backupPath := cmn.DataFolder + "/wallets/backups/" + backupFile
-	walletName := extractWalletName(backupFile)
+	backupPath := cmn.BackupFolder() + "/" + backupFile
+	walletName := cmn.BackupWalletName(backupFile)
 
 	return &gocui.Popup{
 		Title: "Restore Wallet from " + backupFile,
@@ -57,24 +47,8 @@ func DlgWalletRestore(backupFile string) *gocui.Popup {
 					}
 
 					bus.Send("ui", "popup", DlgConfirm("Confirm Restore", confirmMsg, func() bool {
-						// Copy backup to wallet location
-						srcFile, err := os.Open(backupPath)
-						if err != nil {
-							Notification.ShowErrorf("Failed to open backup: %s", err)
-							return false
-						}
-						defer srcFile.Close()
-
-						dstFile, err := os.Create(targetPath)
-						if err != nil {
-							Notification.ShowErrorf("Failed to create wallet file: %s", err)
-							return false
-						}
-						defer dstFile.Close()
-
-						_, err = io.Copy(dstFile, srcFile)
-						if err != nil {
-							Notification.ShowErrorf("Failed to restore wallet: %s", err)
+						if err := cmn.RestoreBackup(backupFile, walletName); err != nil {
+							Notification.ShowErrorf("%s", err)
 							return false
 						}
 
@@ -0,0 +1,72 @@
+package ui
+
+import (
+	"github.com/AlexNa-Holdings/web3pro/bus"
+	"github.com/AlexNa-Holdings/web3pro/cmn"
+	"github.com/AlexNa-Holdings/web3pro/gocui"
+)
+
+func DlgWalletRollback(backupFile string) *gocui.Popup {
+	backupPath := cmn.BackupFolder() + "/" + backupFile
+	walletName := cmn.BackupWalletName(backupFile)
+
+	return &gocui.Popup{
+		Title: "Roll Back Wallet to " + backupFile,
+		OnClickHotspot: func(v *gocui.View, hs *gocui.Hotspot) {
+
+			if hs != nil {
+				switch hs.Value {
+				case "button Ok":
+					pass := v.GetInput("pass")
+
+					// the backup may have been saved with an older password
+					_, err := cmn.OpenFromFile(backupPath, pass)
+					if err != nil {
+						Notification.ShowErrorf("Error opening backup: %s", err)
+						v.SetInput("pass", "")
+						v.SetFocus(0)
+						break
+					}
+
+					Gui.HidePopup()
+
+					t, _ := cmn.BackupTime(backupFile)
+
+					bus.Send("ui", "popup", DlgConfirm("Confirm Rollback",
+						"Roll back wallet '"+walletName+"' to the version of "+t.Format("2006-01-02 15:04:05")+
+							"?\nThe current version will be kept as an automatic backup.",
+						func() bool {
+							if err := cmn.RestoreBackup(backupFile, walletName); err != nil {
+								Notification.ShowErrorf("%s", err)
+								return false
+							}
+
+							// drop the in-memory state so it is not saved over the restored file
+							cmn.CurrentWallet = nil
+							Printf("\nWallet is reopening...\n")
+
+							go func() {
+								err := cmn.Open(walletName, pass)
+								if err != nil {
+									Terminal.SetCommandPrefix(DEFAULT_COMMAND_PREFIX)
+									Notification.ShowErrorf("Error opening wallet: %s", err)
+									return
+								}
+
+								Notification.Showf("Wallet '%s' rolled back", walletName)
+								Terminal.SetCommandPrefix(walletName)
+							}()
+							return true
+						}))
+
+				case "button Cancel":
+					Gui.HidePopup()
+				}
+			}
+		},
+		Template: `
+ Password: <input id:pass masked:true size:24>
+ <c>
+ <button text:Ok tip:"verify and roll back">  <button text:Cancel>`,
+	}
+}
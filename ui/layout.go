@@ -29,6 +29,8 @@ var panes = []Pane{
 	&LP_V4,
 	&Staking,
 	&Token,
+	&History,
+	&NFT,
 	&Terminal,
 }
 
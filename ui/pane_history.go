@@ -0,0 +1,129 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/AlexNa-Holdings/web3pro/bus"
+	"github.com/AlexNa-Holdings/web3pro/cmn"
+	"github.com/AlexNa-Holdings/web3pro/gocui"
+	"github.com/rs/zerolog/log"
+)
+
+// HISTORY_PANE_SIZE is the number of recent transactions shown in the pane
+const HISTORY_PANE_SIZE = 20
+
+type HistoryPane struct {
+	PaneDescriptor
+	On bool
+}
+
+var History HistoryPane = HistoryPane{
+	PaneDescriptor: PaneDescriptor{
+		MinWidth:               60,
+		MinHeight:              1,
+		MaxHeight:              HISTORY_PANE_SIZE,
+		SupportCachedHightCalc: true,
+	},
+}
+
+func (p *HistoryPane) GetDesc() *PaneDescriptor {
+	return &p.PaneDescriptor
+}
+
+func (p *HistoryPane) EstimateLines(w int) int {
+	return gocui.EstimateTemplateLines(p.GetTemplate(), w)
+}
+
+func (p *HistoryPane) IsOn() bool {
+	return p.On
+}
+
+func (p *HistoryPane) SetOn(on bool) {
+	p.On = on
+}
+
+func (p *HistoryPane) SetView(x0, y0, x1, y1 int, overlap byte) {
+	v, err := Gui.SetView("history", x0, y0, x1, y1, overlap)
+	if err != nil {
+		if !errors.Is(err, gocui.ErrUnknownView) {
+			log.Error().Err(err).Msgf("SetView error: %s", err)
+		}
+
+		p.PaneDescriptor.View = v
+		v.JoinedFrame = true
+		v.Title = "Transactions"
+		v.ScrollBar = true
+		v.OnResize = func(v *gocui.View) {
+			v.RenderTemplate(p.GetTemplate())
+			v.ScrollTop()
+		}
+		v.OnOverHotspot = ProcessOnOverHotspot
+		v.OnClickHotspot = ProcessOnClickHotspot
+		p.SetTemplate(p.rebuidTemplate())
+		v.RenderTemplate(p.GetTemplate())
+	}
+}
+
+func HistoryLoop() {
+	ch := bus.Subscribe("wallet")
+	defer bus.Unsubscribe(ch)
+
+	for msg := range ch {
+		switch msg.Type {
+		case "open", "saved":
+			History.SetTemplate(History.rebuidTemplate())
+			Gui.Update(func(g *gocui.Gui) error {
+				if History.View != nil {
+					History.View.RenderTemplate(History.GetTemplate())
+				}
+				return nil
+			})
+		}
+	}
+}
+
+func (p *HistoryPane) rebuidTemplate() string {
+	temp := "<w>"
+
+	w := cmn.CurrentWallet
+	if w == nil {
+		return temp + "No wallet selected"
+	}
+
+	if len(w.Transactions) == 0 {
+		return temp + "No transactions"
+	}
+
+	for i := len(w.Transactions) - 1; i >= 0 && i >= len(w.Transactions)-HISTORY_PANE_SIZE; i-- {
+		r := w.Transactions[i]
+
+		chain := fmt.Sprintf("%d", r.ChainId)
+		if b := w.GetBlockchain(r.ChainId); b != nil {
+			chain = b.GetShortName()
+		}
+
+		status := fmt.Sprintf("%-9s", r.Status)
+		switch r.Status {
+		case cmn.TX_STATUS_PENDING:
+			status = "<blink>" + status + "</blink>"
+		case cmn.TX_STATUS_REVERTED:
+			status = "<color fg:red>" + status + "</color>"
+		}
+
+		method := r.Method
+		if method == "" {
+			method = "transfer"
+		}
+
+		temp += fmt.Sprintf("%s %-6s ", r.Time.Format("01-02 15:04"), chain)
+		temp += cmn.TagLink(r.Hash.Hex()[:10], "command tx show "+r.Hash.Hex(), "Show transaction")
+		temp += " " + status + " " + method + "\n"
+	}
+
+	if p.View != nil {
+		p.View.Subtitle = fmt.Sprintf("N:%d pending:%d", len(w.Transactions), len(w.PendingTxRecords()))
+	}
+
+	return temp
+}
@@ -0,0 +1,132 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/AlexNa-Holdings/web3pro/bus"
+	"github.com/AlexNa-Holdings/web3pro/cmn"
+	"github.com/AlexNa-Holdings/web3pro/gocui"
+	"github.com/rs/zerolog/log"
+)
+
+type NFTPane struct {
+	PaneDescriptor
+	On bool
+}
+
+var NFT NFTPane = NFTPane{
+	PaneDescriptor: PaneDescriptor{
+		MinWidth:               60,
+		MinHeight:              1,
+		MaxHeight:              20,
+		SupportCachedHightCalc: true,
+	},
+}
+
+func (p *NFTPane) GetDesc() *PaneDescriptor {
+	return &p.PaneDescriptor
+}
+
+func (p *NFTPane) EstimateLines(w int) int {
+	return gocui.EstimateTemplateLines(p.GetTemplate(), w)
+}
+
+func (p *NFTPane) IsOn() bool {
+	return p.On
+}
+
+func (p *NFTPane) SetOn(on bool) {
+	p.On = on
+}
+
+func (p *NFTPane) SetView(x0, y0, x1, y1 int, overlap byte) {
+	v, err := Gui.SetView("nft", x0, y0, x1, y1, overlap)
+	if err != nil {
+		if !errors.Is(err, gocui.ErrUnknownView) {
+			log.Error().Err(err).Msgf("SetView error: %s", err)
+		}
+
+		p.PaneDescriptor.View = v
+		v.JoinedFrame = true
+		v.Title = "NFTs"
+		v.ScrollBar = true
+		v.OnResize = func(v *gocui.View) {
+			v.RenderTemplate(p.GetTemplate())
+			v.ScrollTop()
+		}
+		v.OnOverHotspot = ProcessOnOverHotspot
+		v.OnClickHotspot = ProcessOnClickHotspot
+		p.SetTemplate(p.rebuidTemplate())
+		v.RenderTemplate(p.GetTemplate())
+	}
+}
+
+func NFTLoop() {
+	ch := bus.Subscribe("wallet")
+	defer bus.Unsubscribe(ch)
+
+	for msg := range ch {
+		switch msg.Type {
+		case "open", "saved":
+			NFT.SetTemplate(NFT.rebuidTemplate())
+			Gui.Update(func(g *gocui.Gui) error {
+				if NFT.View != nil {
+					NFT.View.RenderTemplate(NFT.GetTemplate())
+				}
+				return nil
+			})
+		}
+	}
+}
+
+// rebuidTemplate lists the holdings on the current chain
+func (p *NFTPane) rebuidTemplate() string {
+	w := cmn.CurrentWallet
+	if w == nil {
+		return "No wallet selected"
+	}
+
+	b := w.GetBlockchain(w.CurrentChainId)
+	if b == nil {
+		return "No blockchain selected"
+	}
+
+	list := w.GetNFTs(b.ChainId, [20]byte{})
+	if len(list) == 0 {
+		return "No NFTs " + cmn.TagLink("discover", "command nft discover", "Find the holdings on "+b.Name)
+	}
+
+	temp := ""
+	for i, n := range list {
+		collection := n.Collection
+		if collection == "" {
+			collection = cmn.ShortAddress(n.Contract)
+		}
+
+		owner := cmn.ShortAddress(n.Owner)
+		if a := w.GetAddress(n.Owner.String()); a != nil {
+			owner = a.Name
+		}
+
+		temp += cmn.TagLink(cmn.FixedWidth(collection, 16), "copy "+n.Contract.String(), n.Contract.String())
+		temp += " #" + cmn.FixedWidth(n.TokenId.String(), 10)
+		if n.IsERC1155() {
+			temp += fmt.Sprintf(" x%-6s", n.Balance.String())
+		} else {
+			temp += "        "
+		}
+		temp += " " + cmn.TagLink(cmn.ICON_SEND, "command nft send "+n.Owner.String()+" "+n.Contract.String()+" "+n.TokenId.String(), "Transfer")
+		temp += owner
+
+		if i < len(list)-1 {
+			temp += "\n"
+		}
+	}
+
+	if p.View != nil {
+		p.View.Subtitle = fmt.Sprintf("N:%d %s", len(list), b.GetShortName())
+	}
+
+	return temp
+}
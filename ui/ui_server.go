@@ -32,6 +32,8 @@ func Init() {
 	go LP_V4Loop()
 	go TokenLoop()
 	go StakingLoop()
+	go HistoryLoop()
+	go NFTLoop()
 
 	Gui, err = gocui.NewGui(gocui.OutputTrue, true)
 	if err != nil {
@@ -163,6 +165,18 @@ func process(msg *bus.Message) {
 			} else {
 				HidePane(&Staking)
 			}
+
+			if cmn.CurrentWallet.HistoryPaneOn {
+				ShowPane(&History)
+			} else {
+				HidePane(&History)
+			}
+
+			if cmn.CurrentWallet.NFTPaneOn {
+				ShowPane(&NFT)
+			} else {
+				HidePane(&NFT)
+			}
 		}
 	case "saved": // save wallet
 		if cmn.CurrentWallet != nil {
@@ -183,6 +197,18 @@ func process(msg *bus.Message) {
 			} else {
 				HidePane(&Staking)
 			}
+
+			if cmn.CurrentWallet.HistoryPaneOn {
+				ShowPane(&History)
+			} else {
+				HidePane(&History)
+			}
+
+			if cmn.CurrentWallet.NFTPaneOn {
+				ShowPane(&NFT)
+			} else {
+				HidePane(&NFT)
+			}
 		}
 	}
 }
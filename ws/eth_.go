@@ -291,9 +291,11 @@ func sendTransaction(o *cmn.Origin, req RPCRequest, ctx *ConContext, res *RPCRes
 		return fmt.Errorf("address not found in wallet")
 	}
 
-	signer := w.GetSigner(from.Signer)
-	if signer == nil {
-		return fmt.Errorf("signer not found")
+	if from.Safe == nil { // Safe transactions are signed by the owners
+		signer := w.GetSigner(from.Signer)
+		if signer == nil {
+			return fmt.Errorf("signer not found")
+		}
 	}
 
 	b := w.GetBlockchain(o.ChainId)
@@ -334,6 +336,7 @@ func sendTransaction(o *cmn.Origin, req RPCRequest, ctx *ConContext, res *RPCRes
 		To:      to,
 		Amount:  value,
 		Data:    data,
+		Origin:  o.URL,
 	})
 
 	if send_res.Error != nil {